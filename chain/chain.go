@@ -119,14 +119,22 @@ func InitSettlement(
 		return nil, errors.New("init vault factory error")
 	}
 
+	// keep the cashout data of every chain apart, data written before it was namespaced belongs to the current chain
+	_, err = vault.MigrateToChainNamespace(stateStore, chainID)
+	if err != nil {
+		return nil, fmt.Errorf("migrate cashout data: %w", err)
+	}
+	vaultStore := vault.ChainNamespacedStore(stateStore, chainID)
+
 	//initChequeStoreCashout
 	chequeStore, cashoutService := initChequeStoreCashout(
-		stateStore,
+		vaultStore,
 		chaininfo.Backend,
 		factory,
 		chainID,
 		chaininfo.OverlayAddress,
 		chaininfo.TransactionService,
+		chaininfo.Signer,
 	)
 
 	//new accounting
@@ -139,7 +147,7 @@ func InitSettlement(
 	//InitVaultService
 	vaultService, err := initVaultService(
 		ctx,
-		stateStore,
+		vaultStore,
 		chaininfo.Signer,
 		chaininfo.ChainID,
 		chaininfo.PeerID,
@@ -187,6 +195,14 @@ func InitSettlement(
 	return &SettleObject, nil
 }
 
+// Close stops the background work of the settlement services, it is called on node shutdown
+func (s *SettleInfo) Close() error {
+	if s.CashoutService == nil {
+		return nil
+	}
+	return s.CashoutService.Close()
+}
+
 // InitVaultFactory will initialize the vault factory with the given
 // chain backend.
 func initVaultFactory(
@@ -273,6 +289,7 @@ func initChequeStoreCashout(
 	chainID int64,
 	overlayEthAddress common.Address,
 	transactionService transaction.Service,
+	signer crypto.Signer,
 ) (vault.ChequeStore, vault.CashoutService) {
 	chequeStore := vault.NewChequeStore(
 		stateStore,
@@ -288,7 +305,10 @@ func initChequeStoreCashout(
 		swapBackend,
 		transactionService,
 		chequeStore,
+		vault.WithBeneficiary(overlayEthAddress),
+		vault.WithResultSigner(signer, chainID),
 	)
+	cashout.Start()
 
 	return chequeStore, cashout
 }
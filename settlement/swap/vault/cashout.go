@@ -1,34 +1,220 @@
 package vault
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"math/big"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/bittorrent/go-btfs/statestore"
 	"github.com/bittorrent/go-btfs/transaction"
+	"github.com/bittorrent/go-btfs/transaction/sctx"
 	"github.com/bittorrent/go-btfs/transaction/storage"
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 var (
 	// ErrNoCashout is the error if there has not been any cashout action for the vault
 	ErrNoCashout = errors.New("no prior cashout")
+	// ErrTooManyInFlight is the error if the maximum number of pending cashouts has been reached
+	ErrTooManyInFlight = errors.New("too many cashouts in flight")
+	// ErrNonceTooLow is the error if an explicit cashout nonce has already been used by the account
+	ErrNonceTooLow = errors.New("cashout nonce below account nonce")
+	// ErrMultipleCashedEvents is the error if a receipt contains several ChequeCashed events of the vault and none is
+	// unambiguously ours
+	ErrMultipleCashedEvents = errors.New("multiple cheque cashed events")
+	// ErrBeneficiaryMismatch is the error if the beneficiary of the cheque is not the account sending the cashout
+	ErrBeneficiaryMismatch = errors.New("cheque beneficiary mismatch")
+	// ErrCashoutDropped is the error passed to the result callback if the cashout transaction was never mined and
+	// has been dropped
+	ErrCashoutDropped = errors.New("cashout transaction dropped")
 )
 
+const (
+	// CashoutPhaseLookup is the phase in which the last received cheque is looked up
+	CashoutPhaseLookup = "lookup"
+	// CashoutPhasePolicy is the phase in which the recipient policy is applied
+	CashoutPhasePolicy = "policy"
+	// CashoutPhaseValidate is the phase in which the cashout is checked against the configured limits
+	CashoutPhaseValidate = "validate"
+	// CashoutPhasePack is the phase in which the cashout call data is abi encoded
+	CashoutPhasePack = "pack"
+	// CashoutPhaseSend is the phase in which the cashout transaction is sent
+	CashoutPhaseSend = "send"
+	// CashoutPhaseStore is the phase in which the cashout action is persisted
+	CashoutPhaseStore = "store"
+)
+
+// CashoutError is the error returned by CashCheque. It records the phase in which the cashout failed.
+type CashoutError struct {
+	Phase string // one of the CashoutPhase constants
+	Err   error  // the underlying error
+}
+
+func (e *CashoutError) Error() string {
+	return fmt.Sprintf("cashout failed during %s: %v", e.Phase, e.Err)
+}
+
+func (e *CashoutError) Unwrap() error {
+	return e.Err
+}
+
+func newCashoutError(phase string, err error) error {
+	return &CashoutError{
+		Phase: phase,
+		Err:   err,
+	}
+}
+
 // CashoutService is the service responsible for managing cashout actions
 type CashoutService interface {
-	// CashCheque sends a cashing transaction for the last cheque of the vault
+	// CashCheque sends a cashing transaction for the last cheque of the vault.
+	// Failures are reported as a *CashoutError.
 	CashCheque(ctx context.Context, vault, recipient common.Address) (common.Hash, error)
+	// CashChequeWithOptions is like CashCheque but allows to customize the cashout transaction
+	CashChequeWithOptions(ctx context.Context, vault, recipient common.Address, opts CashoutOptions) (common.Hash, error)
+	// CashChequeTo cashes the last cheque of the vault to the recipient given by name, e.g. an ENS name
+	CashChequeTo(ctx context.Context, vault common.Address, recipientName string) (common.Hash, error)
+	// CashChequeWithCallback is like CashCheque and calls cb exactly once with the final result of the cashout
+	CashChequeWithCallback(ctx context.Context, vault, recipient common.Address, cb CashoutCallback) (common.Hash, error)
+	// CashAndWithdraw cashes the last cheque of the vault into the operator's vault recipient and withdraws the cashed amount
+	CashAndWithdraw(ctx context.Context, vault, recipient common.Address, withdrawTo common.Address) (*CashAndWithdrawResult, error)
+	// CashChequeSplit cashes the last cheque of the vault to the node and splits the cashed amount between several recipients
+	CashChequeSplit(ctx context.Context, vault common.Address, splits []RecipientSplit) (*CashChequeSplitResult, error)
 	// CashoutStatus gets the status of the latest cashout transaction for the vault
 	CashoutStatus(ctx context.Context, vaultAddress common.Address) (*CashoutStatus, error)
+	// CashoutStatusBatch gets the cashout status of many vaults, fetching their receipts in batches if possible
+	CashoutStatusBatch(ctx context.Context, vaults []common.Address) ([]VaultCashoutStatus, error)
 	HasCashoutAction(ctx context.Context, peer common.Address) (bool, error)
+	// CashoutResults returns all cashout results ordered by CashTime, ties broken by TxHash
 	CashoutResults() ([]CashOutResult, error)
+	// TrackVaultMetrics reports the cashout metrics of the vault under its own label instead of the aggregate
+	TrackVaultMetrics(vault common.Address)
+	// DumpVaultCashout gathers everything known about the cashouts of a vault for diagnostics
+	DumpVaultCashout(ctx context.Context, vault common.Address) (VaultCashoutDump, error)
+	// BounceRate returns the fraction of the successful cashouts of the vault within the window which bounced
+	BounceRate(vault common.Address, window time.Duration) (float64, error)
+	// ForgetVault removes all cashout data of the vault and its contribution to the cashed totals
+	ForgetVault(ctx context.Context, vault common.Address) error
+	// PauseRetries halts the cashouts retried in the background until ResumeRetries
+	PauseRetries()
+	// ResumeRetries continues the retries halted by PauseRetries
+	ResumeRetries()
+	// RetryQueueStatus reports whether the retries are paused and how many cashouts are being retried
+	RetryQueueStatus() (paused bool, depth int)
+	// ConfirmationTimeStats returns the statistics of the time from submission to confirmation of recent cashouts
+	ConfirmationTimeStats(window time.Duration) (ConfTimeStats, error)
+	// CashoutEvents returns the recorded state transitions of the cashouts of the vault in order
+	CashoutEvents(vault common.Address) ([]CashoutEvent, error)
+	// CashChequeOptimal cashes the last cheque of the vault only if the uncashed amount exceeds the gas cost
+	CashChequeOptimal(ctx context.Context, vault, recipient common.Address) (common.Hash, error)
+	// SetRecipientAllowlist persists the recipients cashouts may be sent to, an empty list allows every recipient
+	SetRecipientAllowlist(recipients []common.Address) error
+	// RecipientAllowlist returns the recipients cashouts may be sent to, empty if every recipient is allowed
+	RecipientAllowlist() ([]common.Address, error)
+	// VerifyTotals compares the stored cashed totals with the totals recomputed from the cashout results
+	VerifyTotals(ctx context.Context) (TotalsDiff, error)
+	// CashoutResultsByLabel returns the cashout results with the given label in the order of CashoutResults
+	CashoutResultsByLabel(label string) ([]CashOutResult, error)
+	// CashoutResultsByAmount returns the cashout results with a cashed amount between min and max, nil is open-ended
+	CashoutResultsByAmount(min, max *big.Int) ([]CashOutResult, error)
+	// FilterCashoutResults returns the cashout results passing the filter in the order of CashoutResults
+	FilterCashoutResults(filter CashoutResultFilter) ([]CashOutResult, error)
+	// IterateCashoutResults streams all cashout results in store order to fn until it returns false
+	IterateCashoutResults(ctx context.Context, fn func(CashOutResult) bool) error
+	// RebuildHistoryFromChain reconstructs the cashout results of the vault from its on-chain ChequeCashed events
+	RebuildHistoryFromChain(ctx context.Context, vault common.Address, fromBlock uint64) error
+	// CashoutResultsForDay returns the cashout results of the given day in the order of CashoutResults
+	CashoutResultsForDay(day time.Time) ([]CashOutResult, error)
+	// ResolveImplementation returns the implementation contract behind the vault proxy
+	ResolveImplementation(ctx context.Context, proxy common.Address) (common.Address, error)
+	// ExcludeVaultFromAutoCashout excludes the vault from the auto cashout loop until it is included again
+	ExcludeVaultFromAutoCashout(vault common.Address) error
+	// IncludeVaultInAutoCashout lets the auto cashout loop cash out the vault again
+	IncludeVaultInAutoCashout(vault common.Address) error
+	// AutoCashoutExclusions returns the vaults excluded from the auto cashout loop
+	AutoCashoutExclusions() ([]common.Address, error)
+	// NetPosition returns the amount the issuer of the vault owes the node considering cheques in both directions
+	NetPosition(ctx context.Context, vault common.Address) (*big.Int, error)
+	// RawReceipt returns the stored receipt of a cashout transaction if raw receipts are stored
+	RawReceipt(txHash common.Hash) (*types.Receipt, error)
+	// PruneRawReceipts deletes raw receipts stored longer than maxAge ago
+	PruneRawReceipts(maxAge time.Duration) (int, error)
+	// ImportVaults stores the last cheques another node received so they can be cashed by this node
+	ImportVaults(ctx context.Context, entries []ImportEntry) error
+	// StoredResults returns the number of stored cashout results and how far the oldest have been evicted
+	StoredResults() (StoredResultsInfo, error)
+	// AutoCashoutCursor returns the last vault checked by the auto cashout loop
+	AutoCashoutCursor() (common.Address, error)
+	// SignCashoutResult signs the vault, amount and transaction hash of the result with the node's key
+	SignCashoutResult(result CashOutResult) ([]byte, error)
+	// VerifyCashoutResult checks that the signature of the result was made by signer
+	VerifyCashoutResult(result CashOutResult, signature []byte, signer common.Address) error
+	// CashableVaults returns the vaults which can be cashed right now without bouncing
+	CashableVaults(ctx context.Context, minAmount *big.Int) ([]VaultCashable, error)
+	// DestroyedVaults returns the vaults whose contract was destroyed and the uncashed amount lost with them
+	DestroyedVaults() ([]DestroyedVault, error)
+	// RecoverableValue returns the uncashed amount of all vaults capped by their current balances
+	RecoverableValue(ctx context.Context) (*big.Int, error)
+	// LastAutoCashoutDecision returns why the auto cashout loop did or did not cash the vault last time
+	LastAutoCashoutDecision(vault common.Address) (AutoDecision, error)
+	// CashoutTxChain returns the original and replacing transactions of the last cashout of the vault
+	CashoutTxChain(vault common.Address) ([]common.Hash, error)
+	// CashoutETA estimates when a pending cashout transaction will be mined
+	CashoutETA(ctx context.Context, txHash common.Hash) (time.Duration, ETAConfidence, error)
+	// SetDefaultRecipient sets the recipient used when the vault is cashed with a zero recipient
+	SetDefaultRecipient(vault, recipient common.Address) error
+	// DefaultRecipient returns the default recipient of the vault
+	DefaultRecipient(vault common.Address) (common.Address, error)
+	// CashChequeBatchTx cashes the cheques of several vaults, sending one cashout transaction per vault
+	CashChequeBatchTx(ctx context.Context, requests []CashoutRequest) ([]common.Hash, error)
+	// CashChequeMulti cashes the cheques of several vaults, submitting them in the given ordering
+	CashChequeMulti(ctx context.Context, vaults []common.Address, recipient common.Address, ordering BatchOrdering) ([]CashChequeMultiResult, error)
+	// SimulateCashout projects the result of cashing the last cheque without sending a transaction
+	SimulateCashout(ctx context.Context, vault, recipient common.Address) (*CashChequeResult, error)
+	// PreviewCashout runs the checks of CashChequeWithOptions and returns the transaction it would send without sending it
+	PreviewCashout(ctx context.Context, vault, recipient common.Address, opts CashoutOptions) (*DryRunResult, error)
+	// EstimateCashout estimates the gas of cashing the last cheque, falling back to the recent average gas
+	EstimateCashout(ctx context.Context, vault, recipient common.Address) (uint64, error)
+	// RecoverFailedResults promotes failed cashout results whose transaction did confirm on-chain to successful ones
+	RecoverFailedResults(ctx context.Context) (int, error)
+	// RecentAverageGas returns the moving average of the gas used by recent cashouts
+	RecentAverageGas() uint64
+	// StatsSnapshot returns a summary of the cashout statistics
+	StatsSnapshot() (CashoutStatsSnapshot, error)
+	// CashedVaults returns all vaults which have ever been cashed
+	CashedVaults() ([]common.Address, error)
+	// SubscribeCashoutResults returns a channel receiving every cashout result as it is stored
+	SubscribeCashoutResults() (<-chan CashOutResult, func())
+	// TailCashoutResults writes every new cashout result as a JSON line to w until ctx is cancelled
+	TailCashoutResults(ctx context.Context, w io.Writer) error
+	// WaitForCashouts waits until results of all the given cashout transactions are stored
+	WaitForCashouts(ctx context.Context, txHashes []common.Hash) (map[common.Hash]*CashOutResult, error)
+	// TotalReceivedCashedByToken returns the total received cashed amount for every token
+	TotalReceivedCashedByToken() (map[common.Address]*big.Int, error)
+	// DailyTotalsRange returns the daily totals of every day in the range, zero for days without records
+	DailyTotalsRange(from, to time.Time) ([]DailyTotal, error)
+	// DailyReceivedCashedByToken returns the received cashed amount of the given day for every token
+	DailyReceivedCashedByToken(day time.Time) (map[common.Address]*big.Int, error)
+	// Start resumes the tracking of cashouts interrupted by a shutdown and starts the background cashout loop if it has been configured
+	Start()
+	// Metrics returns the prometheus collectors of the cashout service
+	Metrics() []prometheus.Collector
+	// SelfTest runs a cashout against the configured test vault and checks that it is recorded correctly
+	SelfTest(ctx context.Context) error
+	// PushMetrics pushes the current cashout metrics to a Prometheus pushgateway
+	PushMetrics(ctx context.Context, gatewayURL string) error
+	// Close stops the background cashout loop
+	Close() error
 }
 
 type cashoutService struct {
@@ -36,6 +222,63 @@ type cashoutService struct {
 	backend            transaction.Backend
 	transactionService transaction.Service
 	chequeStore        ChequeStore
+	repository         CashoutRepository
+	codec              Codec // encoding of the actions and results kept by the default repository
+	recipientPolicy    RecipientPolicy
+	autoCashout        *AutoCashoutConfig
+	gasOracle          GasOracle
+	priceFeed          PriceFeed       // consulted for cashouts with a minimum token price
+	paidOutProvider    PaidOutProvider // replaces the on-chain paidOut call if set
+	now                func() time.Time
+
+	receiptPollingInterval time.Duration
+	headSubscriber         headSubscriber // set if the backend supports new head subscriptions
+	confirmationPolicy     ConfirmationPolicy
+	chainIDCheck           *chainIDCheck   // validates the chain id before cashing, nil disables the validation
+	selfTest               *SelfTestConfig // test vault of SelfTest, nil if not configured
+
+	dailyGasBudget *big.Int
+	beneficiary    common.Address // account sending the cashouts, zero if unknown
+
+	allowArbitraryBeneficiary bool // testnet only, cash cheques issued to other beneficiaries
+
+	shortfallMonitor *shortfallMonitor // alerts on vaults not covering their uncashed amount, nil disables it
+	autoDecisions    autoDecisions     // last decision of the auto cashout loop per vault
+
+	pendingStaleAfter time.Duration // age after which a pending cashout whose transaction is unknown is considered dropped, 0 disables it
+
+	storeRawReceipts bool // persist the full receipt of every cashout
+
+	totalsVerification *totalsVerification // periodic comparison of the totals with the results, nil disables it
+
+	signatureVerifier *signatureVerifier // verifies cheque signatures before cashing, nil disables the verification
+
+	chequeFreshnessCheck bool // re-read the last cheque right before sending and cash a newer one if it arrived
+
+	maxInFlight  int        // maximum number of pending cashouts, 0 means unlimited
+	inFlightLock sync.Mutex // serializes the in-flight check with the creation of the write-ahead log record
+	actionLock   sync.Mutex // serializes read-modify-write updates of stored cashout actions
+	recoverLock  sync.Mutex // serializes recovery passes so a result is never promoted twice
+	totalsLock   sync.Mutex // serializes the read-modify-write updates of the cashed totals
+
+	resultEviction *resultEviction // bounds the number of stored results, nil keeps all of them
+	dryRun         bool            // validate and simulate cashouts without sending them
+	preSendHook    PreSendHook     // invoked before every send, nil disables it
+	resultSigner   *resultSigner   // signs cashout results, nil disables signing
+	nameResolution *nameResolution // resolves recipient names for CashChequeTo, nil disables names
+
+	resultSubscribers resultSubscribers
+	resultPublisher   *resultPublisher // publishes results on pubsub, nil disables publishing
+	metrics           metrics
+	tracer            Tracer // traces cashouts and status queries, a no-op tracer by default
+	eventSeq          uint32 // sequence number of the last recorded cashout event
+	retries           *retryQueue
+	trackedVaults     trackedVaults
+	trackers          cashoutTrackers
+
+	quit      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
 }
 
 // LastCashout contains information about the last cashout
@@ -46,10 +289,50 @@ type LastCashout struct {
 	Reverted bool
 }
 
+// CashoutState describes the state of the cashouts of a vault
+type CashoutState int
+
+const (
+	// CashoutStateNeverCashed means the cheques of the vault have never been cashed
+	CashoutStateNeverCashed CashoutState = iota
+	// CashoutStatePending means the last cashout transaction has not been mined yet
+	CashoutStatePending
+	// CashoutStateConfirmed means the last cashout transaction was mined successfully
+	CashoutStateConfirmed
+	// CashoutStateReverted means the last cashout transaction was reverted
+	CashoutStateReverted
+	// CashoutStateFullyCashedExternally means there is no local cashout but the cheque was fully cashed on-chain
+	CashoutStateFullyCashedExternally
+	// CashoutStateDropped means the last cashout transaction was never mined and has been forgotten
+	CashoutStateDropped
+)
+
+func (st CashoutState) String() string {
+	switch st {
+	case CashoutStateNeverCashed:
+		return "never_cashed"
+	case CashoutStatePending:
+		return "pending"
+	case CashoutStateConfirmed:
+		return "confirmed"
+	case CashoutStateReverted:
+		return "reverted"
+	case CashoutStateFullyCashedExternally:
+		return "fully_cashed_externally"
+	case CashoutStateDropped:
+		return "dropped"
+	default:
+		return "unknown"
+	}
+}
+
 // CashoutStatus is information about the last cashout and uncashed amounts
 type CashoutStatus struct {
 	Last           *LastCashout // last cashout for a vault
 	UncashedAmount *big.Int     // amount not yet cashed out
+	State          CashoutState // state of the cashouts of the vault
+	ChainDerived   bool         // UncashedAmount is a best-effort estimate from on-chain data as there is no local cheque
+	Settled        bool         // the last cashout is confirmed without bouncing and nothing has been received since
 }
 
 // CashChequeResult summarizes the result of a CashCheque or CashChequeBeneficiary call
@@ -63,10 +346,29 @@ type CashChequeResult struct {
 	Bounced          bool           // indicates wether parts of the cheque bounced
 }
 
-// cashoutAction is the data we store for a cashout
-type cashoutAction struct {
-	TxHash common.Hash
-	Cheque SignedCheque // the cheque that was used to cashout which may be different from the latest cheque
+// CashoutAction is the data we store for a cashout
+type CashoutAction struct {
+	TxHash             common.Hash
+	Cheque             SignedCheque   // the cheque that was used to cashout which may be different from the latest cheque
+	RequestedRecipient common.Address // recipient requested by the caller
+	Recipient          common.Address // recipient used after applying the recipient policy
+	Nonce              uint64         // nonce of the cashout transaction
+	Submitted          int64          // unix nano time the cashout was sent, 0 for actions stored before it was recorded
+	Replacements       []common.Hash  // transactions which replaced the cashout transaction, oldest first
+	Label              string         // label given by the caller of the cashout
+	GasLimit           uint64         // gas limit forced for the cashout transaction, 0 if it was estimated
+}
+
+// CashoutOptions are optional per call settings of a cashout
+type CashoutOptions struct {
+	Nonce    *uint64   // explicit nonce of the cashout transaction, nil lets the transaction service assign one
+	Deadline time.Time // the cashout transaction is cancelled if it has not been mined by then, zero disables it
+
+	MinTokenPriceUSD float64 // the cashout is only sent if the vault token is worth at least this much, 0 disables it
+
+	Label string // free-form label stored with the action and the result, e.g. an accounting period
+
+	GasLimit uint64 // gas limit of the cashout transaction for chains with unreliable estimates, 0 estimates it
 }
 
 type CashOutResult struct {
@@ -75,6 +377,30 @@ type CashOutResult struct {
 	Amount   *big.Int
 	CashTime int64
 	Status   string
+	GasUsed  uint64
+	Label    string // label given by the caller of the cashout
+	Bounced  bool   // parts of the cheque bounced, only recorded for results stored after it was introduced
+	// Recipient received the payout, zero for failed cashouts and results stored before it was recorded
+	Recipient common.Address
+	// Submitted is the unix nano time the cashout was sent, 0 if it is not known
+	Submitted int64
+	// Beneficiary, Caller and CallerPayout are taken from the ChequeCashed event of successful cashouts, zero for
+	// results stored before they were recorded
+	Beneficiary  common.Address
+	Caller       common.Address
+	CallerPayout *big.Int
+	// VaultBalanceChange is the net change of the vault balance in the cashout transaction, nil for failed cashouts
+	// and results stored before it was recorded
+	VaultBalanceChange *big.Int
+	// Resolved marks a failed result whose failure was confirmed on-chain, e.g. by a reverted receipt. It is not
+	// re-checked by RecoverFailedResults.
+	Resolved bool
+	// Rebuilt marks a result reconstructed by RebuildHistoryFromChain. Its amount is not part of the cashed totals.
+	Rebuilt bool
+	// Token is the token whose totals the amount was added to, zero if the token totals were not updated
+	Token common.Address
+	// CashedCount is the number of received cheques the cashout added to the cashed count
+	CashedCount int
 }
 
 type chequeCashedEvent struct {
@@ -92,21 +418,50 @@ func NewCashoutService(
 	backend transaction.Backend,
 	transactionService transaction.Service,
 	chequeStore ChequeStore,
+	opts ...CashoutServiceOption,
 ) CashoutService {
-	return &cashoutService{
+	s := &cashoutService{
 		store:              store,
 		backend:            backend,
 		transactionService: transactionService,
 		chequeStore:        chequeStore,
+		recipientPolicy:    identityRecipientPolicy,
+		gasOracle:          backend,
+		confirmationPolicy: immediateConfirmation,
+		codec:              JSONCodec{},
+		metrics:            newMetrics(),
+		tracer:             noopTracer{},
+		retries:            newRetryQueue(),
+		quit:               make(chan struct{}),
+		now:                time.Now,
 	}
+	for _, o := range opts {
+		o.apply(s)
+	}
+	if s.repository == nil {
+		s.repository = newStatestoreRepository(store, s.codec)
+	}
+	if subscriber, ok := backend.(headSubscriber); ok {
+		s.headSubscriber = subscriber
+	}
+	s.initChainID()
+	return s
 }
 
+// cashoutActionPrefix is the prefix of the store key of the last cashout action
+const cashoutActionPrefix = "swap_cashout_"
+
 // cashoutActionKey computes the store key for the last cashout action for the vault
 func cashoutActionKey(vault common.Address) string {
-	return fmt.Sprintf("swap_cashout_%x", vault)
+	return fmt.Sprintf("%s%x", cashoutActionPrefix, vault)
 }
 
+// paidOut returns the amount the vault has paid out to the beneficiary, from the PaidOutProvider if one is set
 func (s *cashoutService) paidOut(ctx context.Context, vault, beneficiary common.Address) (*big.Int, error) {
+	if s.paidOutProvider != nil {
+		return s.providedPaidOut(ctx, vault, beneficiary)
+	}
+
 	callData, err := vaultABI.Pack("paidOut", beneficiary)
 	if err != nil {
 		return nil, err
@@ -136,165 +491,754 @@ func (s *cashoutService) paidOut(ctx context.Context, vault, beneficiary common.
 
 	return paidOut, nil
 }
+
+// CashoutResults returns all cashout results. The iteration order of the store depends on the backend, so the
+// results are sorted by CashTime and then TxHash to be deterministic.
 func (s *cashoutService) CashoutResults() ([]CashOutResult, error) {
 	result := make([]CashOutResult, 0, 0)
-	err := s.store.Iterate(statestore.CashoutResultPrefixKey(), func(key, val []byte) (stop bool, err error) {
-		cashOutResult := CashOutResult{}
-		err = s.store.Get(string(key), &cashOutResult)
-		if err != nil {
-			return false, err
-		}
+	err := s.repository.IterateResults(func(cashOutResult CashOutResult) (stop bool, err error) {
 		result = append(result, cashOutResult)
 		return false, nil
 	})
 	if err != nil {
 		return nil, err
 	}
+	sortCashoutResults(result)
+	return result, nil
+}
+
+// IterateCashoutResults calls fn for every cashout result while iterating the store, no slice of all results is
+// built. The results are passed in store order. The iteration stops when fn returns false or ctx is done.
+func (s *cashoutService) IterateCashoutResults(ctx context.Context, fn func(CashOutResult) bool) error {
+	return s.repository.IterateResults(func(cashOutResult CashOutResult) (stop bool, err error) {
+		if err := ctx.Err(); err != nil {
+			return true, err
+		}
+		return !fn(cashOutResult), nil
+	})
+}
+
+// CashoutResultsByLabel returns the cashout results with the given label in the order of CashoutResults
+func (s *cashoutService) CashoutResultsByLabel(label string) ([]CashOutResult, error) {
+	result := make([]CashOutResult, 0)
+	err := s.repository.IterateResults(func(cashOutResult CashOutResult) (stop bool, err error) {
+		if cashOutResult.Label == label {
+			result = append(result, cashOutResult)
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sortCashoutResults(result)
 	return result, nil
 }
 
-// CashCheque sends a cashout transaction for the last cheque of the vault
+// sortCashoutResults orders results by CashTime, ties are broken by TxHash
+func sortCashoutResults(results []CashOutResult) {
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].CashTime != results[j].CashTime {
+			return results[i].CashTime < results[j].CashTime
+		}
+		return bytes.Compare(results[i].TxHash.Bytes(), results[j].TxHash.Bytes()) < 0
+	})
+}
+
+// CashedVaults returns the distinct vaults which have a cashout action or result, sorted by address.
+func (s *cashoutService) CashedVaults() ([]common.Address, error) {
+	return s.repository.Vaults()
+}
+
+// CashCheque sends a cashout transaction for the last cheque of the vault. A zero recipient means the default
+// recipient of the vault.
 func (s *cashoutService) CashCheque(ctx context.Context, vault, recipient common.Address) (common.Hash, error) {
-	cheque, err := s.chequeStore.LastReceivedCheque(vault)
+	return s.CashChequeWithOptions(ctx, vault, recipient, CashoutOptions{})
+}
+
+// CashChequeWithOptions sends a cashout transaction for the last cheque of the vault using the given options.
+// If sending fails but still yields a transaction hash the cashout is tracked anyway and the hash is returned
+// together with the error. In dry run mode nothing is sent and the error wraps ErrDryRun.
+func (s *cashoutService) CashChequeWithOptions(ctx context.Context, vault, recipient common.Address, opts CashoutOptions) (common.Hash, error) {
+	ctx, span := s.startSpan(ctx, "vault.CashCheque")
+	span.SetAttribute(TraceAttributeVault, vault.Hex())
+	txHash, dryRun, err := s.cashChequeWithOptions(ctx, vault, recipient, opts, s.dryRun)
+	if dryRun != nil {
+		log.Infof("dry run: not sending cashout of vault %x", vault)
+		err = newCashoutError(CashoutPhaseSend, fmt.Errorf("%w: %v", ErrDryRun, dryRun))
+	}
+	if txHash != (common.Hash{}) {
+		span.SetAttribute(TraceAttributeTxHash, txHash.Hex())
+	}
+	endSpan(span, err)
+	return txHash, err
+}
+
+// PreviewCashout runs all checks of a cashout of the last cheque of the vault, estimates and simulates it and
+// returns the transaction which would be sent. Nothing is sent or stored.
+func (s *cashoutService) PreviewCashout(ctx context.Context, vault, recipient common.Address, opts CashoutOptions) (*DryRunResult, error) {
+	_, dryRun, err := s.cashChequeWithOptions(ctx, vault, recipient, opts, true)
+	return dryRun, err
+}
+
+// cashChequeWithOptions sends the cashout of the last cheque of the vault. With dryRun it stops before sending
+// and returns the transaction it would have sent instead, without storing anything.
+func (s *cashoutService) cashChequeWithOptions(ctx context.Context, vault, recipient common.Address, opts CashoutOptions, dryRun bool) (common.Hash, *DryRunResult, error) {
+	cheque, err := s.lastReceivedCheque(vault)
 	if err != nil {
-		return common.Hash{}, err
+		return common.Hash{}, nil, newCashoutError(CashoutPhaseLookup, err)
 	}
+	spanFromContext(ctx).SetAttribute(TraceAttributeAmount, cheque.CumulativePayout.String())
 
-	callData, err := vaultABI.Pack("cashChequeBeneficiary", recipient, cheque.CumulativePayout, cheque.Signature)
+	err = s.checkVaultDestroyed(ctx, vault, cheque, !dryRun)
 	if err != nil {
-		return common.Hash{}, err
+		return common.Hash{}, nil, newCashoutError(CashoutPhaseValidate, err)
+	}
+
+	recipient, err = s.resolveRecipient(vault, recipient)
+	if err != nil {
+		return common.Hash{}, nil, newCashoutError(CashoutPhaseLookup, err)
+	}
+
+	effectiveRecipient, err := s.recipientPolicy(vault, recipient)
+	if err != nil {
+		return common.Hash{}, nil, newCashoutError(CashoutPhasePolicy, err)
+	}
+	if effectiveRecipient != recipient {
+		log.Infof("cashout of vault %x: recipient policy changed recipient from %x to %x", vault, recipient, effectiveRecipient)
+	}
+
+	err = s.checkRecipientAllowed(effectiveRecipient)
+	if err != nil {
+		return common.Hash{}, nil, newCashoutError(CashoutPhasePolicy, err)
+	}
+
+	err = s.checkBeneficiary(cheque)
+	if err != nil {
+		return common.Hash{}, nil, newCashoutError(CashoutPhaseValidate, err)
+	}
+
+	err = s.checkChainID(ctx)
+	if err != nil {
+		return common.Hash{}, nil, newCashoutError(CashoutPhaseValidate, err)
+	}
+
+	err = s.checkDailyGasBudget()
+	if err != nil {
+		return common.Hash{}, nil, newCashoutError(CashoutPhaseValidate, err)
+	}
+
+	err = s.checkDeadline(opts.Deadline)
+	if err != nil {
+		return common.Hash{}, nil, newCashoutError(CashoutPhaseValidate, err)
+	}
+
+	err = checkGasLimit(opts.GasLimit)
+	if err != nil {
+		return common.Hash{}, nil, newCashoutError(CashoutPhaseValidate, err)
+	}
+
+	err = s.checkTokenPrice(ctx, vault, opts.MinTokenPriceUSD)
+	if err != nil {
+		return common.Hash{}, nil, newCashoutError(CashoutPhaseValidate, err)
+	}
+
+	if opts.Nonce != nil {
+		err = s.checkNonce(ctx, *opts.Nonce)
+		if err != nil {
+			return common.Hash{}, nil, newCashoutError(CashoutPhaseValidate, err)
+		}
+		ctx = sctx.SetNonce(ctx, *opts.Nonce)
+	}
+
+	if s.chequeFreshnessCheck {
+		cheque, err = s.freshestCheque(vault, cheque)
+		if err != nil {
+			return common.Hash{}, nil, newCashoutError(CashoutPhaseLookup, err)
+		}
+	}
+
+	err = s.verifyChequeSignature(ctx, cheque)
+	if err != nil {
+		return common.Hash{}, nil, newCashoutError(CashoutPhaseValidate, err)
+	}
+
+	// estimating the gas takes a call to the backend, it is only done if the preflight or the dry run uses it
+	var gas uint64
+	if s.beneficiary != (common.Address{}) || dryRun {
+		gas = s.cashoutGas(ctx, vault, effectiveRecipient, opts.GasLimit)
+	}
+	err = s.checkGasBalance(ctx, gas)
+	if err != nil {
+		return common.Hash{}, nil, newCashoutError(CashoutPhaseValidate, err)
+	}
+
+	callData, err := vaultABI.Pack("cashChequeBeneficiary", effectiveRecipient, cheque.CumulativePayout, cheque.Signature)
+	if err != nil {
+		return common.Hash{}, nil, newCashoutError(CashoutPhasePack, err)
+	}
+	if dryRun {
+		return common.Hash{}, &DryRunResult{
+			To:       vault,
+			CallData: callData,
+			Cashouts: []DryRunCashout{s.dryRunCashout(ctx, vault, recipient, effectiveRecipient, cheque, gas)},
+		}, nil
 	}
 	request := &transaction.TxRequest{
 		To:          &vault,
 		Data:        callData,
+		GasLimit:    opts.GasLimit,
 		Value:       big.NewInt(0),
 		Description: "cheque cashout",
 	}
 
-	txHash, err := s.transactionService.Send(ctx, request)
+	intent := &cashoutIntent{
+		Vault:              vault,
+		Cheque:             *cheque,
+		RequestedRecipient: recipient,
+		Recipient:          effectiveRecipient,
+		CallData:           callData,
+		Label:              opts.Label,
+	}
+	intentKey, err := s.startCashout(intent)
+	if err != nil {
+		return common.Hash{}, nil, err
+	}
+	request.Description = cashoutTxDescription(CashoutTxMetadata{
+		Vault:  vault,
+		Amount: cheque.CumulativePayout,
+		Label:  opts.Label,
+		Key:    intentKey,
+	}, request.Description)
+
+	txHash, sendErr := s.sendTransaction(ctx, request)
+	if sendErr != nil {
+		if txHash == (common.Hash{}) {
+			s.clearCashoutIntent(intentKey)
+			return common.Hash{}, nil, newCashoutError(CashoutPhaseSend, sendErr)
+		}
+		// the transaction might have been broadcast anyway, it is tracked like any other so it does not go unnoticed
+		log.Warnf("cashout of vault %x: send of %x failed, tracking it in case it was broadcast: %v", vault, txHash, sendErr)
+	}
+
+	intent.TxHash = txHash
+	err = s.store.Put(intentKey, intent)
+	if err != nil {
+		return common.Hash{}, nil, newCashoutError(CashoutPhaseStore, err)
+	}
+
+	// record the nonce so the cashout can be replaced or cancelled later
+	var nonce uint64
+	stored, err := s.transactionService.StoredTransaction(txHash)
 	if err != nil {
-		return common.Hash{}, err
+		log.Infof("cashout: could not get stored transaction %x: %v", txHash, err)
+	} else {
+		nonce = stored.Nonce
 	}
 
-	err = s.store.Put(cashoutActionKey(vault), &cashoutAction{
-		TxHash: txHash,
-		Cheque: *cheque,
+	err = s.repository.PutAction(vault, &CashoutAction{
+		TxHash:             txHash,
+		Cheque:             *cheque,
+		RequestedRecipient: recipient,
+		Recipient:          effectiveRecipient,
+		Nonce:              nonce,
+		Submitted:          s.now().UnixNano(),
+		Label:              opts.Label,
+		GasLimit:           opts.GasLimit,
 	})
 	if err != nil {
-		return common.Hash{}, err
+		return common.Hash{}, nil, newCashoutError(CashoutPhaseStore, err)
+	}
+	s.recordCashoutEvent(s.store, vault, txHash, CashoutEventSubmitted)
+
+	if !opts.Deadline.IsZero() {
+		s.cancelAfterDeadline(vault, txHash, opts.Deadline)
+	}
+	s.trackCashout(ctx, vault, txHash, cheque, opts.Label, intentKey, false)
+	if sendErr != nil {
+		return txHash, nil, newCashoutError(CashoutPhaseSend, sendErr)
+	}
+	return txHash, nil, nil
+}
+
+// startCashout writes the write-ahead log record of the cashout unless the maximum number of pending cashouts has been reached
+func (s *cashoutService) startCashout(intent *cashoutIntent) (string, error) {
+	s.inFlightLock.Lock()
+	defer s.inFlightLock.Unlock()
+
+	if s.maxInFlight > 0 {
+		pending, err := s.pendingCashouts()
+		if err != nil {
+			return "", newCashoutError(CashoutPhaseValidate, err)
+		}
+		if pending >= s.maxInFlight {
+			return "", newCashoutError(CashoutPhaseValidate, ErrTooManyInFlight)
+		}
+	}
+
+	intentKey, err := s.newCashoutIntent(intent)
+	if err != nil {
+		return "", newCashoutError(CashoutPhaseStore, err)
+	}
+	return intentKey, nil
+}
+
+// freshestCheque re-reads the last received cheque of the vault and returns it instead of cheque if a newer one
+// with a higher cumulative payout arrived in the meantime
+func (s *cashoutService) freshestCheque(vault common.Address, cheque *SignedCheque) (*SignedCheque, error) {
+	latest, err := s.lastReceivedCheque(vault)
+	if err != nil {
+		return nil, err
+	}
+	if latest.CumulativePayout.Cmp(cheque.CumulativePayout) > 0 {
+		log.Infof("cashout of vault %x: newer cheque arrived, cashing cumulative payout %d instead of %d", vault, latest.CumulativePayout, cheque.CumulativePayout)
+		return latest, nil
+	}
+	return cheque, nil
+}
+
+// checkNonce verifies that an explicit nonce has not already been used by the sending account
+func (s *cashoutService) checkNonce(ctx context.Context, nonce uint64) error {
+	if s.beneficiary == (common.Address{}) {
+		return nil
+	}
+	accountNonce, err := s.backend.NonceAt(ctx, s.beneficiary, nil)
+	if err != nil {
+		return err
+	}
+	if nonce < accountNonce {
+		return fmt.Errorf("%w: nonce %d, account nonce %d", ErrNonceTooLow, nonce, accountNonce)
+	}
+	return nil
+}
+
+// checkBeneficiary verifies that the cheque can be cashed by this node. cashChequeBeneficiary pays out to the
+// beneficiary sending the transaction, so a cheque issued to a different beneficiary is guaranteed to revert.
+// The vault contract does not store a beneficiary of its own, so the cheque is compared to the sending account.
+func (s *cashoutService) checkBeneficiary(cheque *SignedCheque) error {
+	if s.beneficiary == (common.Address{}) {
+		return nil
 	}
+	if cheque.Beneficiary != s.beneficiary {
+		if s.allowArbitraryBeneficiary {
+			log.Warnf("TESTNET ONLY: cashing cheque of vault %x for beneficiary %x although cashout is sent by %x, arbitrary beneficiaries are allowed", cheque.Vault, cheque.Beneficiary, s.beneficiary)
+			return nil
+		}
+		return fmt.Errorf("%w: cheque is for %x, but cashout is sent by %x", ErrBeneficiaryMismatch, cheque.Beneficiary, s.beneficiary)
+	}
+	return nil
+}
 
+// trackCashout waits for the cashout transaction in the background, stores its result and then clears the
+// write-ahead log record of the cashout. The span of origin is linked to the span of storing the result.
+func (s *cashoutService) trackCashout(origin context.Context, vault common.Address, txHash common.Hash, cheque *SignedCheque, label, intentKey string, retry bool) {
+	if retry {
+		s.metrics.RetryingCashouts.Inc()
+		s.retries.add(1)
+	}
+	callback := resultCallbackFromContext(origin)
+	// registered before the tracker starts so a drop cannot slip in between
+	trackCtx := s.trackers.add(context.Background(), txHash)
 	// WaitForReceipt takes long time
 	go func() {
 		defer func() {
 			if r := recover(); r != nil {
-				log.Errorf("storeCashResult recovered:%+v", err)
+				log.Errorf("storeCashResult recovered:%+v", r)
+				s.trackers.remove(txHash)
+				callback.invoke(nil, fmt.Errorf("tracking cashout %x: %v", txHash, r))
+			}
+			if retry {
+				s.metrics.RetryingCashouts.Dec()
+				s.retries.add(-1)
 			}
 		}()
-		s.storeCashResult(context.Background(), vault, txHash, cheque)
+		ctx := trackCtx
+		if retry {
+			ctx = withRetry(ctx)
+		}
+		ctx, span := s.startSpan(ctx, "vault.storeCashResult", origin)
+		span.SetAttribute(TraceAttributeVault, vault.Hex())
+		span.SetAttribute(TraceAttributeTxHash, txHash.Hex())
+		result, err := s.storeCashResult(ctx, vault, txHash, cheque, label)
+		endSpan(span, err)
+		if s.trackers.remove(txHash) {
+			// the write-ahead log record was deleted together with the dropped action
+			callback.invoke(nil, ErrCashoutDropped)
+			return
+		}
+		s.clearCashoutIntent(intentKey)
+		callback.invoke(result, err)
 	}()
-	return txHash, nil
 }
 
-func (s *cashoutService) storeCashResult(ctx context.Context, vault common.Address, txHash common.Hash, cheque *SignedCheque) error {
+// storeCashResult waits for the cashout transaction and stores its result. The result is nil if it could not be stored.
+func (s *cashoutService) storeCashResult(ctx context.Context, vault common.Address, txHash common.Hash, cheque *SignedCheque, label string) (*CashOutResult, error) {
 	cashResult := CashOutResult{
 		TxHash:   txHash,
 		Vault:    vault,
 		Amount:   cheque.CumulativePayout,
-		CashTime: time.Now().Unix(),
+		CashTime: s.now().Unix(),
 		Status:   "fail",
+		Label:    label,
+	}
+	cashResult.Submitted = s.submittedAt(vault, txHash)
+	// all bookkeeping is collected in a batch so a crash cannot leave the totals half applied
+	batch := newStoreBatch(s.store)
+	receipt, err := s.waitForMinedReceipt(ctx, txHash)
+	if err == nil {
+		s.recordCashoutEvent(s.store, vault, txHash, CashoutEventConfirming)
+		receipt, err = s.waitForConfirmation(ctx, txHash, receipt)
+	}
+	if err != nil && s.trackers.isDropped(txHash) {
+		// the cashout was dropped while we were waiting, there is no result to store
+		return nil, ErrCashoutDropped
+	}
+	var token common.Address
+	if err == nil && receipt.Status == types.ReceiptStatusSuccessful {
+		token = s.cashoutToken(ctx, vault)
+	}
+	// concurrent cashouts update the same totals, their read-modify-write must not interleave
+	s.totalsLock.Lock()
+	// the transaction may have been recorded meanwhile, e.g. by a recovery pass, its totals must not be applied twice
+	appliedTime, ok, appliedErr := appliedCashTime(batch, vault, txHash)
+	if appliedErr != nil || ok {
+		s.totalsLock.Unlock()
+		if appliedErr != nil {
+			log.Errorf("CashOutStats:get applied marker err:%+v", appliedErr)
+			return nil, appliedErr
+		}
+		log.Infof("cashout: result of %x already recorded", txHash)
+		return s.appliedResult(vault, txHash, appliedTime)
 	}
-	_, err := s.transactionService.WaitForReceipt(ctx, txHash)
 	if err != nil {
 		log.Infof("storeCashResult err:%+v", err)
 	} else {
-		cs, err := s.CashoutStatus(ctx, vault)
+		if receipt.Status == types.ReceiptStatusFailed {
+			s.recordCashoutEvent(batch, vault, txHash, CashoutEventReverted)
+			cashResult.Resolved = true
+		} else {
+			s.recordCashoutEvent(batch, vault, txHash, CashoutEventConfirmed)
+		}
+
+		err = s.recordGasSpent(batch, txHash, cashResult.CashTime, receipt)
+		if err != nil {
+			log.Infof("CashOutStats:put daily cashout gas err:%+v", err)
+		}
+
+		cashResult.GasUsed = receipt.GasUsed
+		if receipt.Status == types.ReceiptStatusSuccessful {
+			events, err := ParseVaultEvents(receipt, vault)
+			if err != nil {
+				log.Infof("CashOutStats:parse vault events err:%+v", err)
+			} else {
+				cashResult.VaultBalanceChange = vaultBalanceChange(events)
+			}
+		}
+		err = updateGasAverage(batch, receipt.GasUsed)
 		if err != nil {
-			log.Infof("CashOutStats:get cashout status err:%+v", err)
-			if cs.UncashedAmount != nil {
-				cashResult.Amount = cs.UncashedAmount
+			log.Infof("CashOutStats:put cashout gas average err:%+v", err)
+		}
+
+		if s.storeRawReceipts {
+			err = batch.Put(rawReceiptKey(txHash), newStoredReceipt(receipt, s.now()))
+			if err != nil {
+				log.Infof("CashOutStats:put raw receipt err:%+v", err)
 			}
+		}
+
+		// the payout is taken from the tracked transaction itself, the latest action of the vault might be another one
+		cashed, err := s.parseCashChequeBeneficiaryReceipt(vault, receipt)
+		if err != nil {
+			log.Infof("CashOutStats:parse cashout receipt err:%+v", err)
 		} else {
 			// update totalReceivedCashed
 			totalPaidOut := big.NewInt(0)
-			if cs.Last != nil && cs.Last.Result != nil && cs.Last.Result.TotalPayout != nil {
-				totalPaidOut = cs.Last.Result.TotalPayout
+			if cashed.TotalPayout != nil {
+				totalPaidOut = cashed.TotalPayout
 			}
 			cashResult.Amount = totalPaidOut
 			cashResult.Status = "success"
-			totalReceivedCashed := big.NewInt(0)
-			if err = s.store.Get(statestore.TotalReceivedCashedKey, &totalReceivedCashed); err == nil || err == storage.ErrNotFound {
-				totalReceivedCashed = totalReceivedCashed.Add(totalReceivedCashed, totalPaidOut)
-				err := s.store.Put(statestore.TotalReceivedCashedKey, totalReceivedCashed)
-				if err != nil {
-					log.Infof("CashOutStats:put totalReceivedCashdKey err:%+v", err)
-				}
+			cashResult.Bounced = cashed.Bounced
+			cashResult.Recipient = cashed.Recipient
+			cashResult.Beneficiary = cashed.Beneficiary
+			cashResult.Caller = cashed.Caller
+			cashResult.CallerPayout = cashed.CallerPayout
+			if cashResult.Bounced {
+				s.recordCashoutEvent(batch, vault, txHash, CashoutEventBounced)
 			}
-
-			totalDailyReceivedCashed := big.NewInt(0)
-			if err = s.store.Get(statestore.GetTodayTotalDailyReceivedCashedKey(), &totalDailyReceivedCashed); err == nil || err == storage.ErrNotFound {
-				totalDailyReceivedCashed = totalDailyReceivedCashed.Add(totalDailyReceivedCashed, totalPaidOut)
-				err := s.store.Put(statestore.GetTodayTotalDailyReceivedCashedKey(), totalDailyReceivedCashed)
-				if err != nil {
-					log.Infof("CashOutStats:put totalReceivedDailyCashdKey err:%+v", err)
-				}
+			s.addCashedTotals(batch, token, cashResult.CashTime, totalPaidOut, cashed.CallerPayout)
+			cashResult.Token = token
+			cashResult.CashedCount = s.addCashedCount(batch, vault)
+			err = markResultApplied(batch, &cashResult)
+			if err != nil {
+				log.Infof("CashOutStats:put applied marker err:%+v", err)
 			}
+		}
+	}
+	err = s.batchRepository(batch).PutResult(&cashResult)
+	if err != nil {
+		log.Infof("CashOutStats:put cashoutResultKey err:%+v", err)
+	}
+	err = batch.Put(statestore.LastCashoutTimeKey, cashResult.CashTime)
+	if err != nil {
+		log.Infof("CashOutStats:put lastCashoutTimeKey err:%+v", err)
+	}
+	err = batch.Commit()
+	s.totalsLock.Unlock()
+	if err != nil {
+		log.Errorf("CashOutStats:commit cashout result err:%+v", err)
+		return nil, err
+	}
+	s.evictResults()
+	span := spanFromContext(ctx)
+	span.SetAttribute(TraceAttributeAmount, cashResult.Amount.String())
+	span.SetAttribute(TraceAttributeStatus, cashResult.Status)
+	if cashResult.Status == "success" {
+		s.observeCashout(vault, cashResult.Amount, cashResult.Bounced)
+	}
+	s.publishCashoutResult(cashResult)
+	s.publishResultMessage(ctx, cashResult)
+	return &cashResult, nil
+}
+
+// addCashedTotals adds a successful cashout to the received cashed totals, the daily totals of the day of cashTime
+// are credited. The token totals are skipped if token is zero. Failures are logged, the remaining totals are still
+// updated.
+func (s *cashoutService) addCashedTotals(batch storeReadWriter, token common.Address, cashTime int64, totalPaidOut, callerPayout *big.Int) {
+	day := dayUnix(time.Unix(cashTime, 0))
+	// update totalReceivedCashed
+	totalReceivedCashed := big.NewInt(0)
+	err := batch.Get(statestore.TotalReceivedCashedKey, &totalReceivedCashed)
+	if err == nil || err == storage.ErrNotFound {
+		totalReceivedCashed = totalReceivedCashed.Add(totalReceivedCashed, totalPaidOut)
+		err := batch.Put(statestore.TotalReceivedCashedKey, totalReceivedCashed)
+		if err != nil {
+			log.Infof("CashOutStats:put totalReceivedCashdKey err:%+v", err)
+		}
+	}
 
-			// update TotalReceivedCountCashed
-			uncashed := 0
-			err := s.store.Get(statestore.PeerReceivedUncashRecordsCountKey(vault), &uncashed)
+	totalDailyReceivedCashed := big.NewInt(0)
+	if err = batch.Get(statestore.GetTotalDailyReceivedCashedKeyByTime(day), &totalDailyReceivedCashed); err == nil || err == storage.ErrNotFound {
+		totalDailyReceivedCashed = totalDailyReceivedCashed.Add(totalDailyReceivedCashed, totalPaidOut)
+		err := batch.Put(statestore.GetTotalDailyReceivedCashedKeyByTime(day), totalDailyReceivedCashed)
+		if err != nil {
+			log.Infof("CashOutStats:put totalReceivedDailyCashdKey err:%+v", err)
+		}
+	}
+
+	// update totalCallerPayout
+	if callerPayout != nil {
+		totalCallerPayout := big.NewInt(0)
+		if err = batch.Get(statestore.TotalCallerPayoutKey, &totalCallerPayout); err == nil || err == storage.ErrNotFound {
+			err := batch.Put(statestore.TotalCallerPayoutKey, totalCallerPayout.Add(totalCallerPayout, callerPayout))
 			if err != nil {
-				log.Infof("CashOutStats:put totalReceivedCountCashed err:%+v", err)
-			} else {
-				cashedCount := 0
-				err := s.store.Get(statestore.TotalReceivedCashedCountKey, &cashedCount)
-				if err == nil || err == storage.ErrNotFound {
-					err := s.store.Put(statestore.TotalReceivedCashedCountKey, cashedCount+uncashed)
-					if err != nil {
-						log.Infof("CashOutStats:put totalReceivedCashedConuntKey err:%+v", err)
-					} else {
-						err := s.store.Put(statestore.PeerReceivedUncashRecordsCountKey(vault), 0)
-						if err != nil {
-							log.Infof("CashOutStats:put totalReceivedCashedConuntKey err:%+v", err)
-						}
-					}
-				}
+				log.Infof("CashOutStats:put totalCallerPayoutKey err:%+v", err)
 			}
 		}
 	}
-	err = s.store.Put(statestore.CashoutResultKey(vault), &cashResult)
+
+	// update the token namespaced totals
+	if token != (common.Address{}) {
+		err = addTokenReceivedCashed(batch, token, day, totalPaidOut)
+		if err != nil {
+			log.Infof("CashOutStats:put token totalReceivedCashed err:%+v", err)
+		}
+	}
+}
+
+// addCashedCount moves the uncashed cheque records of the vault to the cashed count and returns how many were moved.
+// Failures are logged.
+func (s *cashoutService) addCashedCount(batch storeReadWriter, vault common.Address) int {
+	// update TotalReceivedCountCashed
+	uncashed := 0
+	err := batch.Get(statestore.PeerReceivedUncashRecordsCountKey(vault), &uncashed)
 	if err != nil {
-		log.Infof("CashOutStats:put cashoutResultKey err:%+v", err)
+		log.Infof("CashOutStats:put totalReceivedCountCashed err:%+v", err)
+		return 0
 	}
-	return nil
+	cashedCount := 0
+	err = batch.Get(statestore.TotalReceivedCashedCountKey, &cashedCount)
+	if err != nil && err != storage.ErrNotFound {
+		log.Infof("CashOutStats:get totalReceivedCashedConuntKey err:%+v", err)
+		return 0
+	}
+	err = batch.Put(statestore.TotalReceivedCashedCountKey, cashedCount+uncashed)
+	if err != nil {
+		log.Infof("CashOutStats:put totalReceivedCashedConuntKey err:%+v", err)
+		return 0
+	}
+	err = batch.Put(statestore.PeerReceivedUncashRecordsCountKey(vault), 0)
+	if err != nil {
+		log.Infof("CashOutStats:put totalReceivedCashedConuntKey err:%+v", err)
+	}
+	return uncashed
+}
+
+// uncashedAmount returns the part of cumulativePayout not covered by cashed. It never goes below zero, a cashed
+// amount above the cheque means the cheque is older than the cashout it is compared to which indicates inconsistent data.
+func uncashedAmount(vault common.Address, cumulativePayout, cashed *big.Int) *big.Int {
+	uncashed := new(big.Int).Sub(cumulativePayout, cashed)
+	if uncashed.Sign() < 0 {
+		log.Warnf("cashout: cashed amount %d of vault %x exceeds cheque cumulative payout %d", cashed, vault, cumulativePayout)
+		return big.NewInt(0)
+	}
+	return uncashed
+}
+
+// isStale reports whether the cashout action was submitted longer than the pending stale threshold ago
+func (s *cashoutService) isStale(action *CashoutAction) bool {
+	if s.pendingStaleAfter <= 0 || action.Submitted == 0 {
+		return false
+	}
+	return s.now().Sub(time.Unix(0, action.Submitted)) > s.pendingStaleAfter
+}
+
+// dropCashoutAction forgets a cashout action whose transaction was never mined and computes the uncashed amount from the on-chain paidOut
+func (s *cashoutService) dropCashoutAction(ctx context.Context, vaultAddress common.Address, cheque *SignedCheque, action *CashoutAction) (*CashoutStatus, error) {
+	paidOut, err := s.paidOut(ctx, vaultAddress, cheque.Beneficiary)
+	if err != nil {
+		return nil, err
+	}
+
+	intentKey, hasIntent, err := s.cashoutIntentKeyOf(vaultAddress, action.TxHash)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("cashout: transaction %x for vault %x was not found after %v, dropping it", action.TxHash, vaultAddress, s.pendingStaleAfter)
+	// the write-ahead log record goes with the action, otherwise the next start would replay the action
+	batch := newStoreBatch(s.store)
+	err = s.batchRepository(batch).DeleteAction(vaultAddress)
+	if err != nil {
+		return nil, err
+	}
+	if hasIntent {
+		err = batch.Delete(intentKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+	s.recordCashoutEvent(batch, vaultAddress, action.TxHash, CashoutEventDropped)
+	err = batch.Commit()
+	if err != nil {
+		return nil, err
+	}
+	if hasIntent {
+		s.metrics.PendingCashouts.Dec()
+	}
+	s.trackers.drop(action.TxHash)
+
+	return &CashoutStatus{
+		Last: &LastCashout{
+			TxHash:   action.TxHash,
+			Cheque:   action.Cheque,
+			Result:   nil,
+			Reverted: false,
+		},
+		UncashedAmount: uncashedAmount(vaultAddress, cheque.CumulativePayout, paidOut),
+		State:          CashoutStateDropped,
+	}, nil
+}
+
+// chainDerivedStatus estimates the uncashed amount of a vault for which there is no local cheque.
+// If received cheque records are left their sum minus the on-chain paidOut is used, capped at the vault balance as
+// nothing more can be cashed. Without any records nothing is known to be owed to this node, the vault balance is
+// the collateral of the issuer, so the uncashed amount is zero.
+func (s *cashoutService) chainDerivedStatus(ctx context.Context, vaultAddress common.Address) (*CashoutStatus, error) {
+	records, err := s.chequeStore.ReceivedChequeRecordsByPeer(vaultAddress)
+	if err != nil && !errors.Is(err, ErrNoChequeRecords) {
+		return nil, err
+	}
+
+	uncashed := big.NewInt(0)
+	state := CashoutStateNeverCashed
+	if len(records) > 0 {
+		totalBalance, err := newVaultContract(vaultAddress, s.transactionService).TotalBalance(ctx)
+		if err != nil {
+			return nil, err
+		}
+		received := big.NewInt(0)
+		for _, record := range records {
+			received.Add(received, record.Amount)
+		}
+		paidOut, err := s.paidOut(ctx, vaultAddress, records[len(records)-1].Beneficiary)
+		if err != nil {
+			return nil, err
+		}
+		if paidOut.Sign() > 0 && paidOut.Cmp(received) >= 0 {
+			state = CashoutStateFullyCashedExternally
+		}
+		uncashed = uncashedAmount(vaultAddress, received, paidOut)
+		if uncashed.Cmp(totalBalance) > 0 {
+			uncashed = totalBalance
+		}
+	}
+
+	return &CashoutStatus{
+		Last:           nil,
+		UncashedAmount: uncashed,
+		State:          state,
+		ChainDerived:   true,
+	}, nil
 }
 
 // CashoutStatus gets the status of the latest cashout transaction for the vault
 func (s *cashoutService) CashoutStatus(ctx context.Context, vaultAddress common.Address) (*CashoutStatus, error) {
-	cheque, err := s.chequeStore.LastReceivedCheque(vaultAddress)
+	ctx, span := s.startSpan(ctx, "vault.CashoutStatus")
+	span.SetAttribute(TraceAttributeVault, vaultAddress.Hex())
+	status, err := s.cashoutStatus(ctx, vaultAddress)
+	endSpan(span, err)
+	return status, err
+}
+
+func (s *cashoutService) cashoutStatus(ctx context.Context, vaultAddress common.Address) (*CashoutStatus, error) {
+	cheque, err := s.lastReceivedCheque(vaultAddress)
+	if err != nil {
+		if errors.Is(err, ErrNoCheque) {
+			return s.chainDerivedStatus(ctx, vaultAddress)
+		}
+		return nil, err
+	}
+
+	err = s.checkVaultDestroyed(ctx, vaultAddress, cheque, !s.dryRun)
 	if err != nil {
 		return nil, err
 	}
 
-	var action cashoutAction
-	err = s.store.Get(cashoutActionKey(vaultAddress), &action)
+	action, err := s.repository.Action(vaultAddress)
 	if err != nil {
 		if errors.Is(err, storage.ErrNotFound) {
+			// there is no local cashout, the cheque might still have been cashed on-chain by someone else
+			paidOut, err := s.paidOut(ctx, vaultAddress, cheque.Beneficiary)
+			if err != nil {
+				return nil, err
+			}
+			state := CashoutStateNeverCashed
+			if paidOut.Sign() > 0 && paidOut.Cmp(cheque.CumulativePayout) >= 0 {
+				state = CashoutStateFullyCashedExternally
+			}
 			return &CashoutStatus{
 				Last:           nil,
-				UncashedAmount: cheque.CumulativePayout, // if we never cashed out, assume everything is uncashed
+				UncashedAmount: uncashedAmount(vaultAddress, cheque.CumulativePayout, paidOut),
+				State:          state,
 			}, nil
 		}
 		return nil, err
 	}
 
-	_, pending, err := s.backend.TransactionByHash(ctx, action.TxHash)
-	if err != nil {
-		// treat not found as pending
-		if !errors.Is(err, ethereum.NotFound) {
-			return nil, err
+	// a prefetched receipt means the transaction was mined
+	pending := false
+	if !hasPrefetchedReceipt(ctx, action.TxHash) {
+		_, pending, err = s.transactionByHash(ctx, action.TxHash)
+		if err != nil {
+			// treat not found as pending
+			if !errors.Is(err, ethereum.NotFound) {
+				return nil, err
+			}
+			if s.isStale(action) {
+				return s.dropCashoutAction(ctx, vaultAddress, cheque, action)
+			}
+			pending = true
 		}
-		pending = true
 	}
 
 	if pending {
@@ -306,11 +1250,12 @@ func (s *cashoutService) CashoutStatus(ctx context.Context, vaultAddress common.
 				Reverted: false,
 			},
 			// uncashed is the difference since the last sent cashout. we assume that the entire cheque will clear in the pending transaction.
-			UncashedAmount: new(big.Int).Sub(cheque.CumulativePayout, action.Cheque.CumulativePayout),
+			UncashedAmount: uncashedAmount(vaultAddress, cheque.CumulativePayout, action.Cheque.CumulativePayout),
+			State:          CashoutStatePending,
 		}, nil
 	}
 
-	receipt, err := s.backend.TransactionReceipt(ctx, action.TxHash)
+	receipt, err := s.transactionReceipt(ctx, action.TxHash)
 	if err != nil {
 		return nil, err
 	}
@@ -330,7 +1275,8 @@ func (s *cashoutService) CashoutStatus(ctx context.Context, vaultAddress common.
 				Result:   nil,
 				Reverted: true,
 			},
-			UncashedAmount: new(big.Int).Sub(cheque.CumulativePayout, paidOut),
+			UncashedAmount: uncashedAmount(vaultAddress, cheque.CumulativePayout, paidOut),
+			State:          CashoutStateReverted,
 		}, nil
 	}
 
@@ -339,6 +1285,8 @@ func (s *cashoutService) CashoutStatus(ctx context.Context, vaultAddress common.
 		return nil, err
 	}
 
+	// uncashed is the difference since the last sent (and confirmed) cashout.
+	uncashed := uncashedAmount(vaultAddress, cheque.CumulativePayout, result.CumulativePayout)
 	return &CashoutStatus{
 		Last: &LastCashout{
 			TxHash:   action.TxHash,
@@ -346,8 +1294,9 @@ func (s *cashoutService) CashoutStatus(ctx context.Context, vaultAddress common.
 			Result:   result,
 			Reverted: false,
 		},
-		// uncashed is the difference since the last sent (and confirmed) cashout.
-		UncashedAmount: new(big.Int).Sub(cheque.CumulativePayout, result.CumulativePayout),
+		UncashedAmount: uncashed,
+		State:          CashoutStateConfirmed,
+		Settled:        uncashed.Sign() == 0 && !result.Bounced,
 	}, nil
 }
 
@@ -357,8 +1306,7 @@ func (s *cashoutService) parseCashChequeBeneficiaryReceipt(vaultAddress common.A
 		Bounced: false,
 	}
 
-	var cashedEvent chequeCashedEvent
-	err := transaction.FindSingleEvent(&vaultABI, receipt, vaultAddress, chequeCashedEventType, &cashedEvent)
+	cashedEvent, err := s.findChequeCashedEvent(vaultAddress, receipt)
 	if err != nil {
 		return nil, err
 	}
@@ -380,6 +1328,53 @@ func (s *cashoutService) parseCashChequeBeneficiaryReceipt(vaultAddress common.A
 	return result, nil
 }
 
+// findChequeCashedEvent returns the ChequeCashed event of the vault in the receipt. Standard vaults emit exactly
+// one, if there are several the one for our beneficiary is selected. If that is still ambiguous
+// ErrMultipleCashedEvents is returned.
+func (s *cashoutService) findChequeCashedEvent(vaultAddress common.Address, receipt *types.Receipt) (*chequeCashedEvent, error) {
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return nil, transaction.ErrTransactionReverted
+	}
+
+	var events []*chequeCashedEvent
+	for _, l := range receipt.Logs {
+		if l.Address != vaultAddress || len(l.Topics) == 0 || l.Topics[0] != chequeCashedEventType.ID {
+			continue
+		}
+		var event chequeCashedEvent
+		err := transaction.ParseEvent(&vaultABI, chequeCashedEventType.Name, &event, *l)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, &event)
+	}
+
+	switch len(events) {
+	case 0:
+		return nil, transaction.ErrEventNotFound
+	case 1:
+		return events[0], nil
+	}
+
+	var matching *chequeCashedEvent
+	if s.beneficiary != (common.Address{}) {
+		for _, event := range events {
+			if event.Beneficiary != s.beneficiary {
+				continue
+			}
+			if matching != nil {
+				matching = nil
+				break
+			}
+			matching = event
+		}
+	}
+	if matching == nil {
+		return nil, fmt.Errorf("%w: %d events in receipt of %x", ErrMultipleCashedEvents, len(events), receipt.TxHash)
+	}
+	return matching, nil
+}
+
 // Equal compares to CashChequeResults
 func (r *CashChequeResult) Equal(o *CashChequeResult) bool {
 	if r.Beneficiary != o.Beneficiary {
@@ -391,24 +1386,31 @@ func (r *CashChequeResult) Equal(o *CashChequeResult) bool {
 	if r.Caller != o.Caller {
 		return false
 	}
-	if r.CallerPayout.Cmp(o.CallerPayout) != 0 {
+	if !bigIntEqual(r.CallerPayout, o.CallerPayout) {
 		return false
 	}
-	if r.CumulativePayout.Cmp(o.CumulativePayout) != 0 {
+	if !bigIntEqual(r.CumulativePayout, o.CumulativePayout) {
 		return false
 	}
 	if r.Recipient != o.Recipient {
 		return false
 	}
-	if r.TotalPayout.Cmp(o.TotalPayout) != 0 {
+	if !bigIntEqual(r.TotalPayout, o.TotalPayout) {
 		return false
 	}
 	return true
 }
 
+// bigIntEqual compares two possibly nil big.Ints. Two nils are equal, nil and non-nil are not.
+func bigIntEqual(a, b *big.Int) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Cmp(b) == 0
+}
+
 func (s *cashoutService) HasCashoutAction(ctx context.Context, peer common.Address) (bool, error) {
-	var action cashoutAction
-	err := s.store.Get(cashoutActionKey(peer), &action)
+	_, err := s.repository.Action(peer)
 
 	if err != nil {
 		if errors.Is(err, storage.ErrNotFound) {
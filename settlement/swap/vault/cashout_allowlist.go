@@ -0,0 +1,55 @@
+package vault
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/bittorrent/go-btfs/transaction/storage"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const recipientAllowlistKey = "swap_cashout_recipient_allowlist"
+
+var (
+	// ErrRecipientNotAllowed is the error if the recipient of a cashout is not on the recipient allowlist
+	ErrRecipientNotAllowed = errors.New("cashout recipient not allowed")
+)
+
+// SetRecipientAllowlist persists the recipients cashouts may be sent to. An empty list allows every recipient.
+func (s *cashoutService) SetRecipientAllowlist(recipients []common.Address) error {
+	if len(recipients) == 0 {
+		err := s.store.Delete(recipientAllowlistKey)
+		if err != nil && err != storage.ErrNotFound {
+			return err
+		}
+		return nil
+	}
+	return s.store.Put(recipientAllowlistKey, recipients)
+}
+
+// RecipientAllowlist returns the recipients set with SetRecipientAllowlist, empty if every recipient is allowed
+func (s *cashoutService) RecipientAllowlist() ([]common.Address, error) {
+	var recipients []common.Address
+	err := s.store.Get(recipientAllowlistKey, &recipients)
+	if err != nil && err != storage.ErrNotFound {
+		return nil, err
+	}
+	return recipients, nil
+}
+
+// checkRecipientAllowed returns ErrRecipientNotAllowed if an allowlist is set and the recipient is not on it
+func (s *cashoutService) checkRecipientAllowed(recipient common.Address) error {
+	allowed, err := s.RecipientAllowlist()
+	if err != nil {
+		return err
+	}
+	if len(allowed) == 0 {
+		return nil
+	}
+	for _, a := range allowed {
+		if a == recipient {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %x", ErrRecipientNotAllowed, recipient)
+}
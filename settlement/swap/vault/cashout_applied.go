@@ -0,0 +1,54 @@
+package vault
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bittorrent/go-btfs/transaction/storage"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// appliedResultPrefix is the prefix of the markers of the cashouts whose totals have been applied
+const appliedResultPrefix = "swap_cashout_applied_"
+
+// appliedResultKey computes the key of the marker of the cashout of the vault in the transaction. Batch cashouts
+// cash several vaults in one transaction, so the vault is part of the key.
+func appliedResultKey(vault common.Address, txHash common.Hash) string {
+	return fmt.Sprintf("%s%x_%x", appliedResultPrefix, vault, txHash)
+}
+
+// appliedCashTime returns the cash time of the result the totals of the cashout of the vault in the transaction were
+// applied with. ok is false if they have not been applied yet. The store has to be read under the totals lock so the
+// marker cannot be written concurrently.
+func appliedCashTime(store storeReadWriter, vault common.Address, txHash common.Hash) (cashTime int64, ok bool, err error) {
+	err = store.Get(appliedResultKey(vault, txHash), &cashTime)
+	if errors.Is(err, storage.ErrNotFound) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return cashTime, true, nil
+}
+
+// markResultApplied records that the totals of the cashout were applied with the result. Only the cash time is kept,
+// the result itself is stored by the repository. It must be written in the same batch as the totals.
+func markResultApplied(store storeReadWriter, result *CashOutResult) error {
+	return store.Put(appliedResultKey(result.Vault, result.TxHash), result.CashTime)
+}
+
+// appliedResult loads the result the totals of the cashout of the vault in the transaction were applied with from
+// the repository
+func (s *cashoutService) appliedResult(vault common.Address, txHash common.Hash, cashTime int64) (*CashOutResult, error) {
+	results, err := s.repository.ResultsForDay(time.Unix(cashTime, 0))
+	if err != nil {
+		return nil, err
+	}
+	for i := range results {
+		if results[i].Vault == vault && results[i].TxHash == txHash && results[i].CashTime == cashTime {
+			return &results[i], nil
+		}
+	}
+	return nil, storage.ErrNotFound
+}
@@ -0,0 +1,124 @@
+package vault
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/bittorrent/go-btfs/transaction/crypto"
+	"github.com/bittorrent/go-btfs/transaction/crypto/eip712"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+)
+
+var (
+	// ErrNoResultSigner is the error if cashout results are signed or verified without a result signer
+	ErrNoResultSigner = errors.New("no cashout result signer")
+	// ErrInvalidResultSignature is the error if a cashout result signature was not made by the expected signer
+	ErrInvalidResultSignature = errors.New("invalid cashout result signature")
+)
+
+// CashoutResultTypes are the needed type descriptions for cashout result signing
+var CashoutResultTypes = eip712.Types{
+	"EIP712Domain": eip712.EIP712DomainType,
+	"CashoutResult": []eip712.Type{
+		{
+			Name: "vault",
+			Type: "address",
+		},
+		{
+			Name: "txHash",
+			Type: "bytes32",
+		},
+		{
+			Name: "amount",
+			Type: "uint256",
+		},
+	},
+}
+
+// resultSigner signs cashout results with the key of the node
+type resultSigner struct {
+	signer  crypto.Signer
+	chainID int64 // the chainID used for EIP712
+}
+
+// WithResultSigner sets the signer used by SignCashoutResult and the chain id bound into the signatures, usually
+// the key of the node
+func WithResultSigner(signer crypto.Signer, chainID int64) CashoutServiceOption {
+	return cashoutServiceOptionFunc(func(s *cashoutService) {
+		s.resultSigner = &resultSigner{
+			signer:  signer,
+			chainID: chainID,
+		}
+	})
+}
+
+// cashoutResultDomain computes chainId-dependant EIP712 domain of cashout results
+func cashoutResultDomain(chainID int64) eip712.TypedDataDomain {
+	return eip712.TypedDataDomain{
+		Name:    "VaultCashout",
+		Version: "1.0",
+		ChainId: math.NewHexOrDecimal256(chainID),
+	}
+}
+
+// eip712DataForCashoutResult converts the signed fields of a cashout result into the TypedData structure
+func eip712DataForCashoutResult(result *CashOutResult, chainID int64) *eip712.TypedData {
+	amount := "0"
+	if result.Amount != nil {
+		amount = result.Amount.String()
+	}
+	return &eip712.TypedData{
+		Domain: cashoutResultDomain(chainID),
+		Types:  CashoutResultTypes,
+		Message: eip712.TypedDataMessage{
+			"vault":  result.Vault.Hex(),
+			"txHash": result.TxHash.Hex(),
+			"amount": amount,
+		},
+		PrimaryType: "CashoutResult",
+	}
+}
+
+// RecoverCashoutResult recovers the signer of a cashout result signature. Only the vault, the amount and the
+// transaction hash of the result are signed.
+func RecoverCashoutResult(result *CashOutResult, signature []byte, chainID int64) (common.Address, error) {
+	pubkey, err := crypto.RecoverEIP712(signature, eip712DataForCashoutResult(result, chainID))
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	ethAddr, err := crypto.NewEthereumAddress(*pubkey)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	var signer common.Address
+	copy(signer[:], ethAddr)
+	return signer, nil
+}
+
+// SignCashoutResult signs the vault, the amount and the transaction hash of the result, producing an attestation of
+// the cashout a third party can verify with VerifyCashoutResult or RecoverCashoutResult without chain access
+func (s *cashoutService) SignCashoutResult(result CashOutResult) ([]byte, error) {
+	if s.resultSigner == nil {
+		return nil, ErrNoResultSigner
+	}
+	return s.resultSigner.signer.SignTypedData(eip712DataForCashoutResult(&result, s.resultSigner.chainID))
+}
+
+// VerifyCashoutResult returns ErrInvalidResultSignature unless the signature of the result was made by signer. The
+// chain id of the result signer is used.
+func (s *cashoutService) VerifyCashoutResult(result CashOutResult, signature []byte, signer common.Address) error {
+	if s.resultSigner == nil {
+		return ErrNoResultSigner
+	}
+	recovered, err := RecoverCashoutResult(&result, signature, s.resultSigner.chainID)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidResultSignature, err)
+	}
+	if recovered != signer {
+		return ErrInvalidResultSignature
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package vault
+
+import (
+	"encoding"
+	"encoding/json"
+
+	"github.com/bittorrent/go-btfs/transaction/storage"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// storeReadWriter is the subset of the state store used by the cashout bookkeeping
+type storeReadWriter interface {
+	Get(key string, i interface{}) error
+	Put(key string, i interface{}) error
+	Delete(key string) error
+}
+
+// storeBatch collects state store writes so they can be applied at once.
+// Reads see the writes already collected so read-modify-write sequences work as with the store itself.
+type storeBatch struct {
+	store  storage.StateStorer
+	keys   []string          // keys in the order they were first written
+	values map[string][]byte // nil for deleted keys
+}
+
+func newStoreBatch(store storage.StateStorer) *storeBatch {
+	return &storeBatch{
+		store:  store,
+		values: make(map[string][]byte),
+	}
+}
+
+// rawValue is an already encoded value which is stored as is
+type rawValue []byte
+
+func (v rawValue) MarshalBinary() ([]byte, error) {
+	return v, nil
+}
+
+func (v *rawValue) UnmarshalBinary(data []byte) error {
+	*v = append((*v)[:0], data...)
+	return nil
+}
+
+func (b *storeBatch) Get(key string, i interface{}) error {
+	data, ok := b.values[key]
+	if !ok {
+		return b.store.Get(key, i)
+	}
+	if data == nil {
+		return storage.ErrNotFound
+	}
+	if unmarshaler, ok := i.(encoding.BinaryUnmarshaler); ok {
+		return unmarshaler.UnmarshalBinary(data)
+	}
+	return json.Unmarshal(data, i)
+}
+
+func (b *storeBatch) Put(key string, i interface{}) (err error) {
+	var data []byte
+	if marshaler, ok := i.(encoding.BinaryMarshaler); ok {
+		if data, err = marshaler.MarshalBinary(); err != nil {
+			return err
+		}
+	} else if data, err = json.Marshal(i); err != nil {
+		return err
+	}
+
+	b.set(key, data)
+	return nil
+}
+
+// Delete removes the key once the batch is committed
+func (b *storeBatch) Delete(key string) error {
+	b.set(key, nil)
+	return nil
+}
+
+func (b *storeBatch) set(key string, data []byte) {
+	if _, ok := b.values[key]; !ok {
+		b.keys = append(b.keys, key)
+	}
+	b.values[key] = data
+}
+
+// Commit applies the collected writes. If the store is backed by a leveldb database they are written in a single
+// atomic batch, otherwise they fall back to sequential writes.
+func (b *storeBatch) Commit() error {
+	if db := b.store.DB(); db != nil {
+		batch := new(leveldb.Batch)
+		for _, key := range b.keys {
+			// the database is written directly, so the keys have to be mapped like the store does
+			dbKey := []byte(storeKey(b.store, key))
+			if b.values[key] == nil {
+				batch.Delete(dbKey)
+				continue
+			}
+			batch.Put(dbKey, b.values[key])
+		}
+		return db.Write(batch, nil)
+	}
+
+	for _, key := range b.keys {
+		var err error
+		if b.values[key] == nil {
+			err = b.store.Delete(key)
+			if err == storage.ErrNotFound {
+				err = nil
+			}
+		} else {
+			err = b.store.Put(key, rawValue(b.values[key]))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	// ErrEmptyBatch is the error if a batch cashout is requested without any cashouts
+	ErrEmptyBatch = errors.New("empty cashout batch")
+	// ErrDuplicateBatchVault is the error if a batch cashout contains the same vault more than once
+	ErrDuplicateBatchVault = errors.New("vault cashed more than once in batch")
+)
+
+// CashoutRequest is a single cashout of a batch
+type CashoutRequest struct {
+	Vault     common.Address
+	Recipient common.Address // zero means the default recipient of the vault
+	Label     string         // label stored with the action and the result
+}
+
+// CashChequeBatchTx cashes the last cheques of several vaults, sending one cashout transaction per vault.
+// cashChequeBeneficiary pays out to msg.sender, so the cashouts cannot be relayed through a multicall contract.
+// Every cashout runs the checks of CashChequeWithOptions. The batch stops at the first failing cashout and returns
+// the hashes of the transactions sent so far in request order. In dry run mode every cashout is validated and the
+// returned error wraps ErrDryRun, PreviewCashout returns the transaction of each vault.
+func (s *cashoutService) CashChequeBatchTx(ctx context.Context, requests []CashoutRequest) ([]common.Hash, error) {
+	if len(requests) == 0 {
+		return nil, newCashoutError(CashoutPhaseValidate, ErrEmptyBatch)
+	}
+
+	vaults := make(map[common.Address]struct{}, len(requests))
+	for _, request := range requests {
+		if _, ok := vaults[request.Vault]; ok {
+			return nil, newCashoutError(CashoutPhaseValidate, fmt.Errorf("%w: %x", ErrDuplicateBatchVault, request.Vault))
+		}
+		vaults[request.Vault] = struct{}{}
+	}
+
+	if s.dryRun {
+		dryRun := &DryRunResult{}
+		for _, request := range requests {
+			result, err := s.PreviewCashout(ctx, request.Vault, request.Recipient, CashoutOptions{
+				Label: request.Label,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("vault %x: %w", request.Vault, err)
+			}
+			dryRun.Cashouts = append(dryRun.Cashouts, result.Cashouts...)
+		}
+		return nil, newCashoutError(CashoutPhaseSend, fmt.Errorf("%w: %v", ErrDryRun, dryRun))
+	}
+
+	txHashes := make([]common.Hash, 0, len(requests))
+	for _, request := range requests {
+		txHash, err := s.CashChequeWithOptions(ctx, request.Vault, request.Recipient, CashoutOptions{
+			Label: request.Label,
+		})
+		if txHash != (common.Hash{}) {
+			txHashes = append(txHashes, txHash)
+		}
+		if err != nil {
+			return txHashes, fmt.Errorf("vault %x: %w", request.Vault, err)
+		}
+	}
+	return txHashes, nil
+}
@@ -0,0 +1,32 @@
+package vault
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// BounceRate returns the fraction of the successful cashouts of the vault cashed within the last window which
+// bounced partially. It is 0 if the vault has no successful cashouts within the window.
+func (s *cashoutService) BounceRate(vault common.Address, window time.Duration) (float64, error) {
+	since := s.now().Add(-window).Unix()
+
+	var cashed, bounced int
+	err := s.repository.IterateResults(func(result CashOutResult) (bool, error) {
+		if result.Vault != vault || result.Status != "success" || result.CashTime < since {
+			return false, nil
+		}
+		cashed++
+		if result.Bounced {
+			bounced++
+		}
+		return false, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if cashed == 0 {
+		return 0, nil
+	}
+	return float64(bounced) / float64(cashed), nil
+}
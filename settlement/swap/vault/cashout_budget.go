@@ -0,0 +1,82 @@
+package vault
+
+import (
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/bittorrent/go-btfs/statestore"
+	"github.com/bittorrent/go-btfs/transaction/storage"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+var (
+	// ErrDailyBudgetExceeded is the error returned if the gas spent on cashouts today exceeds the daily gas budget
+	ErrDailyBudgetExceeded = errors.New("daily cashout gas budget exceeded")
+)
+
+// WithDailyGasBudget limits the amount of gas (in wei) spent on cashouts per day
+func WithDailyGasBudget(budget *big.Int) CashoutServiceOption {
+	return cashoutServiceOptionFunc(func(s *cashoutService) {
+		s.dailyGasBudget = budget
+	})
+}
+
+// WithClock sets the clock used by the service, mostly useful for tests
+func WithClock(now func() time.Time) CashoutServiceOption {
+	return cashoutServiceOptionFunc(func(s *cashoutService) {
+		s.now = now
+	})
+}
+
+// dayUnix truncates t to its date and returns the unix timestamp of that day
+func dayUnix(t time.Time) int64 {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Unix()
+}
+
+// dailyGasSpent returns the gas (in wei) spent on cashouts on the given day
+func dailyGasSpent(store storeReadWriter, day int64) (*big.Int, error) {
+	spent := big.NewInt(0)
+	err := store.Get(statestore.GetTotalDailyCashoutGasKeyByTime(day), &spent)
+	if err != nil && err != storage.ErrNotFound {
+		return nil, err
+	}
+	return spent, nil
+}
+
+// checkDailyGasBudget returns ErrDailyBudgetExceeded if the gas spent today reached the daily gas budget
+func (s *cashoutService) checkDailyGasBudget() error {
+	if s.dailyGasBudget == nil {
+		return nil
+	}
+	spent, err := dailyGasSpent(s.store, dayUnix(s.now()))
+	if err != nil {
+		return err
+	}
+	if spent.Cmp(s.dailyGasBudget) >= 0 {
+		return ErrDailyBudgetExceeded
+	}
+	return nil
+}
+
+// recordGasSpent adds the gas cost of a mined cashout transaction to the gas spending of the day of cashTime
+func (s *cashoutService) recordGasSpent(store storeReadWriter, txHash common.Hash, cashTime int64, receipt *types.Receipt) error {
+	stored, err := s.transactionService.StoredTransaction(txHash)
+	if err != nil {
+		return err
+	}
+	if stored.GasPrice == nil {
+		return nil
+	}
+
+	// read through the store the spending is written to, so several cashouts in one batch all count
+	day := dayUnix(time.Unix(cashTime, 0))
+	spent, err := dailyGasSpent(store, day)
+	if err != nil {
+		return err
+	}
+	cost := new(big.Int).Mul(new(big.Int).SetUint64(receipt.GasUsed), stored.GasPrice)
+	return store.Put(statestore.GetTotalDailyCashoutGasKeyByTime(day), spent.Add(spent, cost))
+}
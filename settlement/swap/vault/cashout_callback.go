@@ -0,0 +1,61 @@
+package vault
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// CashoutCallback receives the stored result of a cashout, or the error if the cashout could not be sent or its
+// result could not be stored
+type CashoutCallback func(result *CashOutResult, err error)
+
+// resultCallback invokes a CashoutCallback at most once
+type resultCallback struct {
+	once sync.Once
+	cb   CashoutCallback
+}
+
+type resultCallbackContextKey struct{}
+
+// withResultCallback lets the tracking of the cashout started with ctx report its result to cb
+func withResultCallback(ctx context.Context, cb CashoutCallback) (context.Context, *resultCallback) {
+	callback := &resultCallback{cb: cb}
+	return context.WithValue(ctx, resultCallbackContextKey{}, callback), callback
+}
+
+// resultCallbackFromContext returns the callback of the cashout started with ctx, nil if there is none
+func resultCallbackFromContext(ctx context.Context) *resultCallback {
+	callback, _ := ctx.Value(resultCallbackContextKey{}).(*resultCallback)
+	return callback
+}
+
+// invoke runs the callback in its own goroutine the first time it is called. A panicking callback is only logged.
+func (c *resultCallback) invoke(result *CashOutResult, err error) {
+	if c == nil {
+		return
+	}
+	c.once.Do(func() {
+		go func() {
+			defer func() {
+				if r := recover(); r != nil {
+					log.Errorf("cashout callback recovered:%+v", r)
+				}
+			}()
+			c.cb(result, err)
+		}()
+	})
+}
+
+// CashChequeWithCallback cashes the last cheque of the vault like CashCheque and calls cb exactly once, with the
+// stored result once tracking of the transaction completed or with the error if the cashout could not be sent.
+func (s *cashoutService) CashChequeWithCallback(ctx context.Context, vault, recipient common.Address, cb CashoutCallback) (common.Hash, error) {
+	ctx, callback := withResultCallback(ctx, cb)
+	txHash, err := s.CashCheque(ctx, vault, recipient)
+	if err != nil && txHash == (common.Hash{}) {
+		// nothing is tracked so the result is the error
+		callback.invoke(nil, err)
+	}
+	return txHash, err
+}
@@ -0,0 +1,93 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// VaultCashable is a vault which can be cashed right now without bouncing
+type VaultCashable struct {
+	Vault        common.Address
+	Amount       *big.Int // uncashed amount, fully covered by the vault balance
+	EstimatedGas uint64   // estimated gas of the cashout, 0 if it could not be estimated
+}
+
+// CashableVaults returns the vaults whose uncashed amount exceeds minAmount and is covered by the vault balance,
+// largest amount first. Vaults with a pending cashout are left out.
+func (s *cashoutService) CashableVaults(ctx context.Context, minAmount *big.Int) ([]VaultCashable, error) {
+	cheques, err := s.lastReceivedCheques()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]VaultCashable, 0)
+	for vault := range cheques {
+		status, err := s.CashoutStatus(ctx, vault)
+		if errors.Is(err, ErrVaultDestroyed) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if status.State == CashoutStatePending || status.UncashedAmount.Sign() <= 0 {
+			continue
+		}
+		if minAmount != nil && status.UncashedAmount.Cmp(minAmount) <= 0 {
+			continue
+		}
+
+		balance, err := newVaultContract(vault, s.transactionService).TotalBalance(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if balance.Cmp(status.UncashedAmount) < 0 {
+			continue
+		}
+
+		recipient, err := s.resolveRecipient(vault, common.Address{})
+		if err != nil {
+			recipient = s.beneficiary
+		}
+		gas, err := s.EstimateCashout(ctx, vault, recipient)
+		if err != nil {
+			log.Infof("cashable vaults: could not estimate cashout of vault %x: %v", vault, err)
+			gas = 0
+		}
+
+		result = append(result, VaultCashable{
+			Vault:        vault,
+			Amount:       status.UncashedAmount,
+			EstimatedGas: gas,
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if c := result[i].Amount.Cmp(result[j].Amount); c != 0 {
+			return c > 0
+		}
+		return bytes.Compare(result[i].Vault.Bytes(), result[j].Vault.Bytes()) < 0
+	})
+	return result, nil
+}
+
+// IsCashableLocal reports whether the cheque pays out more than knownPaidOut, the amount already paid out to its
+// beneficiary, and returns the amount a cashout would pay. It only looks at its inputs, a nil knownPaidOut means
+// nothing was paid out yet.
+func IsCashableLocal(cheque SignedCheque, knownPaidOut *big.Int) (bool, *big.Int) {
+	if cheque.CumulativePayout == nil {
+		return false, big.NewInt(0)
+	}
+	amount := new(big.Int).Set(cheque.CumulativePayout)
+	if knownPaidOut != nil {
+		amount.Sub(amount, knownPaidOut)
+	}
+	if amount.Sign() <= 0 {
+		return false, big.NewInt(0)
+	}
+	return true, amount
+}
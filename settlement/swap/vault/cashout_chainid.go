@@ -0,0 +1,106 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+)
+
+// chainIDTimeout bounds the query of the backend chain ID
+const chainIDTimeout = 5 * time.Second
+
+var (
+	// ErrChainIDMismatch is returned if the backend or the cheque belongs to a different chain than the configured one
+	ErrChainIDMismatch = errors.New("chain id mismatch")
+)
+
+// chainIDReader is implemented by backends which can report the chain id, e.g. the ethclient
+type chainIDReader interface {
+	ChainID(ctx context.Context) (*big.Int, error)
+}
+
+// chainIDCheck holds the configured chain id and the cached chain id of the backend
+type chainIDCheck struct {
+	chainID *big.Int
+
+	mu      sync.Mutex
+	backend *big.Int // nil until it could be read from the backend
+}
+
+// WithChainID makes every cashout verify that the backend is connected to the chain with the given id. If
+// signature verification is enabled its chain id, which is part of the cheque's EIP712 domain, must match as well.
+func WithChainID(chainID int64) CashoutServiceOption {
+	return cashoutServiceOptionFunc(func(s *cashoutService) {
+		s.chainIDCheck = &chainIDCheck{chainID: big.NewInt(chainID)}
+	})
+}
+
+// initChainID caches the chain id of the backend. If it cannot be read now it is read again on the next cashout.
+func (s *cashoutService) initChainID() {
+	if s.chainIDCheck == nil {
+		return
+	}
+	if _, ok := s.backend.(chainIDReader); !ok {
+		log.Warnf("cashout: backend cannot report its chain id, only the cheque chain id is validated")
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), chainIDTimeout)
+	defer cancel()
+	_, err := s.backendChainID(ctx)
+	if err != nil {
+		log.Warnf("cashout: could not read backend chain id: %v", err)
+	}
+}
+
+// backendChainID returns the chain id of the backend. It is cached if chain id validation is enabled.
+// It returns nil if the backend cannot report its chain id.
+func (s *cashoutService) backendChainID(ctx context.Context) (*big.Int, error) {
+	reader, ok := s.backend.(chainIDReader)
+	if !ok {
+		return nil, nil
+	}
+
+	c := s.chainIDCheck
+	if c != nil {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.backend != nil {
+			return c.backend, nil
+		}
+	}
+	ctx, cancel := context.WithTimeout(ctx, chainIDTimeout)
+	defer cancel()
+	chainID, err := reader.ChainID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if c != nil {
+		c.backend = chainID
+	}
+	return chainID, nil
+}
+
+// checkChainID returns ErrChainIDMismatch if the backend or the cheque signature domain is for another chain
+// than the configured one. Cheques carry no chain id themselves, it is only bound through their signature.
+func (s *cashoutService) checkChainID(ctx context.Context) error {
+	if s.chainIDCheck == nil {
+		return nil
+	}
+	want := s.chainIDCheck.chainID
+
+	backend, err := s.backendChainID(ctx)
+	if err != nil {
+		return err
+	}
+	if backend != nil && backend.Cmp(want) != 0 {
+		return fmt.Errorf("%w: backend is on chain %d, configured chain %d", ErrChainIDMismatch, backend, want)
+	}
+
+	if s.signatureVerifier != nil && s.signatureVerifier.chainID != want.Int64() {
+		return fmt.Errorf("%w: cheques are verified for chain %d, configured chain %d", ErrChainIDMismatch, s.signatureVerifier.chainID, want)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package vault
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"strings"
+
+	"github.com/bittorrent/go-btfs/statestore"
+	"github.com/bittorrent/go-btfs/transaction/storage"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Codec (de)serializes the cashout actions and results kept in the state store
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// JSONCodec is the default Codec. It produces the same JSON the state store uses for values without a binary
+// encoding, so it reads everything stored before codecs were introduced.
+type JSONCodec struct{}
+
+func (JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JSONCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// GobCodec is a more compact Codec based on encoding/gob
+type GobCodec struct{}
+
+func (GobCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(v)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (GobCodec) Unmarshal(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// WithCodec sets the codec of the stored cashout actions and results. Records written with another codec have to
+// be converted with MigrateCodec first.
+func WithCodec(codec Codec) CashoutServiceOption {
+	return cashoutServiceOptionFunc(func(s *cashoutService) {
+		s.codec = codec
+	})
+}
+
+// encodedValue lets the state store (de)serialize a value with a codec
+type encodedValue struct {
+	codec Codec
+	v     interface{}
+}
+
+func (e encodedValue) MarshalBinary() ([]byte, error) {
+	return e.codec.Marshal(e.v)
+}
+
+func (e encodedValue) UnmarshalBinary(data []byte) error {
+	return e.codec.Unmarshal(data, e.v)
+}
+
+// MigrateCodec re-encodes every cashout action and result in the store from one codec to another and returns the
+// number of converted records. It must run before a CashoutService using the new codec is created.
+func MigrateCodec(store storage.StateStorer, from, to Codec) (int, error) {
+	resultPrefix := statestore.CashoutResultPrefixKey()
+	converted := make(map[string][]byte)
+
+	err := store.Iterate(cashoutActionPrefix, func(key, val []byte) (stop bool, err error) {
+		k := string(key)
+		var v interface{}
+		if strings.HasPrefix(k, resultPrefix) {
+			if hexVault, _ := resultKeyVault(k); hexVault == "" {
+				return false, nil
+			}
+			v = new(CashOutResult)
+		} else {
+			hexVault := strings.TrimPrefix(k, cashoutActionPrefix)
+			if len(hexVault) != 2*common.AddressLength || !common.IsHexAddress(hexVault) {
+				// not an action, e.g. the write-ahead log
+				return false, nil
+			}
+			v = new(CashoutAction)
+		}
+
+		err = from.Unmarshal(val, v)
+		if err != nil {
+			return true, err
+		}
+		data, err := to.Marshal(v)
+		if err != nil {
+			return true, err
+		}
+		converted[k] = data
+		return false, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for key, data := range converted {
+		err = store.Put(key, rawValue(data))
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(converted), nil
+}
@@ -0,0 +1,82 @@
+package vault
+
+import (
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ConfTimeStats summarizes how long cashouts took from submission to confirmation
+type ConfTimeStats struct {
+	Count  int // number of cashouts the statistics are computed from
+	Min    time.Duration
+	Median time.Duration
+	P95    time.Duration
+	Max    time.Duration
+}
+
+// submittedAt returns the unix nano time the cashout transaction of the vault was sent, 0 if it is not known
+func (s *cashoutService) submittedAt(vault common.Address, txHash common.Hash) int64 {
+	action, err := s.repository.Action(vault)
+	if err != nil {
+		return 0
+	}
+	if action.TxHash == txHash {
+		return action.Submitted
+	}
+	for _, replacement := range action.Replacements {
+		if replacement == txHash {
+			return action.Submitted
+		}
+	}
+	return 0
+}
+
+// ConfirmationTimeStats returns the statistics of the time from submission to confirmation of the cashouts
+// confirmed within the window, a window of 0 covers all of them. Results which did not record their submission time
+// are left out. The confirmation time is only stored in seconds.
+func (s *cashoutService) ConfirmationTimeStats(window time.Duration) (ConfTimeStats, error) {
+	var since int64
+	if window > 0 {
+		since = s.now().Add(-window).Unix()
+	}
+
+	var durations []time.Duration
+	err := s.repository.IterateResults(func(result CashOutResult) (bool, error) {
+		if result.Submitted == 0 || result.CashTime < since {
+			return false, nil
+		}
+		d := time.Unix(result.CashTime, 0).Sub(time.Unix(0, result.Submitted))
+		if d < 0 {
+			// confirmed within the second it was sent
+			d = 0
+		}
+		durations = append(durations, d)
+		return false, nil
+	})
+	if err != nil {
+		return ConfTimeStats{}, err
+	}
+	if len(durations) == 0 {
+		return ConfTimeStats{}, nil
+	}
+
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	return ConfTimeStats{
+		Count:  len(durations),
+		Min:    durations[0],
+		Median: percentileDuration(durations, 50),
+		P95:    percentileDuration(durations, 95),
+		Max:    durations[len(durations)-1],
+	}, nil
+}
+
+// percentileDuration returns the nearest-rank percentile of the sorted durations
+func percentileDuration(sorted []time.Duration, percentile int) time.Duration {
+	rank := (percentile*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
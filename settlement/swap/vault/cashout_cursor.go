@@ -0,0 +1,41 @@
+package vault
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+
+	"github.com/bittorrent/go-btfs/transaction/storage"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// autoCashoutCursorKey holds the last vault checked by an iteration of the auto cashout loop
+const autoCashoutCursorKey = "swap_cashout_auto_cursor"
+
+// AutoCashoutCursor returns the last vault checked by the auto cashout loop, the next iteration continues after it.
+// It is the zero address if the loop has not checked any vault yet.
+func (s *cashoutService) AutoCashoutCursor() (common.Address, error) {
+	var cursor common.Address
+	err := s.store.Get(autoCashoutCursorKey, &cursor)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return common.Address{}, err
+	}
+	return cursor, nil
+}
+
+// autoCashoutBatch orders the vaults by address starting after the cursor, wrapping around, and keeps the first max
+// of them. A max of 0 keeps all of them.
+func autoCashoutBatch(vaults []common.Address, cursor common.Address, max int) []common.Address {
+	sort.Slice(vaults, func(i, j int) bool { return bytes.Compare(vaults[i][:], vaults[j][:]) < 0 })
+	start := sort.Search(len(vaults), func(i int) bool {
+		return bytes.Compare(vaults[i][:], cursor[:]) > 0
+	})
+
+	batch := make([]common.Address, 0, len(vaults))
+	batch = append(batch, vaults[start:]...)
+	batch = append(batch, vaults[:start]...)
+	if max > 0 && max < len(batch) {
+		batch = batch[:max]
+	}
+	return batch
+}
@@ -0,0 +1,62 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	// ErrDeadlinePassed is the error if a cashout is requested with a deadline which already passed
+	ErrDeadlinePassed = errors.New("cashout deadline passed")
+)
+
+// checkDeadline returns ErrDeadlinePassed if the deadline is set and not in the future
+func (s *cashoutService) checkDeadline(deadline time.Time) error {
+	if deadline.IsZero() || s.now().Before(deadline) {
+		return nil
+	}
+	return ErrDeadlinePassed
+}
+
+// cancelAfterDeadline cancels the cashout transaction if it has not been mined once the deadline passed.
+// The vault contract has no deadline aware cashout, so the transaction is replaced by a cancellation instead.
+func (s *cashoutService) cancelAfterDeadline(vault common.Address, txHash common.Hash, deadline time.Time) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		timer := time.NewTimer(deadline.Sub(s.now()))
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-s.quit:
+			return
+		}
+
+		ctx := context.Background()
+		_, err := s.transactionReceipt(ctx, txHash)
+		if err == nil {
+			return
+		}
+		if !errors.Is(err, ethereum.NotFound) {
+			log.Errorf("cashout: could not get receipt of %x for vault %x: %v", txHash, vault, err)
+			return
+		}
+
+		cancelTxHash, err := s.transactionService.CancelTransaction(ctx, txHash)
+		if err != nil {
+			log.Errorf("cashout: could not cancel %x for vault %x after deadline: %v", txHash, vault, err)
+			return
+		}
+		log.Infof("cashout: %x for vault %x missed its deadline, cancelled in %x", txHash, vault, cancelTxHash)
+		s.recordCashoutEvent(s.store, vault, txHash, CashoutEventCancelled)
+		err = s.recordReplacement(vault, txHash, cancelTxHash)
+		if err != nil {
+			log.Errorf("cashout: could not record cancellation %x of %x for vault %x: %v", cancelTxHash, txHash, vault, err)
+		}
+	}()
+}
@@ -0,0 +1,91 @@
+package vault
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// AutoDecisionReason tells why the auto cashout loop did or did not cash a vault
+type AutoDecisionReason string
+
+const (
+	AutoDecisionCashed          AutoDecisionReason = "cashed"
+	AutoDecisionGasTooHigh      AutoDecisionReason = "gas price above ceiling"
+	AutoDecisionExcluded        AutoDecisionReason = "excluded"
+	AutoDecisionStatusFailed    AutoDecisionReason = "cashout status unavailable"
+	AutoDecisionPending         AutoDecisionReason = "previous cashout pending"
+	AutoDecisionNothingUncashed AutoDecisionReason = "nothing uncashed"
+	AutoDecisionBelowThreshold  AutoDecisionReason = "below minimum amount"
+	AutoDecisionRateLimited     AutoDecisionReason = "too many cashouts in flight"
+	AutoDecisionCashoutFailed   AutoDecisionReason = "cashout failed"
+	AutoDecisionPriceTooLow     AutoDecisionReason = "token price below minimum"
+	AutoDecisionDryRun          AutoDecisionReason = "dry run"
+)
+
+var (
+	// ErrNoAutoCashoutDecision is the error if the auto cashout loop has not considered the vault yet
+	ErrNoAutoCashoutDecision = errors.New("no auto cashout decision")
+)
+
+// AutoDecision is the outcome of the last auto cashout iteration for a vault
+type AutoDecision struct {
+	Time   time.Time
+	Reason AutoDecisionReason
+	TxHash common.Hash // transaction of the cashout if the vault was cashed
+	Detail string      // e.g. the error of a failed cashout
+}
+
+// Skipped reports whether the vault was not cashed
+func (d AutoDecision) Skipped() bool {
+	return d.Reason != AutoDecisionCashed
+}
+
+// autoDecisions keeps the last auto cashout decision of every vault in memory
+type autoDecisions struct {
+	mu        sync.Mutex
+	decisions map[common.Address]AutoDecision
+}
+
+func (d *autoDecisions) forget(vault common.Address) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.decisions, vault)
+}
+
+func (d *autoDecisions) record(vault common.Address, decision AutoDecision) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.decisions == nil {
+		d.decisions = make(map[common.Address]AutoDecision)
+	}
+	d.decisions[vault] = decision
+}
+
+func (d *autoDecisions) get(vault common.Address) (AutoDecision, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	decision, ok := d.decisions[vault]
+	return decision, ok
+}
+
+// recordAutoDecision remembers why the auto cashout loop did or did not cash the vault
+func (s *cashoutService) recordAutoDecision(vault common.Address, reason AutoDecisionReason, txHash common.Hash, detail string) {
+	s.autoDecisions.record(vault, AutoDecision{
+		Time:   s.now(),
+		Reason: reason,
+		TxHash: txHash,
+		Detail: detail,
+	})
+}
+
+// LastAutoCashoutDecision returns why the auto cashout loop did or did not cash the vault in its last iteration
+func (s *cashoutService) LastAutoCashoutDecision(vault common.Address) (AutoDecision, error) {
+	decision, ok := s.autoDecisions.get(vault)
+	if !ok {
+		return AutoDecision{}, ErrNoAutoCashoutDecision
+	}
+	return decision, nil
+}
@@ -0,0 +1,99 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/bittorrent/go-btfs/transaction/storage"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// destroyedVaultPrefix is the prefix of the store keys of the vaults found without code
+const destroyedVaultPrefix = "swap_cashout_destroyed_"
+
+var (
+	// ErrVaultDestroyed is the error if the vault of a received cheque has no contract code anymore
+	ErrVaultDestroyed = errors.New("vault destroyed")
+)
+
+// DestroyedVault is a vault whose contract was destroyed after it issued cheques
+type DestroyedVault struct {
+	Vault         common.Address
+	Unrecoverable *big.Int // uncashed amount of the last cheque when the destruction was detected
+	Detected      int64    // unix time the destruction was detected
+}
+
+func destroyedVaultKey(vault common.Address) string {
+	return fmt.Sprintf("%s%x", destroyedVaultPrefix, vault)
+}
+
+// checkVaultDestroyed returns ErrVaultDestroyed if the vault of the cheque has no code anymore. The first time the
+// uncashed amount of the cheque is recorded as unrecoverable unless record is false, e.g. in a dry run. If the code
+// cannot be read the vault is assumed to still exist, a cashout would reveal otherwise.
+func (s *cashoutService) checkVaultDestroyed(ctx context.Context, vault common.Address, cheque *SignedCheque, record bool) error {
+	var destroyed DestroyedVault
+	err := s.store.Get(destroyedVaultKey(vault), &destroyed)
+	if err == nil {
+		return fmt.Errorf("%w: %x", ErrVaultDestroyed, vault)
+	}
+	if !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+
+	code, err := s.backend.CodeAt(ctx, vault, nil)
+	if err != nil || len(code) > 0 {
+		return nil
+	}
+	if !record {
+		return fmt.Errorf("%w: %x", ErrVaultDestroyed, vault)
+	}
+
+	// the vault cannot be asked what it paid out anymore, only the local results are left
+	paidOut := big.NewInt(0)
+	err = s.repository.IterateResults(func(result CashOutResult) (bool, error) {
+		if result.Vault == vault && result.Status == "success" && result.Amount != nil {
+			paidOut.Add(paidOut, result.Amount)
+		}
+		return false, nil
+	})
+	if err != nil {
+		return err
+	}
+	_, unrecoverable := IsCashableLocal(*cheque, paidOut)
+
+	err = s.store.Put(destroyedVaultKey(vault), &DestroyedVault{
+		Vault:         vault,
+		Unrecoverable: unrecoverable,
+		Detected:      s.now().Unix(),
+	})
+	if err != nil {
+		return err
+	}
+	log.Warnf("cashout: vault %x was destroyed, %d uncashed is unrecoverable", vault, unrecoverable)
+	return fmt.Errorf("%w: %x", ErrVaultDestroyed, vault)
+}
+
+// DestroyedVaults returns the vaults found destroyed together with the amount which was lost, sorted by address
+func (s *cashoutService) DestroyedVaults() ([]DestroyedVault, error) {
+	result := make([]DestroyedVault, 0)
+	err := s.store.Iterate(destroyedVaultPrefix, func(key, val []byte) (stop bool, err error) {
+		var destroyed DestroyedVault
+		err = s.store.Get(string(key), &destroyed)
+		if err != nil {
+			return true, err
+		}
+		result = append(result, destroyed)
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return bytes.Compare(result[i].Vault.Bytes(), result[j].Vault.Bytes()) < 0
+	})
+	return result, nil
+}
@@ -0,0 +1,62 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	// ErrDryRun is wrapped by the CashoutError returned instead of sending a cashout in dry run mode
+	ErrDryRun = errors.New("dry run")
+)
+
+// WithDryRun makes every cashout validate, estimate and simulate the cashout transaction without sending it or
+// storing anything. The cashout methods fail with ErrDryRun instead of sending, PreviewCashout returns what they
+// would have sent. This lets the auto cashout loop be previewed before enabling it for real.
+func WithDryRun() CashoutServiceOption {
+	return cashoutServiceOptionFunc(func(s *cashoutService) {
+		s.dryRun = true
+	})
+}
+
+// DryRunCashout is a cheque cashout a dry run would have sent
+type DryRunCashout struct {
+	Vault           common.Address
+	Recipient       common.Address // recipient after applying the recipient policy
+	Cheque          SignedCheque
+	EstimatedGas    uint64            // the forced gas limit if one was given, 0 if the gas could not be estimated
+	Simulation      *CashChequeResult // projected result, nil if the simulation failed
+	SimulationError string            // why the simulation failed, e.g. the revert reason
+}
+
+// DryRunResult is returned by PreviewCashout. It describes the transaction which would have been sent.
+type DryRunResult struct {
+	To       common.Address // vault the transaction would have been sent to, zero for a batch of several vaults
+	CallData []byte
+	Cashouts []DryRunCashout
+}
+
+func (r *DryRunResult) String() string {
+	return fmt.Sprintf("would have sent %d cashouts to %x", len(r.Cashouts), r.To)
+}
+
+// dryRunCashout simulates the cashout of the cheque. recipient is the requested recipient before applying the
+// recipient policy, effectiveRecipient the one after. gas is the gas the cashout was estimated at, 0 if unknown.
+func (s *cashoutService) dryRunCashout(ctx context.Context, vault, recipient, effectiveRecipient common.Address, cheque *SignedCheque, gas uint64) DryRunCashout {
+	cashout := DryRunCashout{
+		Vault:        vault,
+		Recipient:    effectiveRecipient,
+		Cheque:       *cheque,
+		EstimatedGas: gas,
+	}
+
+	var err error
+	cashout.Simulation, err = s.SimulateCashout(ctx, vault, recipient)
+	if err != nil {
+		cashout.SimulationError = err.Error()
+	}
+	return cashout
+}
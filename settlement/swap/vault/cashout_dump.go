@@ -0,0 +1,102 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/bittorrent/go-btfs/statestore"
+	"github.com/bittorrent/go-btfs/transaction/storage"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// VaultCashoutDump is everything known about the cashouts of a vault. Parts which could not be determined are
+// left empty and the reason is listed in Errors.
+type VaultCashoutDump struct {
+	Vault         common.Address
+	Cheque        *SignedCheque   // last received cheque, nil if there is none
+	Status        *CashoutStatus  // current cashout status
+	PaidOut       *big.Int        // amount paid out on-chain to the beneficiary of the cheque
+	Action        *CashoutAction  // stored last cashout action, nil if the vault was never cashed locally
+	Results       []CashOutResult // result history in the order of CashoutResults
+	PendingTxs    []common.Hash   // transactions of cashouts whose result has not been stored yet
+	UncashedCount int             // number of received cheques not cashed yet
+	Errors        []string        // failures while gathering the dump
+}
+
+// DumpVaultCashout gathers the full cashout state of a vault for diagnostics. Failures of single parts do not
+// fail the dump, so it can be called for any vault including one without any history.
+func (s *cashoutService) DumpVaultCashout(ctx context.Context, vault common.Address) (VaultCashoutDump, error) {
+	dump := VaultCashoutDump{
+		Vault:      vault,
+		Results:    make([]CashOutResult, 0),
+		PendingTxs: make([]common.Hash, 0),
+	}
+	fail := func(part string, err error) {
+		dump.Errors = append(dump.Errors, fmt.Sprintf("%s: %v", part, err))
+	}
+
+	cheque, err := s.lastReceivedCheque(vault)
+	if err == nil {
+		dump.Cheque = cheque
+	} else if !errors.Is(err, ErrNoCheque) {
+		fail("cheque", err)
+	}
+
+	dump.Status, err = s.CashoutStatus(ctx, vault)
+	if err != nil {
+		fail("status", err)
+	}
+
+	beneficiary := s.beneficiary
+	if cheque != nil {
+		beneficiary = cheque.Beneficiary
+	}
+	if beneficiary != (common.Address{}) {
+		dump.PaidOut, err = s.paidOut(ctx, vault, beneficiary)
+		if err != nil {
+			fail("paid out", err)
+		}
+	}
+
+	dump.Action, err = s.repository.Action(vault)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		fail("action", err)
+	}
+
+	err = s.repository.IterateResults(func(result CashOutResult) (bool, error) {
+		if err := ctx.Err(); err != nil {
+			return true, err
+		}
+		if result.Vault == vault {
+			dump.Results = append(dump.Results, result)
+		}
+		return false, nil
+	})
+	if err != nil {
+		fail("results", err)
+	}
+	sortCashoutResults(dump.Results)
+
+	err = s.store.Iterate(fmt.Sprintf("%s%x_", cashoutIntentPrefix, vault), func(key, val []byte) (bool, error) {
+		var intent cashoutIntent
+		if err := s.store.Get(string(key), &intent); err != nil {
+			return true, err
+		}
+		if intent.TxHash != (common.Hash{}) {
+			dump.PendingTxs = append(dump.PendingTxs, intent.TxHash)
+		}
+		return false, nil
+	})
+	if err != nil {
+		fail("pending", err)
+	}
+
+	err = s.store.Get(statestore.PeerReceivedUncashRecordsCountKey(vault), &dump.UncashedCount)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		fail("uncashed count", err)
+	}
+
+	return dump, ctx.Err()
+}
@@ -0,0 +1,92 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	// etaBlockSample is the number of recent blocks the average block time is derived from
+	etaBlockSample = 20
+	// etaFallbackBlockTime is assumed if the block time cannot be derived from the chain
+	etaFallbackBlockTime = 2 * time.Second
+)
+
+// ETAConfidence tells how reliable an estimate of CashoutETA is
+type ETAConfidence int
+
+const (
+	// ETAConfidenceLow means the transaction is priced well below the market, it may take much longer or never be mined
+	ETAConfidenceLow ETAConfidence = iota
+	// ETAConfidenceMedium means the transaction is priced slightly below the market
+	ETAConfidenceMedium
+	// ETAConfidenceHigh means the transaction is priced at or above the market or already mined
+	ETAConfidenceHigh
+)
+
+func (c ETAConfidence) String() string {
+	switch c {
+	case ETAConfidenceLow:
+		return "low"
+	case ETAConfidenceMedium:
+		return "medium"
+	case ETAConfidenceHigh:
+		return "high"
+	default:
+		return "unknown"
+	}
+}
+
+// CashoutETA estimates when a pending cashout transaction will be mined by comparing its gas price to the price
+// suggested by the gas oracle. The estimate is best effort, the confidence tells how much to trust it. Mined
+// transactions return 0 with high confidence.
+func (s *cashoutService) CashoutETA(ctx context.Context, txHash common.Hash) (time.Duration, ETAConfidence, error) {
+	tx, pending, err := s.transactionByHash(ctx, txHash)
+	if err != nil {
+		return 0, ETAConfidenceLow, err
+	}
+	if !pending {
+		return 0, ETAConfidenceHigh, nil
+	}
+
+	suggested, err := s.gasOracle.SuggestGasPrice(ctx)
+	if err != nil {
+		return 0, ETAConfidenceLow, err
+	}
+	blockTime := s.averageBlockTime(ctx)
+
+	// gasPrice * 10 >= suggested * 10 means at the market, >= suggested * 8 slightly below it
+	offered := new(big.Int).Mul(tx.GasPrice(), big.NewInt(10))
+	switch {
+	case offered.Cmp(new(big.Int).Mul(suggested, big.NewInt(10))) >= 0:
+		return blockTime, ETAConfidenceHigh, nil
+	case offered.Cmp(new(big.Int).Mul(suggested, big.NewInt(8))) >= 0:
+		return 3 * blockTime, ETAConfidenceMedium, nil
+	default:
+		return etaBlockSample * blockTime, ETAConfidenceLow, nil
+	}
+}
+
+// averageBlockTime derives the block time from the timestamps of the recent blocks
+func (s *cashoutService) averageBlockTime(ctx context.Context) time.Duration {
+	latest, err := s.backend.HeaderByNumber(ctx, nil)
+	if err != nil || latest.Number == nil || latest.Number.Uint64() < etaBlockSample {
+		return etaFallbackBlockTime
+	}
+	older, err := s.backend.HeaderByNumber(ctx, new(big.Int).Sub(latest.Number, big.NewInt(etaBlockSample)))
+	if err != nil {
+		if !errors.Is(err, ethereum.NotFound) {
+			log.Infof("cashout eta: could not get header: %v", err)
+		}
+		return etaFallbackBlockTime
+	}
+	if latest.Time <= older.Time {
+		return etaFallbackBlockTime
+	}
+	return time.Duration(latest.Time-older.Time) * time.Second / etaBlockSample
+}
@@ -0,0 +1,79 @@
+package vault
+
+import (
+	"fmt"
+	"sort"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// cashoutEventPrefix is the prefix of the store keys of the cashout event log
+const cashoutEventPrefix = "swap_cashout_event_"
+
+// CashoutEventType is a state transition of a cashout
+type CashoutEventType string
+
+// The state transitions recorded in the cashout event log
+const (
+	CashoutEventSubmitted  CashoutEventType = "submitted"  // the cashout transaction was sent
+	CashoutEventConfirming CashoutEventType = "confirming" // the transaction was mined and waits for the confirmation policy
+	CashoutEventConfirmed  CashoutEventType = "confirmed"  // the transaction was confirmed and succeeded
+	CashoutEventReverted   CashoutEventType = "reverted"   // the transaction was confirmed but reverted
+	CashoutEventBounced    CashoutEventType = "bounced"    // the confirmed cashout bounced partially
+	CashoutEventCancelled  CashoutEventType = "cancelled"  // the transaction was cancelled after missing its deadline
+	CashoutEventDropped    CashoutEventType = "dropped"    // the transaction was never mined and the cashout was forgotten
+)
+
+// CashoutEvent is an entry of the append-only cashout event log
+type CashoutEvent struct {
+	TxHash common.Hash
+	Type   CashoutEventType
+	Time   int64 // unix time in nanoseconds
+}
+
+// cashoutEventKey computes the store key of an event. The zero padded time and sequence number keep the keys of a
+// vault in the order the events were recorded.
+func cashoutEventKey(vault common.Address, event CashoutEvent, seq uint32) string {
+	return fmt.Sprintf("%s%x_%020d_%010d_%x_%s", cashoutEventPrefix, vault, event.Time, seq, event.TxHash, event.Type)
+}
+
+// recordCashoutEvent appends an event to the event log of the vault. The log is diagnostic, so failures are only logged.
+func (s *cashoutService) recordCashoutEvent(store storeReadWriter, vault common.Address, txHash common.Hash, eventType CashoutEventType) {
+	event := CashoutEvent{
+		TxHash: txHash,
+		Type:   eventType,
+		Time:   s.now().UnixNano(),
+	}
+	err := store.Put(cashoutEventKey(vault, event, atomic.AddUint32(&s.eventSeq, 1)), event)
+	if err != nil {
+		log.Errorf("cashout: could not record %s event of %x for vault %x: %v", eventType, txHash, vault, err)
+	}
+}
+
+// CashoutEvents returns the state transitions of the cashouts of the vault in the order they happened
+func (s *cashoutService) CashoutEvents(vault common.Address) ([]CashoutEvent, error) {
+	prefix := fmt.Sprintf("%s%x_", cashoutEventPrefix, vault)
+	var keys []string
+	events := make(map[string]CashoutEvent)
+	err := s.store.Iterate(prefix, func(key, val []byte) (stop bool, err error) {
+		var event CashoutEvent
+		err = s.store.Get(string(key), &event)
+		if err != nil {
+			return false, err
+		}
+		keys = append(keys, string(key))
+		events[string(key)] = event
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(keys)
+	result := make([]CashoutEvent, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, events[key])
+	}
+	return result, nil
+}
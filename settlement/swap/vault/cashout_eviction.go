@@ -0,0 +1,159 @@
+package vault
+
+import (
+	"errors"
+	"math/big"
+	"sync"
+
+	"github.com/bittorrent/go-btfs/transaction/storage"
+)
+
+// resultsEvictedUntilKey holds the cash time of the newest cashout result evicted so far
+const resultsEvictedUntilKey = "swap_cashout_results_evicted_until"
+
+// resultsEvictedTotalsKey holds the running sum of the successful cashout results evicted so far
+const resultsEvictedTotalsKey = "swap_cashout_results_evicted_totals"
+
+// evictedTotals is the part of the cashed totals whose results have been evicted
+type evictedTotals struct {
+	Cashed *big.Int // sum of the amounts of the evicted successful results
+	Count  int      // number of evicted successful results
+}
+
+// loadEvictedTotals reads the evicted totals, zero if nothing has been evicted
+func loadEvictedTotals(store storeReadWriter) (evictedTotals, error) {
+	totals := evictedTotals{
+		Cashed: big.NewInt(0),
+	}
+	err := store.Get(resultsEvictedTotalsKey, &totals)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return evictedTotals{}, err
+	}
+	if totals.Cashed == nil {
+		totals.Cashed = big.NewInt(0)
+	}
+	return totals, nil
+}
+
+// resultEviction bounds the number of stored cashout results
+type resultEviction struct {
+	max int
+
+	mu sync.Mutex // serializes evictions
+}
+
+// StoredResultsInfo describes the stored cashout results
+type StoredResultsInfo struct {
+	Count        int   // number of stored results
+	Max          int   // maximum number of stored results, 0 if unbounded
+	EvictedUntil int64 // cash time of the newest evicted result, results up to it may be missing. 0 if none was evicted
+}
+
+// WithMaxStoredResults bounds the number of stored cashout results. Once it is exceeded the oldest results by
+// CashTime are evicted as new ones are stored. The cashed totals are kept separately and stay intact, the sum of
+// the evicted results is kept so VerifyTotals still accounts for them.
+func WithMaxStoredResults(max int) CashoutServiceOption {
+	return cashoutServiceOptionFunc(func(s *cashoutService) {
+		if max > 0 {
+			s.resultEviction = &resultEviction{max: max}
+		}
+	})
+}
+
+// evictResults deletes the oldest results beyond the maximum number of stored results. The evicted successful
+// results are added to the evicted totals so the totals can still be verified against the remaining results.
+// Failures are only logged, they are retried with the next stored result.
+func (s *cashoutService) evictResults() {
+	e := s.resultEviction
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	// the results and the evicted totals must change together for the totals verification
+	s.totalsLock.Lock()
+	defer s.totalsLock.Unlock()
+
+	var results []CashOutResult
+	err := s.repository.IterateResults(func(result CashOutResult) (bool, error) {
+		results = append(results, result)
+		return false, nil
+	})
+	if err != nil {
+		log.Errorf("cashout: could not read results for eviction: %v", err)
+		return
+	}
+	if len(results) <= e.max {
+		return
+	}
+	sortCashoutResults(results)
+	evicted := results[:len(results)-e.max]
+
+	batch := newStoreBatch(s.store)
+	totals, err := loadEvictedTotals(batch)
+	if err != nil {
+		log.Errorf("cashout: could not read evicted totals: %v", err)
+		return
+	}
+	repository := s.batchRepository(batch)
+	for i := range evicted {
+		if evicted[i].Status == "success" && evicted[i].Amount != nil && !evicted[i].Rebuilt {
+			totals.Cashed.Add(totals.Cashed, evicted[i].Amount)
+			totals.Count++
+		}
+		err = repository.DeleteResult(&evicted[i])
+		if err != nil {
+			log.Errorf("cashout: could not evict result of transaction %x: %v", evicted[i].TxHash, err)
+			return
+		}
+		err = batch.Delete(appliedResultKey(evicted[i].Vault, evicted[i].TxHash))
+		if err != nil {
+			log.Errorf("cashout: could not evict applied marker of transaction %x: %v", evicted[i].TxHash, err)
+			return
+		}
+	}
+	err = batch.Put(resultsEvictedTotalsKey, totals)
+	if err != nil {
+		log.Errorf("cashout: could not update evicted totals: %v", err)
+		return
+	}
+	var evictedUntil int64
+	err = batch.Get(resultsEvictedUntilKey, &evictedUntil)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		log.Errorf("cashout: could not read eviction high-water mark: %v", err)
+		return
+	}
+	if newest := evicted[len(evicted)-1].CashTime; newest > evictedUntil {
+		err = batch.Put(resultsEvictedUntilKey, newest)
+		if err != nil {
+			log.Errorf("cashout: could not update eviction high-water mark: %v", err)
+			return
+		}
+	}
+	err = batch.Commit()
+	if err != nil {
+		log.Errorf("cashout: could not evict results: %v", err)
+		return
+	}
+	log.Infof("cashout: evicted %d results", len(evicted))
+}
+
+// StoredResults returns the number of stored cashout results and how far they have been evicted
+func (s *cashoutService) StoredResults() (StoredResultsInfo, error) {
+	var info StoredResultsInfo
+	if s.resultEviction != nil {
+		info.Max = s.resultEviction.max
+	}
+	err := s.repository.IterateResults(func(result CashOutResult) (bool, error) {
+		info.Count++
+		return false, nil
+	})
+	if err != nil {
+		return StoredResultsInfo{}, err
+	}
+	err = s.store.Get(resultsEvictedUntilKey, &info.EvictedUntil)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return StoredResultsInfo{}, err
+	}
+	return info, nil
+}
@@ -0,0 +1,65 @@
+package vault
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bittorrent/go-btfs/transaction/storage"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const autoCashoutExclusionPrefix = "swap_auto_cashout_excluded_"
+
+func autoCashoutExclusionKey(vault common.Address) string {
+	return fmt.Sprintf("%s%x", autoCashoutExclusionPrefix, vault)
+}
+
+// ExcludeVaultFromAutoCashout persistently excludes the vault from the auto cashout loop.
+// Manual cashouts of the vault are not affected.
+func (s *cashoutService) ExcludeVaultFromAutoCashout(vault common.Address) error {
+	return s.store.Put(autoCashoutExclusionKey(vault), vault)
+}
+
+// IncludeVaultInAutoCashout removes an exclusion added with ExcludeVaultFromAutoCashout
+func (s *cashoutService) IncludeVaultInAutoCashout(vault common.Address) error {
+	err := s.store.Delete(autoCashoutExclusionKey(vault))
+	if err != nil && err != storage.ErrNotFound {
+		return err
+	}
+	return nil
+}
+
+// AutoCashoutExclusions returns the vaults excluded from the auto cashout loop, sorted by address
+func (s *cashoutService) AutoCashoutExclusions() ([]common.Address, error) {
+	excluded, err := s.autoCashoutExclusions()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]common.Address, 0, len(excluded))
+	for vault := range excluded {
+		result = append(result, vault)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return bytes.Compare(result[i].Bytes(), result[j].Bytes()) < 0
+	})
+	return result, nil
+}
+
+func (s *cashoutService) autoCashoutExclusions() (map[common.Address]struct{}, error) {
+	excluded := make(map[common.Address]struct{})
+	err := s.store.Iterate(autoCashoutExclusionPrefix, func(key, val []byte) (stop bool, err error) {
+		hexVault := strings.TrimPrefix(string(key), autoCashoutExclusionPrefix)
+		if !common.IsHexAddress(hexVault) {
+			return false, nil
+		}
+		excluded[common.HexToAddress(hexVault)] = struct{}{}
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return excluded, nil
+}
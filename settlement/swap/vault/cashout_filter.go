@@ -0,0 +1,65 @@
+package vault
+
+import (
+	"math/big"
+	"time"
+)
+
+// CashoutResultFilter selects cashout results. Zero fields do not restrict the results.
+type CashoutResultFilter struct {
+	MinAmount *big.Int  // smallest cashed amount, included
+	MaxAmount *big.Int  // largest cashed amount, included
+	From      time.Time // earliest cash time, included
+	To        time.Time // cash time the results have to be before
+	Status    string    // status of the results, e.g. "success"
+}
+
+// matches reports whether the result passes the filter
+func (f *CashoutResultFilter) matches(result *CashOutResult) bool {
+	if f.MinAmount != nil || f.MaxAmount != nil {
+		if result.Amount == nil {
+			return false
+		}
+		if f.MinAmount != nil && result.Amount.Cmp(f.MinAmount) < 0 {
+			return false
+		}
+		if f.MaxAmount != nil && result.Amount.Cmp(f.MaxAmount) > 0 {
+			return false
+		}
+	}
+	if !f.From.IsZero() && result.CashTime < f.From.Unix() {
+		return false
+	}
+	if !f.To.IsZero() && result.CashTime >= f.To.Unix() {
+		return false
+	}
+	if f.Status != "" && result.Status != f.Status {
+		return false
+	}
+	return true
+}
+
+// FilterCashoutResults returns the cashout results passing the filter in the order of CashoutResults
+func (s *cashoutService) FilterCashoutResults(filter CashoutResultFilter) ([]CashOutResult, error) {
+	result := make([]CashOutResult, 0)
+	err := s.repository.IterateResults(func(cashOutResult CashOutResult) (stop bool, err error) {
+		if filter.matches(&cashOutResult) {
+			result = append(result, cashOutResult)
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sortCashoutResults(result)
+	return result, nil
+}
+
+// CashoutResultsByAmount returns the cashout results whose cashed amount is between min and max, both included, in
+// the order of CashoutResults. A nil bound leaves the range open on that side.
+func (s *cashoutService) CashoutResultsByAmount(min, max *big.Int) ([]CashOutResult, error) {
+	return s.FilterCashoutResults(CashoutResultFilter{
+		MinAmount: min,
+		MaxAmount: max,
+	})
+}
@@ -0,0 +1,190 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/bittorrent/go-btfs/statestore"
+	"github.com/bittorrent/go-btfs/transaction/storage"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	// ErrCashoutPending is the error if the data of a vault is to be removed while one of its cashouts is pending
+	ErrCashoutPending = errors.New("cashout pending")
+)
+
+// ForgetVault removes everything the service stored about the cashouts of the vault: the last action, the
+// results, the event log, the raw receipts, the default recipient and the auto cashout exclusion. The amounts of
+// the successful results are subtracted from the cashed totals so they stay consistent with the remaining results.
+// It returns ErrCashoutPending while a cashout of the vault has not been recorded yet.
+func (s *cashoutService) ForgetVault(ctx context.Context, vault common.Address) error {
+	s.actionLock.Lock()
+	defer s.actionLock.Unlock()
+
+	err := s.checkNoPendingCashout(ctx, vault)
+	if err != nil {
+		return err
+	}
+
+	var results []CashOutResult
+	err = s.repository.IterateResults(func(result CashOutResult) (bool, error) {
+		if result.Vault == vault {
+			results = append(results, result)
+		}
+		return false, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.totalsLock.Lock()
+	defer s.totalsLock.Unlock()
+
+	batch := newStoreBatch(s.store)
+	err = s.subtractCashedTotals(batch, results)
+	if err != nil {
+		return err
+	}
+
+	repository := s.batchRepository(batch)
+	for i := range results {
+		err = repository.DeleteResult(&results[i])
+		if err != nil {
+			return err
+		}
+		err = batch.Delete(rawReceiptKey(results[i].TxHash))
+		if err != nil {
+			return err
+		}
+		err = batch.Delete(appliedResultKey(vault, results[i].TxHash))
+		if err != nil {
+			return err
+		}
+	}
+	err = repository.DeleteAction(vault)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+
+	err = s.store.Iterate(fmt.Sprintf("%s%x_", cashoutEventPrefix, vault), func(key, val []byte) (stop bool, err error) {
+		return false, batch.Delete(string(key))
+	})
+	if err != nil {
+		return err
+	}
+	for _, key := range []string{defaultRecipientKey(vault), autoCashoutExclusionKey(vault), destroyedVaultKey(vault), importedChequeKey(vault)} {
+		err = batch.Delete(key)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = batch.Commit()
+	if err != nil {
+		return err
+	}
+	s.autoDecisions.forget(vault)
+	log.Infof("cashout: forgot vault %x and its %d cashout results", vault, len(results))
+	return nil
+}
+
+// checkNoPendingCashout returns ErrCashoutPending if the write-ahead log holds a cashout of the vault or its last
+// cashout transaction has not been mined
+func (s *cashoutService) checkNoPendingCashout(ctx context.Context, vault common.Address) error {
+	intents := 0
+	err := s.store.Iterate(fmt.Sprintf("%s%x_", cashoutIntentPrefix, vault), func(key, val []byte) (stop bool, err error) {
+		intents++
+		return true, nil
+	})
+	if err != nil {
+		return err
+	}
+	if intents > 0 {
+		return fmt.Errorf("%w: vault %x has unrecorded cashouts", ErrCashoutPending, vault)
+	}
+
+	action, err := s.repository.Action(vault)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	_, pending, err := s.transactionByHash(ctx, action.TxHash)
+	if err != nil {
+		if !errors.Is(err, ethereum.NotFound) {
+			return err
+		}
+		// an unknown transaction might still be broadcast unless it has been pending for too long
+		pending = !s.isStale(action)
+	}
+	if pending {
+		return fmt.Errorf("%w: transaction %x of vault %x", ErrCashoutPending, action.TxHash, vault)
+	}
+	return nil
+}
+
+// subtractCashedTotals removes the successful results from the cashed totals. Exactly what addCashedTotals and
+// addCashedCount added for a result is subtracted, as recorded on the result.
+func (s *cashoutService) subtractCashedTotals(batch storeReadWriter, results []CashOutResult) error {
+	amounts := make(map[string]*big.Int)
+	subtract := func(key string, amount *big.Int) {
+		if amounts[key] == nil {
+			amounts[key] = big.NewInt(0)
+		}
+		amounts[key].Add(amounts[key], amount)
+	}
+	count := 0
+	for _, result := range results {
+		// rebuilt results were never added to the totals
+		if result.Status != "success" || result.Amount == nil || result.Rebuilt {
+			continue
+		}
+		day := dayUnix(time.Unix(result.CashTime, 0))
+		subtract(statestore.TotalReceivedCashedKey, result.Amount)
+		subtract(statestore.GetTotalDailyReceivedCashedKeyByTime(day), result.Amount)
+		if result.CallerPayout != nil {
+			subtract(statestore.TotalCallerPayoutKey, result.CallerPayout)
+		}
+		if result.Token != (common.Address{}) {
+			subtract(statestore.TotalReceivedCashedByTokenKey(result.Token), result.Amount)
+			subtract(statestore.GetTotalDailyReceivedCashedByTokenKeyByTime(day, result.Token), result.Amount)
+		}
+		count += result.CashedCount
+	}
+
+	for key, amount := range amounts {
+		stored := big.NewInt(0)
+		err := batch.Get(key, &stored)
+		if err != nil && err != storage.ErrNotFound {
+			return err
+		}
+		stored.Sub(stored, amount)
+		if stored.Sign() < 0 {
+			stored.SetInt64(0)
+		}
+		err = batch.Put(key, stored)
+		if err != nil {
+			return err
+		}
+	}
+
+	if count == 0 {
+		return nil
+	}
+	cashedCount := 0
+	err := batch.Get(statestore.TotalReceivedCashedCountKey, &cashedCount)
+	if err != nil && err != storage.ErrNotFound {
+		return err
+	}
+	cashedCount -= count
+	if cashedCount < 0 {
+		cashedCount = 0
+	}
+	return batch.Put(statestore.TotalReceivedCashedCountKey, cashedCount)
+}
@@ -0,0 +1,93 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/bittorrent/go-btfs/transaction/storage"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	cashoutGasAverageKey = "swap_cashout_gas_average"
+
+	// gasAverageWeight is the inverse of the smoothing factor of the moving average, recent cashouts weigh 1/5
+	gasAverageWeight = 5
+
+	// estimateCashoutTimeout bounds the gas estimation before the moving average is used instead
+	estimateCashoutTimeout = 2 * time.Second
+)
+
+var (
+	// ErrNoGasEstimate is the error if the gas could neither be estimated nor derived from recent cashouts
+	ErrNoGasEstimate = errors.New("no gas estimate available")
+)
+
+// updateGasAverage folds the gas used by a cashout into the exponential moving average of recent cashouts
+func updateGasAverage(store storeReadWriter, gasUsed uint64) error {
+	var average uint64
+	err := store.Get(cashoutGasAverageKey, &average)
+	if err != nil && err != storage.ErrNotFound {
+		return err
+	}
+	if average == 0 {
+		average = gasUsed
+	} else if gasUsed >= average {
+		average += (gasUsed - average) / gasAverageWeight
+	} else {
+		average -= (average - gasUsed) / gasAverageWeight
+	}
+	return store.Put(cashoutGasAverageKey, average)
+}
+
+// RecentAverageGas returns the exponential moving average of the gas used by recent cashouts, 0 if none is known
+func (s *cashoutService) RecentAverageGas() uint64 {
+	var average uint64
+	err := s.store.Get(cashoutGasAverageKey, &average)
+	if err != nil {
+		return 0
+	}
+	return average
+}
+
+// EstimateCashout estimates the gas needed to cash the last cheque of the vault. If the estimation fails or takes
+// too long the average gas of recent cashouts is returned instead. If the estimation reverts the decoded reason is
+// returned.
+func (s *cashoutService) EstimateCashout(ctx context.Context, vault, recipient common.Address) (uint64, error) {
+	cheque, err := s.lastReceivedCheque(vault)
+	if err != nil {
+		return 0, err
+	}
+
+	callData, err := vaultABI.Pack("cashChequeBeneficiary", recipient, cheque.CumulativePayout, cheque.Signature)
+	if err != nil {
+		return 0, err
+	}
+
+	estimateCtx, cancel := context.WithTimeout(ctx, estimateCashoutTimeout)
+	defer cancel()
+	gas, err := s.backend.EstimateGas(estimateCtx, ethereum.CallMsg{
+		From:  s.beneficiary,
+		To:    &vault,
+		Value: big.NewInt(0),
+		Data:  callData,
+	})
+	if err == nil {
+		return gas, nil
+	}
+	// a revert is not a slow or unavailable estimate, the cashout would fail
+	if reason, ok := revertReason(err); ok {
+		return 0, fmt.Errorf("%w: %s", ErrCashoutSimulationReverted, reason)
+	}
+
+	average := s.RecentAverageGas()
+	if average == 0 {
+		return 0, fmt.Errorf("%w: %v", ErrNoGasEstimate, err)
+	}
+	log.Infof("estimate cashout of vault %x failed, using recent average gas %d: %v", vault, average, err)
+	return average, nil
+}
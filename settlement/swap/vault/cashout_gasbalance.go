@@ -0,0 +1,77 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	// ErrInsufficientGasBalance is matched by the InsufficientGasBalanceError returned if the sending account cannot
+	// pay for the gas of a cashout
+	ErrInsufficientGasBalance = errors.New("insufficient native balance for cashout gas")
+)
+
+// InsufficientGasBalanceError is returned if the native balance of the sending account is below the estimated gas
+// cost of a cashout
+type InsufficientGasBalanceError struct {
+	Balance   *big.Int // native balance of the sending account
+	Cost      *big.Int // estimated gas cost of the cashout
+	Shortfall *big.Int // how much is missing to pay for the gas
+}
+
+func (e *InsufficientGasBalanceError) Error() string {
+	return fmt.Sprintf("%v: balance %d, estimated cost %d, shortfall %d", ErrInsufficientGasBalance, e.Balance, e.Cost, e.Shortfall)
+}
+
+// Is makes every InsufficientGasBalanceError match ErrInsufficientGasBalance
+func (e *InsufficientGasBalanceError) Is(target error) bool {
+	return target == ErrInsufficientGasBalance
+}
+
+// checkGasBalance returns an InsufficientGasBalanceError if the native balance of the sending account does not cover
+// gas at the suggested gas price. The preflight is best effort, if the balance or the gas price cannot be queried the
+// cashout is sent as before.
+func (s *cashoutService) checkGasBalance(ctx context.Context, gas uint64) error {
+	if gas == 0 || s.beneficiary == (common.Address{}) {
+		return nil
+	}
+
+	gasPrice, err := s.gasOracle.SuggestGasPrice(ctx)
+	if err != nil {
+		log.Infof("cashout: skipping gas balance check, could not get gas price: %v", err)
+		return nil
+	}
+	balance, err := s.backend.BalanceAt(ctx, s.beneficiary, nil)
+	if err != nil {
+		log.Infof("cashout: skipping gas balance check, could not get balance of %x: %v", s.beneficiary, err)
+		return nil
+	}
+
+	cost := new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(gas))
+	if balance.Cmp(cost) >= 0 {
+		return nil
+	}
+	return &InsufficientGasBalanceError{
+		Balance:   balance,
+		Cost:      cost,
+		Shortfall: new(big.Int).Sub(cost, balance),
+	}
+}
+
+// cashoutGas returns the gas limit of the cashout if it is forced, otherwise the estimated gas. It is 0 if the gas
+// cannot be estimated.
+func (s *cashoutService) cashoutGas(ctx context.Context, vault, recipient common.Address, gasLimit uint64) uint64 {
+	if gasLimit != 0 {
+		return gasLimit
+	}
+	gas, err := s.EstimateCashout(ctx, vault, recipient)
+	if err != nil {
+		log.Infof("cashout: could not estimate gas of cashout of vault %x: %v", vault, err)
+		return 0
+	}
+	return gas
+}
@@ -0,0 +1,23 @@
+package vault
+
+import (
+	"errors"
+	"fmt"
+)
+
+// minCashoutGasLimit is the lowest gas limit accepted for a cashout. cashChequeBeneficiary verifies the signature,
+// updates paidOut and transfers the tokens, which never fits in less, so a lower limit is a guaranteed revert.
+const minCashoutGasLimit = 50000
+
+var (
+	// ErrGasLimitTooLow is the error if a cashout is requested with a gas limit it cannot succeed with
+	ErrGasLimitTooLow = errors.New("cashout gas limit too low")
+)
+
+// checkGasLimit returns ErrGasLimitTooLow if the gas limit is set and below minCashoutGasLimit
+func checkGasLimit(gasLimit uint64) error {
+	if gasLimit == 0 || gasLimit >= minCashoutGasLimit {
+		return nil
+	}
+	return fmt.Errorf("%w: %d, minimum %d", ErrGasLimitTooLow, gasLimit, minCashoutGasLimit)
+}
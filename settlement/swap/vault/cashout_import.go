@@ -0,0 +1,164 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bittorrent/go-btfs/transaction/storage"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// importedChequePrefix is the prefix of the store keys of the cheques imported with ImportVaults
+const importedChequePrefix = "swap_cashout_imported_"
+
+var (
+	// ErrInvalidImport is the error if an imported entry does not match its cheque
+	ErrInvalidImport = errors.New("invalid import entry")
+	// ErrUnknownChainID is the error if the chain id needed to verify a cheque signature is not known
+	ErrUnknownChainID = errors.New("unknown chain id")
+)
+
+// ImportEntry is a vault whose last cheque was received by another node
+type ImportEntry struct {
+	Vault       common.Address
+	Beneficiary common.Address
+	Cheque      SignedCheque // last cheque known for the vault
+}
+
+func importedChequeKey(vault common.Address) string {
+	return fmt.Sprintf("%s%x", importedChequePrefix, vault)
+}
+
+// ImportVaults stores the last cheques received by another node so they can be cashed like the cheques received by
+// this node. Every cheque must be signed by the issuer of its vault. Nothing is imported if any entry is invalid. An
+// imported cheque only replaces a previously imported one with a higher cumulative payout.
+func (s *cashoutService) ImportVaults(ctx context.Context, entries []ImportEntry) error {
+	batch := newStoreBatch(s.store)
+	for i := range entries {
+		entry := &entries[i]
+		err := s.validateImportEntry(ctx, entry)
+		if err != nil {
+			return fmt.Errorf("import of vault %x: %w", entry.Vault, err)
+		}
+
+		var imported SignedCheque
+		err = batch.Get(importedChequeKey(entry.Vault), &imported)
+		if err == nil && imported.CumulativePayout.Cmp(entry.Cheque.CumulativePayout) >= 0 {
+			continue
+		}
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return err
+		}
+		err = batch.Put(importedChequeKey(entry.Vault), &entry.Cheque)
+		if err != nil {
+			return err
+		}
+	}
+	err := batch.Commit()
+	if err != nil {
+		return err
+	}
+	log.Infof("cashout: imported %d vaults", len(entries))
+	return nil
+}
+
+// validateImportEntry checks that the cheque belongs to the entry, can be cashed by the node and was signed by the
+// issuer of the vault
+func (s *cashoutService) validateImportEntry(ctx context.Context, entry *ImportEntry) error {
+	cheque := &entry.Cheque
+	if cheque.Vault != entry.Vault || cheque.Beneficiary != entry.Beneficiary || cheque.CumulativePayout == nil {
+		return ErrInvalidImport
+	}
+	err := s.checkBeneficiary(cheque)
+	if err != nil {
+		return err
+	}
+
+	recoverCheque := RecoverChequeFunc(RecoverCheque)
+	var chainID int64
+	switch {
+	case s.signatureVerifier != nil:
+		recoverCheque, chainID = s.signatureVerifier.recoverCheque, s.signatureVerifier.chainID
+	case s.chainIDCheck != nil:
+		chainID = s.chainIDCheck.chainID.Int64()
+	default:
+		backend, err := s.backendChainID(ctx)
+		if err != nil {
+			return err
+		}
+		if backend == nil {
+			return ErrUnknownChainID
+		}
+		chainID = backend.Int64()
+	}
+
+	signer, err := recoverCheque(cheque, chainID)
+	if err != nil {
+		return err
+	}
+	issuer, err := newVaultContract(entry.Vault, s.transactionService).Issuer(ctx)
+	if err != nil {
+		return err
+	}
+	if signer != issuer {
+		return ErrChequeInvalid
+	}
+	return nil
+}
+
+// lastReceivedCheque returns the last cheque of the vault received by the node or imported, whichever pays out more
+func (s *cashoutService) lastReceivedCheque(vault common.Address) (*SignedCheque, error) {
+	received, err := s.chequeStore.LastReceivedCheque(vault)
+	if err != nil && !errors.Is(err, ErrNoCheque) {
+		return nil, err
+	}
+
+	var imported SignedCheque
+	importErr := s.store.Get(importedChequeKey(vault), &imported)
+	if importErr != nil {
+		if !errors.Is(importErr, storage.ErrNotFound) {
+			return nil, importErr
+		}
+		// err is ErrNoCheque if nothing was received either
+		return received, err
+	}
+	if received != nil && received.CumulativePayout.Cmp(imported.CumulativePayout) >= 0 {
+		return received, nil
+	}
+	return &imported, nil
+}
+
+// lastReceivedCheques returns the last cheques of all vaults received by the node or imported
+func (s *cashoutService) lastReceivedCheques() (map[common.Address]*SignedCheque, error) {
+	cheques, err := s.chequeStore.LastReceivedCheques()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[common.Address]*SignedCheque, len(cheques))
+	for vault, cheque := range cheques {
+		result[vault] = cheque
+	}
+	err = s.store.Iterate(importedChequePrefix, func(key, val []byte) (stop bool, err error) {
+		hexVault := strings.TrimPrefix(string(key), importedChequePrefix)
+		if !common.IsHexAddress(hexVault) {
+			return false, nil
+		}
+		var imported SignedCheque
+		err = s.store.Get(string(key), &imported)
+		if err != nil {
+			return true, err
+		}
+		vault := common.HexToAddress(hexVault)
+		if received, ok := result[vault]; !ok || received.CumulativePayout.Cmp(imported.CumulativePayout) < 0 {
+			result[vault] = &imported
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,304 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// AutoCashoutConfig configures the background loop which periodically cashes out received cheques
+type AutoCashoutConfig struct {
+	Recipient    common.Address // recipient of the automatic cashouts, zero to use the default recipient of each vault
+	LoopInterval time.Duration  // base interval between two iterations of the loop
+	LoopJitter   time.Duration  // maximum random delay added to every iteration, spreads submissions of many nodes
+	MinAmount    *big.Int       // minimum uncashed amount for a vault to be cashed out
+	MaxGasPrice  *big.Int       // cashouts are deferred while the suggested gas price is above this ceiling, nil disables the check
+
+	MinTokenPriceUSD float64 // cashouts are deferred while the vault token is worth less, 0 disables the check
+
+	// MaxVaultsPerIteration bounds the number of vaults checked by one iteration, 0 checks all of them. Every
+	// iteration continues after the last vault checked by the previous one, also across restarts.
+	MaxVaultsPerIteration int
+}
+
+// ReceivedChequeSubscriber is implemented by cheque stores which notify about received cheques. The auto cashout
+// loop checks the vault of every notified cheque right away instead of waiting for its next iteration.
+type ReceivedChequeSubscriber interface {
+	SubscribeReceivedCheques() (c <-chan *SignedCheque, unsubscribe func())
+}
+
+// GasOracle suggests the current gas price
+type GasOracle interface {
+	SuggestGasPrice(ctx context.Context) (*big.Int, error)
+}
+
+// WithGasOracle sets the gas oracle consulted by the auto cashout loop. By default the backend is used.
+func WithGasOracle(oracle GasOracle) CashoutServiceOption {
+	return cashoutServiceOptionFunc(func(s *cashoutService) {
+		s.gasOracle = oracle
+	})
+}
+
+// WithAutoCashout enables the background cashout loop. The loop only runs once Start has been called.
+func WithAutoCashout(config AutoCashoutConfig) CashoutServiceOption {
+	return cashoutServiceOptionFunc(func(s *cashoutService) {
+		s.autoCashout = &config
+	})
+}
+
+// startupTaskTimeout bounds every RPC-bound task run by Start, so an unreachable backend cannot keep it busy forever
+const startupTaskTimeout = 2 * time.Minute
+
+// Start resumes the tracking of cashouts interrupted by a shutdown and starts the background cashout loop if it has
+// been configured. Only the local migration runs before it returns, the tasks talking to the backend run in the
+// background so a slow backend does not hold up the startup of the node.
+func (s *cashoutService) Start() {
+	err := s.migrateCashoutResults()
+	if err != nil {
+		log.Errorf("cashout: could not partition cashout results: %v", err)
+	}
+
+	s.wg.Add(1)
+	go s.startupTasks()
+}
+
+// startupTasks resumes interrupted cashouts, each task bounded by startupTaskTimeout and cancelled by Close. The
+// background loops are started once the tasks are done so they do not race the recovery.
+func (s *cashoutService) startupTasks() {
+	defer s.wg.Done()
+
+	for _, task := range []struct {
+		name string
+		run  func(ctx context.Context) error
+	}{
+		{"replay write-ahead log", s.replayCashoutIntents},
+		{"resume batch cashouts", s.resumeCashoutBatches},
+		{"recover failed cashout results", func(ctx context.Context) error {
+			_, err := s.RecoverFailedResults(ctx)
+			return err
+		}},
+	} {
+		ctx, cancel := s.startupContext()
+		err := task.run(ctx)
+		cancel()
+		if err != nil {
+			log.Errorf("cashout: could not %s: %v", task.name, err)
+		}
+		select {
+		case <-s.quit:
+			return
+		default:
+		}
+	}
+
+	if s.totalsVerification != nil {
+		s.wg.Add(1)
+		go s.totalsVerificationLoop()
+	}
+
+	if s.autoCashout == nil {
+		return
+	}
+	s.wg.Add(1)
+	go s.autoCashoutLoop()
+}
+
+// startupContext returns a context for a startup task which expires after startupTaskTimeout or on Close
+func (s *cashoutService) startupContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(context.Background(), startupTaskTimeout)
+	go func() {
+		select {
+		case <-s.quit:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+// Close stops the background cashout loop and waits for it to exit
+func (s *cashoutService) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.quit)
+	})
+	s.wg.Wait()
+	return nil
+}
+
+// nextLoopDelay computes the delay until the next iteration. The jitter is drawn anew for every iteration.
+func (s *cashoutService) nextLoopDelay() time.Duration {
+	delay := s.autoCashout.LoopInterval
+	if s.autoCashout.LoopJitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(s.autoCashout.LoopJitter)))
+	}
+	return delay
+}
+
+func (s *cashoutService) autoCashoutLoop() {
+	defer s.wg.Done()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-s.quit
+		cancel()
+	}()
+
+	// cheque stores which notify about received cheques trigger a check of the vault right away,
+	// the periodic iterations still run in case a notification is missed
+	var received <-chan *SignedCheque
+	if subscriber, ok := s.chequeStore.(ReceivedChequeSubscriber); ok {
+		c, unsubscribe := subscriber.SubscribeReceivedCheques()
+		defer unsubscribe()
+		received = c
+	}
+
+	timer := time.NewTimer(s.nextLoopDelay())
+	defer timer.Stop()
+	for {
+		select {
+		case <-s.quit:
+			return
+		case <-timer.C:
+			s.autoCashoutIteration(ctx)
+			timer.Reset(s.nextLoopDelay())
+		case cheque, ok := <-received:
+			if !ok {
+				received = nil
+				continue
+			}
+			s.autoCashoutVaults(ctx, []common.Address{cheque.Vault})
+		}
+	}
+}
+
+// gasPriceAboveCeiling reports whether the suggested gas price is above the configured ceiling
+func (s *cashoutService) gasPriceAboveCeiling(ctx context.Context) (bool, error) {
+	if s.autoCashout.MaxGasPrice == nil {
+		return false, nil
+	}
+	gasPrice, err := s.gasOracle.SuggestGasPrice(ctx)
+	if err != nil {
+		return false, err
+	}
+	return gasPrice.Cmp(s.autoCashout.MaxGasPrice) > 0, nil
+}
+
+// autoCashoutIteration cashes out every vault whose uncashed amount reached the configured minimum
+func (s *cashoutService) autoCashoutIteration(ctx context.Context) {
+	cheques, err := s.lastReceivedCheques()
+	if err != nil {
+		log.Errorf("auto cashout: could not get received cheques: %v", err)
+		return
+	}
+
+	vaults := make([]common.Address, 0, len(cheques))
+	for vault := range cheques {
+		vaults = append(vaults, vault)
+	}
+
+	cursor, err := s.AutoCashoutCursor()
+	if err != nil {
+		log.Errorf("auto cashout: could not get cursor, starting over: %v", err)
+	}
+	vaults = autoCashoutBatch(vaults, cursor, s.autoCashout.MaxVaultsPerIteration)
+
+	checked := s.autoCashoutVaults(ctx, vaults)
+	if checked > 0 {
+		err = s.store.Put(autoCashoutCursorKey, vaults[checked-1])
+		if err != nil {
+			log.Errorf("auto cashout: could not store cursor: %v", err)
+		}
+	}
+}
+
+// autoCashoutVaults cashes out the vaults whose uncashed amount reached the configured minimum and returns how many
+// of them were checked. The decision taken for every vault is recorded for LastAutoCashoutDecision.
+func (s *cashoutService) autoCashoutVaults(ctx context.Context, vaults []common.Address) int {
+	tooExpensive, err := s.gasPriceAboveCeiling(ctx)
+	if err != nil {
+		log.Errorf("auto cashout: could not get gas price: %v", err)
+		return 0
+	}
+	if tooExpensive {
+		log.Infof("auto cashout: gas price above ceiling of %d, deferring cashouts", s.autoCashout.MaxGasPrice)
+		for _, vault := range vaults {
+			s.recordAutoDecision(vault, AutoDecisionGasTooHigh, common.Hash{}, "")
+		}
+		return 0
+	}
+
+	excluded, err := s.autoCashoutExclusions()
+	if err != nil {
+		log.Errorf("auto cashout: could not get excluded vaults: %v", err)
+		return 0
+	}
+
+	for i, vault := range vaults {
+		select {
+		case <-s.quit:
+			return i
+		default:
+		}
+
+		if _, ok := excluded[vault]; ok {
+			s.recordAutoDecision(vault, AutoDecisionExcluded, common.Hash{}, "")
+			continue
+		}
+
+		status, err := s.CashoutStatus(ctx, vault)
+		if err != nil {
+			log.Errorf("auto cashout: could not get cashout status of vault %x: %v", vault, err)
+			s.recordAutoDecision(vault, AutoDecisionStatusFailed, common.Hash{}, err.Error())
+			continue
+		}
+
+		s.checkShortfall(ctx, vault, status.UncashedAmount)
+
+		// do not cash out while a previous cashout is still pending
+		if status.State == CashoutStatePending {
+			s.recordAutoDecision(vault, AutoDecisionPending, status.Last.TxHash, "")
+			continue
+		}
+
+		if status.UncashedAmount.Sign() <= 0 {
+			s.recordAutoDecision(vault, AutoDecisionNothingUncashed, common.Hash{}, "")
+			continue
+		}
+		if s.autoCashout.MinAmount != nil && status.UncashedAmount.Cmp(s.autoCashout.MinAmount) < 0 {
+			s.recordAutoDecision(vault, AutoDecisionBelowThreshold, common.Hash{}, fmt.Sprintf("uncashed %d, minimum %d", status.UncashedAmount, s.autoCashout.MinAmount))
+			continue
+		}
+
+		txHash, err := s.CashChequeWithOptions(ctx, vault, s.autoCashout.Recipient, CashoutOptions{
+			MinTokenPriceUSD: s.autoCashout.MinTokenPriceUSD,
+		})
+		if errors.Is(err, ErrTokenPriceTooLow) {
+			log.Infof("auto cashout: deferring cashout of vault %x: %v", vault, err)
+			s.recordAutoDecision(vault, AutoDecisionPriceTooLow, common.Hash{}, err.Error())
+			continue
+		}
+		if errors.Is(err, ErrDryRun) {
+			log.Infof("auto cashout: %v", err)
+			s.recordAutoDecision(vault, AutoDecisionDryRun, common.Hash{}, err.Error())
+			continue
+		}
+		if err != nil {
+			log.Errorf("auto cashout: could not cash cheque of vault %x: %v", vault, err)
+			reason := AutoDecisionCashoutFailed
+			if errors.Is(err, ErrTooManyInFlight) {
+				reason = AutoDecisionRateLimited
+			}
+			s.recordAutoDecision(vault, reason, common.Hash{}, err.Error())
+			continue
+		}
+		log.Infof("auto cashout: sent cashout of vault %x in transaction %x", vault, txHash)
+		s.recordAutoDecision(vault, AutoDecisionCashed, txHash, "")
+	}
+	return len(vaults)
+}
@@ -0,0 +1,292 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/bittorrent/go-btfs/transaction/storage"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	// ErrInvalidBatchOrdering is the error if CashChequeMulti is given an unknown ordering
+	ErrInvalidBatchOrdering = errors.New("invalid batch ordering")
+)
+
+// BatchOrdering is the order in which CashChequeMulti submits the cashouts
+type BatchOrdering int
+
+const (
+	// BatchOrderingGiven submits the cashouts in the order of the given vaults
+	BatchOrderingGiven BatchOrdering = iota
+	// BatchOrderingLargestFirst submits the cashouts with the largest uncashed amount first
+	BatchOrderingLargestFirst
+	// BatchOrderingOldestFirst submits the cashouts of the vaults whose cheques were received earliest first
+	BatchOrderingOldestFirst
+	// BatchOrderingMostEfficientFirst submits the cashouts with the highest uncashed amount per gas first
+	BatchOrderingMostEfficientFirst
+)
+
+func (o BatchOrdering) String() string {
+	switch o {
+	case BatchOrderingGiven:
+		return "given"
+	case BatchOrderingLargestFirst:
+		return "largest-first"
+	case BatchOrderingOldestFirst:
+		return "oldest-first"
+	case BatchOrderingMostEfficientFirst:
+		return "most-efficient-first"
+	default:
+		return "unknown"
+	}
+}
+
+// CashChequeMultiResult is the outcome of a single cashout submitted by CashChequeMulti
+type CashChequeMultiResult struct {
+	Vault  common.Address
+	TxHash common.Hash // zero if the cashout was not sent
+	Err    error
+}
+
+// batchCandidate holds what the orderings need to know about a vault
+type batchCandidate struct {
+	vault    common.Address
+	amount   *big.Int // uncashed amount, zero if unknown
+	received int64    // receive time of the oldest cheque record, 0 if unknown
+	gas      uint64   // estimated gas of the cashout, 0 if unknown
+}
+
+// CashChequeMulti cashes the last cheques of the given vaults to recipient, submitting them in the given ordering.
+// Once the in-flight limit is reached the remaining vaults are not attempted, so the ordering decides which
+// cashouts make it. The results are returned in submission order.
+func (s *cashoutService) CashChequeMulti(ctx context.Context, vaults []common.Address, recipient common.Address, ordering BatchOrdering) ([]CashChequeMultiResult, error) {
+	candidates := make([]*batchCandidate, 0, len(vaults))
+	for _, vault := range vaults {
+		candidate, err := s.batchCandidate(ctx, vault, recipient, ordering)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, candidate)
+	}
+	orderBatchCandidates(candidates, ordering)
+
+	batch := &cashoutBatch{
+		Vaults:    make([]common.Address, 0, len(candidates)),
+		Recipient: recipient,
+	}
+	for _, candidate := range candidates {
+		batch.Vaults = append(batch.Vaults, candidate.vault)
+	}
+	key, err := s.newCashoutBatch(batch)
+	if err != nil {
+		return nil, err
+	}
+	defer s.clearCashoutBatch(key)
+
+	results := make([]CashChequeMultiResult, 0, len(candidates))
+	var inFlightErr error
+	for _, candidate := range candidates {
+		result := CashChequeMultiResult{Vault: candidate.vault}
+		if inFlightErr != nil {
+			result.Err = inFlightErr
+			results = append(results, result)
+			continue
+		}
+		result.TxHash, result.Err = s.CashCheque(ctx, candidate.vault, recipient)
+		if errors.Is(result.Err, ErrTooManyInFlight) {
+			inFlightErr = result.Err
+		}
+		results = append(results, result)
+
+		batch.Processed++
+		err = s.store.Put(key, batch)
+		if err != nil {
+			log.Errorf("cashout: could not record progress of batch %s: %v", key, err)
+		}
+	}
+	return results, nil
+}
+
+const cashoutBatchPrefix = "swap_cashout_batch_"
+
+// cashoutBatch is the stored progress of a CashChequeMulti call. It is written before the first cashout and removed
+// once all vaults were processed, so a batch interrupted by a crash can be resumed on the next start.
+type cashoutBatch struct {
+	Vaults    []common.Address // vaults in submission order
+	Recipient common.Address
+	Processed int // number of vaults processed, the next one might have been sent right before a crash
+	Created   int64
+}
+
+// cashoutBatchKey computes the store key for a batch record
+func cashoutBatchKey(created int64) string {
+	return fmt.Sprintf("%s%d", cashoutBatchPrefix, created)
+}
+
+// newCashoutBatch persists the batch before its first cashout is sent
+func (s *cashoutService) newCashoutBatch(batch *cashoutBatch) (string, error) {
+	// the batch records share the in-flight lock with the write-ahead log records to keep their keys apart
+	s.inFlightLock.Lock()
+	defer s.inFlightLock.Unlock()
+
+	batch.Created = s.now().UnixNano()
+	key := cashoutBatchKey(batch.Created)
+	// the clock may not have advanced since the last batch, e.g. a test clock
+	for {
+		err := s.store.Get(key, &cashoutBatch{})
+		if errors.Is(err, storage.ErrNotFound) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		batch.Created++
+		key = cashoutBatchKey(batch.Created)
+	}
+	err := s.store.Put(key, batch)
+	if err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// clearCashoutBatch removes a batch record once all its vaults have been processed
+func (s *cashoutService) clearCashoutBatch(key string) {
+	err := s.store.Delete(key)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		log.Errorf("cashout: could not clear batch record %s: %v", key, err)
+	}
+}
+
+// resumeCashoutBatches submits the cashouts of interrupted batches which were not sent before the shutdown.
+// A vault is skipped if it has a pending cashout, either in the write-ahead log or on-chain, or if nothing is left
+// to cash, so cashouts which already landed are not submitted again.
+func (s *cashoutService) resumeCashoutBatches(ctx context.Context) error {
+	batches := make(map[string]cashoutBatch)
+	err := s.store.Iterate(cashoutBatchPrefix, func(key, val []byte) (stop bool, err error) {
+		var batch cashoutBatch
+		err = s.store.Get(string(key), &batch)
+		if err != nil {
+			return false, err
+		}
+		batches[string(key)] = batch
+		return false, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for key, batch := range batches {
+		for i := batch.Processed; i < len(batch.Vaults); i++ {
+			vault := batch.Vaults[i]
+			submitted, err := s.batchEntrySubmitted(ctx, vault)
+			if err != nil {
+				log.Errorf("cashout: could not determine state of vault %x of batch %s, not resuming it: %v", vault, key, err)
+				continue
+			}
+			if submitted {
+				continue
+			}
+			txHash, err := s.CashCheque(ctx, vault, batch.Recipient)
+			if err != nil {
+				log.Errorf("cashout: could not resume cashout of vault %x of batch %s: %v", vault, key, err)
+				continue
+			}
+			log.Infof("cashout: resumed cashout of vault %x of batch %s in transaction %x", vault, key, txHash)
+		}
+		s.clearCashoutBatch(key)
+	}
+	return nil
+}
+
+// batchEntrySubmitted reports whether the cashout of the vault was already sent or has nothing left to cash
+func (s *cashoutService) batchEntrySubmitted(ctx context.Context, vault common.Address) (bool, error) {
+	pending := false
+	err := s.store.Iterate(fmt.Sprintf("%s%x_", cashoutIntentPrefix, vault), func(key, val []byte) (stop bool, err error) {
+		pending = true
+		return true, nil
+	})
+	if err != nil {
+		return false, err
+	}
+	if pending {
+		return true, nil
+	}
+
+	status, err := s.CashoutStatus(ctx, vault)
+	if err != nil {
+		return false, err
+	}
+	if status.State == CashoutStatePending {
+		return true, nil
+	}
+	return status.UncashedAmount == nil || status.UncashedAmount.Sign() <= 0, nil
+}
+
+// batchCandidate collects the data the ordering needs for the vault. Lookup failures leave the fields zero so the
+// vault is ordered last, the cashout itself reports the error then.
+func (s *cashoutService) batchCandidate(ctx context.Context, vault, recipient common.Address, ordering BatchOrdering) (*batchCandidate, error) {
+	candidate := &batchCandidate{
+		vault:  vault,
+		amount: big.NewInt(0),
+	}
+	switch ordering {
+	case BatchOrderingGiven:
+	case BatchOrderingLargestFirst, BatchOrderingMostEfficientFirst:
+		status, err := s.CashoutStatus(ctx, vault)
+		if err == nil {
+			candidate.amount = status.UncashedAmount
+		}
+		if ordering == BatchOrderingMostEfficientFirst {
+			gas, err := s.EstimateCashout(ctx, vault, recipient)
+			if err == nil {
+				candidate.gas = gas
+			}
+		}
+	case BatchOrderingOldestFirst:
+		records, err := s.chequeStore.ReceivedChequeRecordsByPeer(vault)
+		if err == nil {
+			for _, record := range records {
+				if candidate.received == 0 || record.ReceiveTime < candidate.received {
+					candidate.received = record.ReceiveTime
+				}
+			}
+		}
+	default:
+		return nil, fmt.Errorf("%w: %d", ErrInvalidBatchOrdering, ordering)
+	}
+	return candidate, nil
+}
+
+// orderBatchCandidates sorts the candidates for submission. Ties keep the given order.
+func orderBatchCandidates(candidates []*batchCandidate, ordering BatchOrdering) {
+	switch ordering {
+	case BatchOrderingLargestFirst:
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return candidates[i].amount.Cmp(candidates[j].amount) > 0
+		})
+	case BatchOrderingOldestFirst:
+		sort.SliceStable(candidates, func(i, j int) bool {
+			a, b := candidates[i].received, candidates[j].received
+			if a == 0 || b == 0 {
+				return b == 0 && a != 0
+			}
+			return a < b
+		})
+	case BatchOrderingMostEfficientFirst:
+		sort.SliceStable(candidates, func(i, j int) bool {
+			a, b := candidates[i], candidates[j]
+			if a.gas == 0 || b.gas == 0 {
+				return b.gas == 0 && a.gas != 0
+			}
+			// compare a.amount / a.gas > b.amount / b.gas without dividing
+			left := new(big.Int).Mul(a.amount, new(big.Int).SetUint64(b.gas))
+			right := new(big.Int).Mul(b.amount, new(big.Int).SetUint64(a.gas))
+			return left.Cmp(right) > 0
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// nameCacheTTL is how long a resolved recipient name is reused before it is resolved again
+const nameCacheTTL = 5 * time.Minute
+
+var (
+	// ErrNoNameResolver is the error if a recipient is given by name but no name resolver is configured
+	ErrNoNameResolver = errors.New("no name resolver")
+	// ErrNameResolution is the error if a recipient name could not be resolved to an address
+	ErrNameResolution = errors.New("recipient name resolution failed")
+)
+
+// NameResolver resolves names such as ENS names to addresses
+type NameResolver interface {
+	ResolveName(ctx context.Context, name string) (common.Address, error)
+}
+
+// resolvedName is a cached name resolution
+type resolvedName struct {
+	address common.Address
+	expires time.Time
+}
+
+// nameResolution resolves recipient names and caches the resolutions for nameCacheTTL
+type nameResolution struct {
+	resolver NameResolver
+
+	mu    sync.Mutex
+	cache map[string]resolvedName
+}
+
+// WithNameResolver sets the resolver used by CashChequeTo to resolve recipient names
+func WithNameResolver(resolver NameResolver) CashoutServiceOption {
+	return cashoutServiceOptionFunc(func(s *cashoutService) {
+		s.nameResolution = &nameResolution{
+			resolver: resolver,
+			cache:    make(map[string]resolvedName),
+		}
+	})
+}
+
+// resolveRecipientName resolves the name with the name resolver unless a recent resolution is cached
+func (s *cashoutService) resolveRecipientName(ctx context.Context, name string) (common.Address, error) {
+	r := s.nameResolution
+	if r == nil {
+		return common.Address{}, ErrNoNameResolver
+	}
+	key := strings.ToLower(name)
+
+	r.mu.Lock()
+	cached, ok := r.cache[key]
+	r.mu.Unlock()
+	if ok && s.now().Before(cached.expires) {
+		return cached.address, nil
+	}
+
+	address, err := r.resolver.ResolveName(ctx, name)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("%w: %s: %v", ErrNameResolution, name, err)
+	}
+	if address == (common.Address{}) {
+		return common.Address{}, fmt.Errorf("%w: %s resolves to the zero address", ErrNameResolution, name)
+	}
+
+	r.mu.Lock()
+	r.cache[key] = resolvedName{
+		address: address,
+		expires: s.now().Add(nameCacheTTL),
+	}
+	r.mu.Unlock()
+	return address, nil
+}
+
+// CashChequeTo cashes the last cheque of the vault to the recipient given by name, e.g. an ENS name, which is
+// resolved with the configured name resolver. A hex address is used as is.
+func (s *cashoutService) CashChequeTo(ctx context.Context, vault common.Address, recipientName string) (common.Hash, error) {
+	recipientName = strings.TrimSpace(recipientName)
+	if common.IsHexAddress(recipientName) {
+		return s.CashCheque(ctx, vault, common.HexToAddress(recipientName))
+	}
+
+	recipient, err := s.resolveRecipientName(ctx, recipientName)
+	if err != nil {
+		return common.Hash{}, newCashoutError(CashoutPhaseLookup, err)
+	}
+	log.Infof("cashout of vault %x: resolved recipient %s to %x", vault, recipientName, recipient)
+	return s.CashCheque(ctx, vault, recipient)
+}
@@ -0,0 +1,156 @@
+package vault
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bittorrent/go-btfs/statestore"
+	"github.com/bittorrent/go-btfs/transaction/storage"
+)
+
+// cashoutKeyPrefixes are the prefixes of the store keys written by the cashout service. Keys of the cheque store,
+// e.g. the received totals, are shared by all services and stay outside of the chain namespace.
+var cashoutKeyPrefixes = []string{
+	cashoutActionPrefix, // also the prefix of the results, the write-ahead log and the other cashout records
+	autoCashoutExclusionPrefix,
+	statestore.TotalReceivedCashedKey, // also the prefix of the cashed count and the per token totals
+	statestore.TotalDailyReceivedCashedKey,
+	statestore.TotalDailyCashoutGasKey,
+	statestore.TotalCallerPayoutKey,
+	statestore.LastCashoutTimeKey,
+}
+
+// isCashoutKey reports whether the key or iteration prefix belongs to the cashout service
+func isCashoutKey(key string) bool {
+	for _, prefix := range cashoutKeyPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	// chainNamespaceMigratedKey marks that the cashout data without a chain namespace has been migrated. It holds
+	// the chain id the data was moved to.
+	chainNamespaceMigratedKey = "cashout_chain_namespace_migrated"
+	// legacyCashoutArchivePrefix is the prefix the records without a chain namespace are archived under if the
+	// namespace already had a record of the same key
+	legacyCashoutArchivePrefix = "cashout_legacy_archive_"
+)
+
+// chainNamespace is the prefix of the cashout keys of the chain
+func chainNamespace(chainID int64) string {
+	return fmt.Sprintf("chain_%d_", chainID)
+}
+
+// chainNamespacedStore prefixes the cashout keys with the chain namespace and passes all other keys through
+type chainNamespacedStore struct {
+	storage.StateStorer
+	namespace string
+}
+
+// ChainNamespacedStore returns a store which keeps the cashout data of the chain apart from the cashout data of
+// other chains kept in the same store. Data written without a namespace has to be moved with
+// MigrateToChainNamespace first.
+func ChainNamespacedStore(store storage.StateStorer, chainID int64) storage.StateStorer {
+	return &chainNamespacedStore{
+		StateStorer: store,
+		namespace:   chainNamespace(chainID),
+	}
+}
+
+// storeKey returns the key under which the value of key is kept in the underlying store
+func (s *chainNamespacedStore) storeKey(key string) string {
+	if isCashoutKey(key) {
+		return s.namespace + key
+	}
+	return key
+}
+
+func (s *chainNamespacedStore) Get(key string, i interface{}) error {
+	return s.StateStorer.Get(s.storeKey(key), i)
+}
+
+func (s *chainNamespacedStore) Put(key string, i interface{}) error {
+	return s.StateStorer.Put(s.storeKey(key), i)
+}
+
+func (s *chainNamespacedStore) Delete(key string) error {
+	return s.StateStorer.Delete(s.storeKey(key))
+}
+
+// Iterate iterates the keys of the chain if the prefix belongs to the cashout service. The keys are passed to
+// iterFunc without the namespace so they can be used with the other methods.
+func (s *chainNamespacedStore) Iterate(prefix string, iterFunc storage.StateIterFunc) error {
+	if !isCashoutKey(prefix) {
+		return s.StateStorer.Iterate(prefix, iterFunc)
+	}
+	return s.StateStorer.Iterate(s.namespace+prefix, func(key, value []byte) (bool, error) {
+		return iterFunc(key[len(s.namespace):], value)
+	})
+}
+
+// storeKey returns the key under which the value of key is kept in the store
+func storeKey(store storage.StateStorer, key string) string {
+	if namespaced, ok := store.(*chainNamespacedStore); ok {
+		return namespaced.storeKey(key)
+	}
+	return key
+}
+
+// MigrateToChainNamespace moves the cashout data written without a chain namespace into the namespace of the chain
+// and returns the number of moved records. All of it is assumed to belong to that chain. Records which already
+// exist in the namespace are kept and the records without namespace are archived instead. The migration runs only
+// once, later calls return 0. It must run before the services using the namespaced store are created.
+func MigrateToChainNamespace(store storage.StateStorer, chainID int64) (int, error) {
+	var migratedChainID int64
+	err := store.Get(chainNamespaceMigratedKey, &migratedChainID)
+	if err == nil {
+		return 0, nil
+	}
+	if err != storage.ErrNotFound {
+		return 0, err
+	}
+
+	namespace := chainNamespace(chainID)
+	legacy := make(map[string][]byte)
+	for _, prefix := range cashoutKeyPrefixes {
+		err := store.Iterate(prefix, func(key, val []byte) (stop bool, err error) {
+			legacy[string(key)] = append([]byte(nil), val...)
+			return false, nil
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	batch := newStoreBatch(store)
+	moved := 0
+	for key, data := range legacy {
+		target := namespace + key
+		err := store.Get(target, new(rawValue))
+		switch {
+		case err == nil:
+			log.Warnf("cashout: %s already exists on chain %d, archiving the record without namespace", key, chainID)
+			target = legacyCashoutArchivePrefix + key
+		case err == storage.ErrNotFound:
+			moved++
+		default:
+			return 0, err
+		}
+		if err := batch.Put(target, rawValue(data)); err != nil {
+			return 0, err
+		}
+		if err := batch.Delete(key); err != nil {
+			return 0, err
+		}
+	}
+	if err := batch.Put(chainNamespaceMigratedKey, chainID); err != nil {
+		return 0, err
+	}
+	if err := batch.Commit(); err != nil {
+		return 0, err
+	}
+	return moved, nil
+}
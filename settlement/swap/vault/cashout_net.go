@@ -0,0 +1,57 @@
+package vault
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/bittorrent/go-btfs/transaction/storage"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// NetPosition returns the uncashed amount received from vault minus what the node still owes the vault issuer
+// through cheques issued from its own vault. A positive value means the peer owes the node.
+func (s *cashoutService) NetPosition(ctx context.Context, vault common.Address) (*big.Int, error) {
+	status, err := s.CashoutStatus(ctx, vault)
+	if err != nil {
+		return nil, err
+	}
+
+	sent, err := s.sentOutstanding(ctx, vault)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).Sub(status.UncashedAmount, sent), nil
+}
+
+// sentOutstanding returns the amount of the cheques issued by the node to the issuer of vault which has not been
+// cashed yet. The sending side shares the state store, so its own vault and last issued cheque are read from there.
+func (s *cashoutService) sentOutstanding(ctx context.Context, vault common.Address) (*big.Int, error) {
+	var ownVault common.Address
+	err := s.store.Get(VaultKey, &ownVault)
+	if err != nil {
+		if err == storage.ErrNotFound {
+			return big.NewInt(0), nil
+		}
+		return nil, err
+	}
+
+	peer, err := newVaultContract(vault, s.transactionService).Issuer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var lastSent *SignedCheque
+	err = s.store.Get(lastIssuedChequeKey(peer), &lastSent)
+	if err != nil {
+		if err == storage.ErrNotFound {
+			return big.NewInt(0), nil
+		}
+		return nil, err
+	}
+
+	paidOut, err := s.paidOut(ctx, ownVault, peer)
+	if err != nil {
+		return nil, err
+	}
+	return uncashedAmount(ownVault, lastSent.CumulativePayout, paidOut), nil
+}
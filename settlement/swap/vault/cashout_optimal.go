@@ -0,0 +1,45 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	// ErrUnprofitable is the error if the gas cost of a cashout is not below the uncashed amount
+	ErrUnprofitable = errors.New("cashout gas cost exceeds uncashed amount")
+)
+
+// CashChequeOptimal cashes the last cheque of the vault if the uncashed amount exceeds the estimated gas cost at
+// the current gas price. A cheque can only be cashed for its full cumulative payout, so maximizing the net value
+// means cashing everything or nothing, in which case ErrUnprofitable is returned.
+func (s *cashoutService) CashChequeOptimal(ctx context.Context, vault, recipient common.Address) (common.Hash, error) {
+	recipient, err := s.resolveRecipient(vault, recipient)
+	if err != nil {
+		return common.Hash{}, newCashoutError(CashoutPhaseLookup, err)
+	}
+
+	status, err := s.CashoutStatus(ctx, vault)
+	if err != nil {
+		return common.Hash{}, newCashoutError(CashoutPhaseLookup, err)
+	}
+
+	gas, err := s.EstimateCashout(ctx, vault, recipient)
+	if err != nil {
+		return common.Hash{}, newCashoutError(CashoutPhaseValidate, err)
+	}
+	gasPrice, err := s.gasOracle.SuggestGasPrice(ctx)
+	if err != nil {
+		return common.Hash{}, newCashoutError(CashoutPhaseValidate, err)
+	}
+	cost := new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(gas))
+
+	if status.UncashedAmount.Cmp(cost) <= 0 {
+		return common.Hash{}, newCashoutError(CashoutPhaseValidate, fmt.Errorf("%w: uncashed %d, gas cost %d", ErrUnprofitable, status.UncashedAmount, cost))
+	}
+	return s.CashCheque(ctx, vault, recipient)
+}
@@ -0,0 +1,72 @@
+package vault
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// RecipientPolicy decides the recipient which actually receives the funds of a cashout.
+// It is given the vault and the recipient requested by the caller.
+type RecipientPolicy func(vault, requested common.Address) (common.Address, error)
+
+// identityRecipientPolicy is the default RecipientPolicy which never overrides the requested recipient
+func identityRecipientPolicy(vault, requested common.Address) (common.Address, error) {
+	return requested, nil
+}
+
+// CashoutServiceOption configures optional behaviour of the CashoutService
+type CashoutServiceOption interface {
+	apply(*cashoutService)
+}
+
+type cashoutServiceOptionFunc func(*cashoutService)
+
+func (f cashoutServiceOptionFunc) apply(s *cashoutService) { f(s) }
+
+// WithRecipientPolicy sets the policy consulted by CashCheque to possibly override the recipient
+func WithRecipientPolicy(policy RecipientPolicy) CashoutServiceOption {
+	return cashoutServiceOptionFunc(func(s *cashoutService) {
+		s.recipientPolicy = policy
+	})
+}
+
+// WithBeneficiary sets the account which sends the cashout transactions. Cheques for other beneficiaries are
+// rejected before sending as they would revert.
+func WithBeneficiary(beneficiary common.Address) CashoutServiceOption {
+	return cashoutServiceOptionFunc(func(s *cashoutService) {
+		s.beneficiary = beneficiary
+	})
+}
+
+// WithAllowArbitraryBeneficiary relaxes the beneficiary check of WithBeneficiary so cheques for other
+// beneficiaries are cashed anyway. This is meant for testing on testnets only and must never be enabled on
+// mainnet, every cashout it lets through is logged with a warning.
+func WithAllowArbitraryBeneficiary(allow bool) CashoutServiceOption {
+	return cashoutServiceOptionFunc(func(s *cashoutService) {
+		s.allowArbitraryBeneficiary = allow
+	})
+}
+
+// WithMaxInFlightCashouts limits the number of cashouts which may be pending at the same time
+func WithMaxInFlightCashouts(max int) CashoutServiceOption {
+	return cashoutServiceOptionFunc(func(s *cashoutService) {
+		s.maxInFlight = max
+	})
+}
+
+// WithChequeFreshnessCheck toggles re-reading the last received cheque right before the cashout is sent.
+// If a newer cheque with a higher cumulative payout arrived in the meantime that cheque is cashed instead.
+func WithChequeFreshnessCheck(enabled bool) CashoutServiceOption {
+	return cashoutServiceOptionFunc(func(s *cashoutService) {
+		s.chequeFreshnessCheck = enabled
+	})
+}
+
+// WithPendingStaleAfter sets the age after which a pending cashout whose transaction cannot be found is considered
+// dropped. CashoutStatus then forgets it so the uncashed amount is derived from the chain again.
+func WithPendingStaleAfter(d time.Duration) CashoutServiceOption {
+	return cashoutServiceOptionFunc(func(s *cashoutService) {
+		s.pendingStaleAfter = d
+	})
+}
@@ -0,0 +1,38 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ErrInvalidPaidOut is the error if the PaidOutProvider returns no or a negative amount
+var ErrInvalidPaidOut = errors.New("invalid paidOut from provider")
+
+// PaidOutProvider reports the amount a vault has paid out to a beneficiary, e.g. from an indexer of the operator.
+// It must return the same value as the paidOut method of the vault contract.
+type PaidOutProvider interface {
+	PaidOut(ctx context.Context, vault, beneficiary common.Address) (*big.Int, error)
+}
+
+// WithPaidOutProvider makes CashoutStatus and the cashout guards query the provider instead of the vault contract
+func WithPaidOutProvider(provider PaidOutProvider) CashoutServiceOption {
+	return cashoutServiceOptionFunc(func(s *cashoutService) {
+		s.paidOutProvider = provider
+	})
+}
+
+func (s *cashoutService) providedPaidOut(ctx context.Context, vault, beneficiary common.Address) (*big.Int, error) {
+	paidOut, err := s.paidOutProvider.PaidOut(ctx, vault, beneficiary)
+	if err != nil {
+		return nil, err
+	}
+	if paidOut == nil || paidOut.Sign() < 0 {
+		return nil, fmt.Errorf("%w: %v for vault %x", ErrInvalidPaidOut, paidOut, vault)
+	}
+	// the caller must not be able to modify the value held by the provider
+	return new(big.Int).Set(paidOut), nil
+}
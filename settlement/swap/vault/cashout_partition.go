@@ -0,0 +1,93 @@
+package vault
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bittorrent/go-btfs/statestore"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// CashoutResultsForDay returns the cashout results of the given day
+func (s *cashoutService) CashoutResultsForDay(day time.Time) ([]CashOutResult, error) {
+	result, err := s.repository.ResultsForDay(day)
+	if err != nil {
+		return nil, err
+	}
+	sortCashoutResults(result)
+	return result, nil
+}
+
+// resultKeyVault returns the hex encoded vault of a cashout result key. It understands both the day partitioned
+// <prefix><day>_<vault>_<time> keys and the <prefix><vault>_<time> keys written before results were partitioned.
+func resultKeyVault(key string) (hexVault string, partitioned bool) {
+	parts := strings.Split(strings.TrimPrefix(key, statestore.CashoutResultPrefixKey()), "_")
+	switch len(parts) {
+	case 2:
+		return parts[0], false
+	case 3:
+		return parts[1], true
+	default:
+		return "", false
+	}
+}
+
+// misplacedResultKey reports whether a partitioned result key is in another partition than the day of its cash
+// time, e.g. as results used to be partitioned by the UTC date
+func misplacedResultKey(key, hexVault string) bool {
+	parts := strings.Split(key, "_")
+	cashTime, err := strconv.ParseInt(parts[len(parts)-1], 10, 64)
+	if err != nil {
+		return false
+	}
+	return key != statestore.CashoutResultKeyByTime(common.HexToAddress(hexVault), cashTime)
+}
+
+// migrateCashoutResults moves cashout results stored before results were partitioned, or stored in the partition
+// of another day, into the partition of their day
+func (s *cashoutService) migrateCashoutResults() error {
+	var keys []string
+	err := s.store.Iterate(statestore.CashoutResultPrefixKey(), func(key, val []byte) (stop bool, err error) {
+		hexVault, partitioned := resultKeyVault(string(key))
+		if hexVault != "" && (!partitioned || misplacedResultKey(string(key), hexVault)) {
+			keys = append(keys, string(key))
+		}
+		return false, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		var result CashOutResult
+		err = s.store.Get(key, &result)
+		if err != nil {
+			return err
+		}
+
+		cashTime := result.CashTime
+		if cashTime == 0 {
+			// fall back to the time the result was stored at
+			parts := strings.Split(key, "_")
+			cashTime, err = strconv.ParseInt(parts[len(parts)-1], 10, 64)
+			if err != nil {
+				return err
+			}
+		}
+
+		result.CashTime = cashTime
+		err = s.repository.PutResult(&result)
+		if err != nil {
+			return err
+		}
+		err = s.store.Delete(key)
+		if err != nil {
+			return err
+		}
+	}
+	if len(keys) > 0 {
+		log.Infof("cashout: partitioned %d cashout results by day", len(keys))
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bittorrent/go-btfs/transaction"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// PreSendHook is invoked with the fully built request right before a cashout transaction is sent. Returning an
+// error aborts the send.
+type PreSendHook func(ctx context.Context, request *transaction.TxRequest) error
+
+// WithPreSendHook sets a hook invoked before every transaction sent by the cashout service, e.g. for last-second
+// policy checks, rate limiting or logging. Cancellations are built by the transaction service and do not pass it.
+func WithPreSendHook(hook PreSendHook) CashoutServiceOption {
+	return cashoutServiceOptionFunc(func(s *cashoutService) {
+		s.preSendHook = hook
+	})
+}
+
+// sendTransaction passes the request to the pre-send hook and sends it unless the hook rejects it
+func (s *cashoutService) sendTransaction(ctx context.Context, request *transaction.TxRequest) (common.Hash, error) {
+	if s.preSendHook != nil {
+		err := s.preSendHook(ctx, request)
+		if err != nil {
+			return common.Hash{}, fmt.Errorf("pre-send hook: %w", err)
+		}
+	}
+	return s.transactionService.Send(ctx, request)
+}
@@ -0,0 +1,52 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	// ErrTokenPriceTooLow is the error if the price of the vault token is below the requested minimum
+	ErrTokenPriceTooLow = errors.New("token price below minimum")
+	// ErrNoPriceFeed is the error if a minimum token price is requested but no price feed is configured
+	ErrNoPriceFeed = errors.New("no price feed configured")
+)
+
+// PriceFeed reports the current price of a token
+type PriceFeed interface {
+	TokenPriceUSD(ctx context.Context, token common.Address) (float64, error)
+}
+
+// WithPriceFeed sets the price feed consulted for cashouts with a minimum token price
+func WithPriceFeed(feed PriceFeed) CashoutServiceOption {
+	return cashoutServiceOptionFunc(func(s *cashoutService) {
+		s.priceFeed = feed
+	})
+}
+
+// checkTokenPrice returns ErrTokenPriceTooLow if the price of the token paid out by the vault is below min.
+// A non positive min disables the check.
+func (s *cashoutService) checkTokenPrice(ctx context.Context, vault common.Address, min float64) error {
+	if min <= 0 {
+		return nil
+	}
+	if s.priceFeed == nil {
+		return ErrNoPriceFeed
+	}
+
+	token, err := s.vaultToken(ctx, vault)
+	if err != nil {
+		return err
+	}
+	price, err := s.priceFeed.TokenPriceUSD(ctx, token)
+	if err != nil {
+		return err
+	}
+	if price < min {
+		return fmt.Errorf("%w: %x is at %g USD, minimum %g USD", ErrTokenPriceTooLow, token, price, min)
+	}
+	return nil
+}
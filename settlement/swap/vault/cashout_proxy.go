@@ -0,0 +1,45 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// eip1967ImplementationSlot is the storage slot holding the implementation of an EIP-1967 proxy,
+// bytes32(uint256(keccak256("eip1967.proxy.implementation")) - 1)
+var eip1967ImplementationSlot = common.HexToHash("0x360894a13ba1a3210667c828492db98dca3e2076cc3735a920a3ca505d382bbc")
+
+// ErrNoImplementation is the error if the implementation of a vault proxy could not be resolved
+var ErrNoImplementation = errors.New("vault implementation not found")
+
+// storageReader is implemented by backends which can read contract storage
+type storageReader interface {
+	StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error)
+}
+
+// ResolveImplementation returns the implementation behind the vault proxy. The EIP-1967 implementation slot is read
+// if the backend supports storage access, otherwise or if the slot is empty the implementation() view of the vault
+// is used. Cashouts and event parsing always target the proxy address, this only tells which code it delegates to.
+func (s *cashoutService) ResolveImplementation(ctx context.Context, proxy common.Address) (common.Address, error) {
+	if reader, ok := s.backend.(storageReader); ok {
+		value, err := reader.StorageAt(ctx, proxy, eip1967ImplementationSlot, nil)
+		if err != nil {
+			return common.Address{}, err
+		}
+		if implementation := common.BytesToAddress(value); implementation != (common.Address{}) {
+			return implementation, nil
+		}
+	}
+
+	implementation, err := GetVaultImpl(ctx, proxy, s.transactionService)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if implementation == (common.Address{}) {
+		return common.Address{}, ErrNoImplementation
+	}
+	return implementation, nil
+}
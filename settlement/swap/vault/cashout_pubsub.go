@@ -0,0 +1,101 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"time"
+
+	"github.com/bittorrent/go-btfs/transaction/crypto"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// DefaultCashoutTopic is the pubsub topic cashout results are published on if no other topic is configured
+const DefaultCashoutTopic = "/btfs/cashout/1.0.0"
+
+// publishTimeout bounds the publication of a single cashout message
+const publishTimeout = 5 * time.Second
+
+// EventPublisher publishes messages on a pubsub topic. The node's PubSubAPI satisfies it.
+type EventPublisher interface {
+	Publish(ctx context.Context, topic string, data []byte) error
+}
+
+// CashoutMessage is published on the pubsub topic for every stored cashout result
+type CashoutMessage struct {
+	Vault    common.Address
+	TxHash   common.Hash
+	Amount   *big.Int
+	Status   string
+	CashTime int64
+}
+
+// SignedCashoutMessage is the payload of a cashout pubsub message. The signature is an EIP-191 signature over the
+// JSON encoding of Message, empty if signing is disabled.
+type SignedCashoutMessage struct {
+	Message   json.RawMessage
+	Signature []byte
+}
+
+// resultPublisher publishes cashout results on pubsub
+type resultPublisher struct {
+	publisher EventPublisher
+	topic     string
+	signer    crypto.Signer // nil publishes unsigned messages
+}
+
+// WithResultPublishing publishes every stored cashout result on the pubsub topic, DefaultCashoutTopic if topic is
+// empty. If signer is not nil the messages are signed so subscribers can verify the sending node.
+func WithResultPublishing(publisher EventPublisher, topic string, signer crypto.Signer) CashoutServiceOption {
+	return cashoutServiceOptionFunc(func(s *cashoutService) {
+		if topic == "" {
+			topic = DefaultCashoutTopic
+		}
+		s.resultPublisher = &resultPublisher{
+			publisher: publisher,
+			topic:     topic,
+			signer:    signer,
+		}
+	})
+}
+
+// publishResultMessage publishes the cashout result on pubsub if publishing is enabled. Failures are only logged
+// as the result is already stored.
+func (s *cashoutService) publishResultMessage(ctx context.Context, result CashOutResult) {
+	p := s.resultPublisher
+	if p == nil {
+		return
+	}
+
+	message, err := json.Marshal(CashoutMessage{
+		Vault:    result.Vault,
+		TxHash:   result.TxHash,
+		Amount:   result.Amount,
+		Status:   result.Status,
+		CashTime: result.CashTime,
+	})
+	if err != nil {
+		log.Errorf("cashout: could not encode pubsub message for %x: %v", result.TxHash, err)
+		return
+	}
+	signed := SignedCashoutMessage{Message: message}
+	if p.signer != nil {
+		signed.Signature, err = p.signer.Sign(message)
+		if err != nil {
+			log.Errorf("cashout: could not sign pubsub message for %x: %v", result.TxHash, err)
+			return
+		}
+	}
+	data, err := json.Marshal(signed)
+	if err != nil {
+		log.Errorf("cashout: could not encode pubsub message for %x: %v", result.TxHash, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, publishTimeout)
+	defer cancel()
+	err = p.publisher.Publish(ctx, p.topic, data)
+	if err != nil {
+		log.Errorf("cashout: could not publish result of %x on %s: %v", result.TxHash, p.topic, err)
+	}
+}
@@ -0,0 +1,115 @@
+package vault
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/bittorrent/go-btfs/transaction/storage"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+const rawReceiptPrefix = "swap_cashout_raw_receipt_"
+
+func rawReceiptKey(txHash common.Hash) string {
+	return fmt.Sprintf("%s%x", rawReceiptPrefix, txHash)
+}
+
+// WithStoreRawReceipts toggles persisting the full receipt of every cashout for later inspection.
+// Receipts are large, so this is off by default and stored receipts should be pruned with PruneRawReceipts.
+func WithStoreRawReceipts(enabled bool) CashoutServiceOption {
+	return cashoutServiceOptionFunc(func(s *cashoutService) {
+		s.storeRawReceipts = enabled
+	})
+}
+
+// storedReceipt is the RLP encoded form of a raw receipt. The consensus encoding of a receipt lacks the derived
+// fields, so the ones needed for inspection are stored next to it.
+type storedReceipt struct {
+	Receipt          *types.Receipt
+	TxHash           common.Hash
+	GasUsed          uint64
+	BlockHash        common.Hash
+	BlockNumber      *big.Int
+	TransactionIndex uint
+	Stored           uint64 // unix time the receipt was stored at
+}
+
+func (r *storedReceipt) MarshalBinary() ([]byte, error) {
+	return rlp.EncodeToBytes(r)
+}
+
+func (r *storedReceipt) UnmarshalBinary(data []byte) error {
+	return rlp.DecodeBytes(data, r)
+}
+
+func newStoredReceipt(receipt *types.Receipt, stored time.Time) *storedReceipt {
+	blockNumber := receipt.BlockNumber
+	if blockNumber == nil {
+		blockNumber = big.NewInt(0)
+	}
+	return &storedReceipt{
+		Receipt:          receipt,
+		TxHash:           receipt.TxHash,
+		GasUsed:          receipt.GasUsed,
+		BlockHash:        receipt.BlockHash,
+		BlockNumber:      blockNumber,
+		TransactionIndex: receipt.TransactionIndex,
+		Stored:           uint64(stored.Unix()),
+	}
+}
+
+// RawReceipt returns the stored receipt of a cashout transaction
+func (s *cashoutService) RawReceipt(txHash common.Hash) (*types.Receipt, error) {
+	var stored storedReceipt
+	err := s.store.Get(rawReceiptKey(txHash), &stored)
+	if err != nil {
+		return nil, err
+	}
+
+	receipt := stored.Receipt
+	receipt.TxHash = stored.TxHash
+	receipt.GasUsed = stored.GasUsed
+	receipt.BlockHash = stored.BlockHash
+	receipt.BlockNumber = stored.BlockNumber
+	receipt.TransactionIndex = stored.TransactionIndex
+	for i, l := range receipt.Logs {
+		l.TxHash = stored.TxHash
+		l.BlockHash = stored.BlockHash
+		l.BlockNumber = stored.BlockNumber.Uint64()
+		l.TxIndex = stored.TransactionIndex
+		l.Index = uint(i)
+	}
+	return receipt, nil
+}
+
+// PruneRawReceipts deletes the raw receipts stored longer than maxAge ago and returns how many were deleted
+func (s *cashoutService) PruneRawReceipts(maxAge time.Duration) (int, error) {
+	cutoff := s.now().Add(-maxAge).Unix()
+
+	var keys []string
+	err := s.store.Iterate(rawReceiptPrefix, func(key, val []byte) (stop bool, err error) {
+		var stored storedReceipt
+		err = stored.UnmarshalBinary(val)
+		if err != nil {
+			return false, err
+		}
+		if int64(stored.Stored) < cutoff {
+			keys = append(keys, string(key))
+		}
+		return false, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, key := range keys {
+		err = s.store.Delete(key)
+		if err != nil && err != storage.ErrNotFound {
+			return 0, err
+		}
+	}
+	return len(keys), nil
+}
@@ -0,0 +1,80 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/bittorrent/go-btfs/transaction"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// RebuildHistoryFromChain reconstructs the cashout results of vault from the ChequeCashed events it emitted for
+// the node's beneficiary since fromBlock. It is meant for recovering from a lost state store, results which are
+// still stored are left untouched. The cashed totals are not updated, the rebuilt results are marked so the totals
+// verification leaves them out.
+func (s *cashoutService) RebuildHistoryFromChain(ctx context.Context, vault common.Address, fromBlock uint64) error {
+	if s.beneficiary == (common.Address{}) {
+		return ErrNoBeneficiary
+	}
+
+	known := make(map[common.Hash]struct{})
+	results, err := s.CashoutResults()
+	if err != nil {
+		return err
+	}
+	for _, result := range results {
+		known[result.TxHash] = struct{}{}
+	}
+
+	logs, err := s.backend.FilterLogs(ctx, ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(fromBlock),
+		Addresses: []common.Address{vault},
+		Topics:    [][]common.Hash{{chequeCashedEventType.ID}, {s.beneficiary.Hash()}},
+	})
+	if err != nil {
+		return err
+	}
+
+	headerTimes := make(map[uint64]int64)
+	for _, l := range logs {
+		if l.Removed {
+			continue
+		}
+		if _, ok := known[l.TxHash]; ok {
+			continue
+		}
+
+		var event chequeCashedEvent
+		err = transaction.ParseEvent(&vaultABI, "ChequeCashed", &event, l)
+		if err != nil {
+			return fmt.Errorf("parse event in %x: %w", l.TxHash, err)
+		}
+
+		cashTime, ok := headerTimes[l.BlockNumber]
+		if !ok {
+			header, err := s.backend.HeaderByNumber(ctx, new(big.Int).SetUint64(l.BlockNumber))
+			if err != nil {
+				return err
+			}
+			cashTime = int64(header.Time)
+			headerTimes[l.BlockNumber] = cashTime
+		}
+
+		err = s.repository.PutResult(&CashOutResult{
+			TxHash:    l.TxHash,
+			Vault:     vault,
+			Amount:    event.TotalPayout,
+			CashTime:  cashTime,
+			Status:    "success",
+			Recipient: event.Recipient,
+			Rebuilt:   true,
+		})
+		if err != nil {
+			return err
+		}
+		known[l.TxHash] = struct{}{}
+	}
+	return nil
+}
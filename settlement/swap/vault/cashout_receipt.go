@@ -0,0 +1,199 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/bittorrent/go-btfs/transaction"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// headSubscriber is implemented by backends which support subscriptions for new block headers
+type headSubscriber interface {
+	SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error)
+}
+
+// WithReceiptPollingInterval makes the service wait for cashout receipts itself instead of relying on the
+// transaction service. If the backend supports new head subscriptions the receipt is checked on every new block
+// and the interval is only used as a fallback, otherwise the receipt is polled with the given interval.
+func WithReceiptPollingInterval(interval time.Duration) CashoutServiceOption {
+	return cashoutServiceOptionFunc(func(s *cashoutService) {
+		s.receiptPollingInterval = interval
+	})
+}
+
+// ConfirmationPolicy decides whether the receipt of a cashout transaction is confirmed enough to be accounted,
+// e.g. after a number of blocks or once a finality gadget finalized its block. It is asked again on every new
+// block, or every polling interval, until it returns true.
+type ConfirmationPolicy func(ctx context.Context, receipt *types.Receipt, backend transaction.Backend) (bool, error)
+
+// cashoutCancellationDepth is the number of blocks the nonce of a cashout transaction must have been used by
+// another transaction before the cashout is considered cancelled, the same depth the transaction monitor uses
+const cashoutCancellationDepth = 6
+
+// defaultConfirmationCheckInterval is the interval the confirmation policy is asked again if no receipt polling
+// interval is configured
+const defaultConfirmationCheckInterval = time.Second
+
+// immediateConfirmation is the default ConfirmationPolicy which accepts every receipt as soon as it exists
+func immediateConfirmation(ctx context.Context, receipt *types.Receipt, backend transaction.Backend) (bool, error) {
+	return true, nil
+}
+
+// WithConfirmationPolicy sets the policy deciding when a cashout receipt is confirmed
+func WithConfirmationPolicy(policy ConfirmationPolicy) CashoutServiceOption {
+	return cashoutServiceOptionFunc(func(s *cashoutService) {
+		s.confirmationPolicy = policy
+	})
+}
+
+// waitForReceipt waits until the cashout transaction has been mined and the receipt is confirmed according to the
+// confirmation policy or the context is cancelled
+func (s *cashoutService) waitForReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	receipt, err := s.waitForMinedReceipt(ctx, txHash)
+	if err != nil {
+		return nil, err
+	}
+	return s.waitForConfirmation(ctx, txHash, receipt)
+}
+
+// waitForConfirmation asks the confirmation policy until it accepts the receipt. The receipt is fetched again
+// before every retry as the transaction might have been moved to another block by a reorg.
+func (s *cashoutService) waitForConfirmation(ctx context.Context, txHash common.Hash, receipt *types.Receipt) (*types.Receipt, error) {
+	interval := s.receiptPollingInterval
+	if interval <= 0 {
+		interval = defaultConfirmationCheckInterval
+	}
+	var ticker *time.Ticker
+	for {
+		confirmed, err := s.confirmationPolicy(ctx, receipt, s.backend)
+		if err != nil {
+			return nil, err
+		}
+		if confirmed {
+			return receipt, nil
+		}
+
+		if ticker == nil {
+			ticker = time.NewTicker(interval)
+			defer ticker.Stop()
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		latest, err := s.transactionReceipt(ctx, txHash)
+		if err != nil {
+			log.Infof("cashout: could not refresh receipt of %x: %v", txHash, err)
+			continue
+		}
+		receipt = latest
+	}
+}
+
+// waitForMinedReceipt waits until the cashout transaction has been mined or the context is cancelled. Like the
+// transaction monitor it gives up with transaction.ErrTransactionCancelled once the nonce of the transaction has
+// been used by another transaction, e.g. a cancellation after the deadline, or a dropped transaction was replaced.
+func (s *cashoutService) waitForMinedReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	if s.receiptPollingInterval <= 0 {
+		err := s.waitRetriesResumed(ctx)
+		if err != nil {
+			return nil, err
+		}
+		ctx, span := s.startSpan(ctx, "transaction.WaitForReceipt")
+		span.SetAttribute(TraceAttributeTxHash, txHash.Hex())
+		receipt, err := s.transactionService.WaitForReceipt(ctx, txHash)
+		endSpan(span, err)
+		return receipt, err
+	}
+
+	var heads chan *types.Header
+	if s.headSubscriber != nil {
+		heads = make(chan *types.Header, 1)
+		sub, err := s.headSubscriber.SubscribeNewHead(ctx, heads)
+		if err != nil {
+			log.Infof("cashout: new head subscription unavailable, polling for receipt of %x: %v", txHash, err)
+			heads = nil
+		} else {
+			defer sub.Unsubscribe()
+		}
+	}
+
+	// the nonce can only be checked if the sending account and the nonce of the transaction are known
+	var nonce uint64
+	checkNonce := s.beneficiary != (common.Address{})
+	if checkNonce {
+		stored, err := s.transactionService.StoredTransaction(txHash)
+		if err != nil {
+			log.Infof("cashout: could not get stored transaction %x, cannot detect its cancellation: %v", txHash, err)
+			checkNonce = false
+		} else {
+			nonce = stored.Nonce
+		}
+	}
+
+	ticker := time.NewTicker(s.receiptPollingInterval)
+	defer ticker.Stop()
+
+	for {
+		err := s.waitRetriesResumed(ctx)
+		if err != nil {
+			return nil, err
+		}
+		receipt, err := s.transactionReceipt(ctx, txHash)
+		if err == nil {
+			return receipt, nil
+		}
+		if !errors.Is(err, ethereum.NotFound) {
+			log.Infof("cashout: could not get receipt of %x: %v", txHash, err)
+		} else if checkNonce {
+			cancelled, err := s.nonceUsedElsewhere(ctx, txHash, nonce)
+			if err != nil {
+				log.Infof("cashout: could not check nonce of %x: %v", txHash, err)
+			} else if cancelled {
+				return nil, transaction.ErrTransactionCancelled
+			}
+		}
+
+		select {
+		case <-heads:
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// nonceUsedElsewhere reports whether the nonce of the transaction was used by another transaction of the sending
+// account at least cashoutCancellationDepth blocks ago, so the transaction itself can no longer be mined
+func (s *cashoutService) nonceUsedElsewhere(ctx context.Context, txHash common.Hash, nonce uint64) (bool, error) {
+	block, err := s.backend.BlockNumber(ctx)
+	if err != nil {
+		return false, err
+	}
+	if block < cashoutCancellationDepth {
+		return false, nil
+	}
+	oldNonce, err := s.backend.NonceAt(ctx, s.beneficiary, new(big.Int).SetUint64(block-cashoutCancellationDepth))
+	if err != nil {
+		return false, err
+	}
+	if nonce >= oldNonce {
+		return false, nil
+	}
+	// the transaction might have been mined since its receipt was looked up
+	_, err = s.transactionReceipt(ctx, txHash)
+	if err == nil {
+		return false, nil
+	}
+	if !errors.Is(err, ethereum.NotFound) {
+		return false, err
+	}
+	return true, nil
+}
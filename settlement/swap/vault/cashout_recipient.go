@@ -0,0 +1,54 @@
+package vault
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/bittorrent/go-btfs/transaction/storage"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const defaultRecipientPrefix = "swap_cashout_default_recipient_"
+
+var (
+	// ErrNoRecipient is the error if a cashout is requested without recipient and the vault has no default recipient
+	ErrNoRecipient = errors.New("no cashout recipient")
+)
+
+func defaultRecipientKey(vault common.Address) string {
+	return fmt.Sprintf("%s%x", defaultRecipientPrefix, vault)
+}
+
+// SetDefaultRecipient persists the recipient used when the vault is cashed with a zero recipient.
+// Setting the zero address removes the default.
+func (s *cashoutService) SetDefaultRecipient(vault, recipient common.Address) error {
+	if recipient == (common.Address{}) {
+		err := s.store.Delete(defaultRecipientKey(vault))
+		if err != nil && err != storage.ErrNotFound {
+			return err
+		}
+		return nil
+	}
+	return s.store.Put(defaultRecipientKey(vault), recipient)
+}
+
+// DefaultRecipient returns the default recipient of the vault or ErrNoRecipient if it has none
+func (s *cashoutService) DefaultRecipient(vault common.Address) (common.Address, error) {
+	var recipient common.Address
+	err := s.store.Get(defaultRecipientKey(vault), &recipient)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return common.Address{}, fmt.Errorf("%w: vault %x has no default recipient", ErrNoRecipient, vault)
+		}
+		return common.Address{}, err
+	}
+	return recipient, nil
+}
+
+// resolveRecipient replaces a zero recipient with the default recipient of the vault
+func (s *cashoutService) resolveRecipient(vault, recipient common.Address) (common.Address, error) {
+	if recipient != (common.Address{}) {
+		return recipient, nil
+	}
+	return s.DefaultRecipient(vault)
+}
@@ -0,0 +1,122 @@
+package vault
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// RecoverFailedResults re-checks all cashout results stored as failed, e.g. because waiting for the receipt timed
+// out, and promotes those whose transaction was confirmed successfully on-chain. The amount is corrected from the
+// ChequeCashed event and the totals are updated in the same batch as the status so a result is only counted once.
+// Results whose transaction is confirmed to have reverted are marked resolved and skipped on later passes.
+// It returns the number of recovered results.
+func (s *cashoutService) RecoverFailedResults(ctx context.Context) (int, error) {
+	s.recoverLock.Lock()
+	defer s.recoverLock.Unlock()
+
+	var failed []CashOutResult
+	err := s.repository.IterateResults(func(result CashOutResult) (bool, error) {
+		if result.Status == "fail" && !result.Resolved && result.TxHash != (common.Hash{}) {
+			failed = append(failed, result)
+		}
+		return false, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	recovered := 0
+	for _, result := range failed {
+		ok, err := s.recoverFailedResult(ctx, result)
+		if err != nil {
+			return recovered, err
+		}
+		if ok {
+			recovered++
+		}
+	}
+	return recovered, nil
+}
+
+// recoverFailedResult promotes a single failed result if its transaction was confirmed successfully
+func (s *cashoutService) recoverFailedResult(ctx context.Context, result CashOutResult) (bool, error) {
+	receipt, err := s.transactionReceipt(ctx, result.TxHash)
+	if err != nil {
+		if errors.Is(err, ethereum.NotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return false, s.resolveFailedResult(result)
+	}
+
+	cashed, err := s.parseCashChequeBeneficiaryReceipt(result.Vault, receipt)
+	if err != nil {
+		// the receipt is final, checking it again on the next start would give the same answer
+		log.Warnf("cashout: could not recover result of %x: %v", result.TxHash, err)
+		return false, s.resolveFailedResult(result)
+	}
+
+	token := s.cashoutToken(ctx, result.Vault)
+	s.totalsLock.Lock()
+	defer s.totalsLock.Unlock()
+	batch := newStoreBatch(s.store)
+	appliedTime, ok, err := appliedCashTime(batch, result.Vault, result.TxHash)
+	if err != nil {
+		return false, err
+	}
+	if ok {
+		// the totals were applied with another result of the transaction, the failed one is a duplicate
+		if appliedTime == result.CashTime {
+			return false, nil
+		}
+		err = s.batchRepository(batch).DeleteResult(&result)
+		if err != nil {
+			return false, err
+		}
+		log.Infof("cashout: removed duplicate failed result of %x for vault %x", result.TxHash, result.Vault)
+		return false, batch.Commit()
+	}
+
+	// the cashout belongs to the day it was made. The cheques it cashed cannot be told apart from those received
+	// since, so the uncashed record count of the vault is left alone.
+	s.addCashedTotals(batch, token, result.CashTime, cashed.TotalPayout, cashed.CallerPayout)
+	result.Status = "success"
+	result.Amount = cashed.TotalPayout
+	result.GasUsed = receipt.GasUsed
+	result.Bounced = cashed.Bounced
+	result.Recipient = cashed.Recipient
+	result.Beneficiary = cashed.Beneficiary
+	result.Caller = cashed.Caller
+	result.CallerPayout = cashed.CallerPayout
+	result.Token = token
+	err = markResultApplied(batch, &result)
+	if err != nil {
+		return false, err
+	}
+	// the result keeps its key so the failed entry is overwritten
+	err = s.batchRepository(batch).PutResult(&result)
+	if err != nil {
+		return false, err
+	}
+	err = batch.Commit()
+	if err != nil {
+		return false, err
+	}
+	s.observeCashout(result.Vault, result.Amount, result.Bounced)
+	log.Infof("cashout: recovered result of %x for vault %x", result.TxHash, result.Vault)
+	return true, nil
+}
+
+// resolveFailedResult marks the failed result as resolved so it is not re-checked on later passes
+func (s *cashoutService) resolveFailedResult(result CashOutResult) error {
+	s.totalsLock.Lock()
+	defer s.totalsLock.Unlock()
+	result.Resolved = true
+	return s.repository.PutResult(&result)
+}
@@ -0,0 +1,75 @@
+package vault
+
+import (
+	"context"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// recoverableValueWorkers bounds the number of vaults RecoverableValue queries concurrently
+const recoverableValueWorkers = 8
+
+// RecoverableValue returns how much of the uncashed amount of all known vaults could be cashed right now, which is
+// the sum of the uncashed amount of every vault capped by its current balance. Vaults which cannot be queried are
+// logged and left out.
+func (s *cashoutService) RecoverableValue(ctx context.Context) (*big.Int, error) {
+	cheques, err := s.lastReceivedCheques()
+	if err != nil {
+		return nil, err
+	}
+
+	vaults := make(chan common.Address)
+	var (
+		mu    sync.Mutex
+		total = big.NewInt(0)
+		wg    sync.WaitGroup
+	)
+	for i := 0; i < recoverableValueWorkers && i < len(cheques); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for vault := range vaults {
+				value, err := s.recoverableValue(ctx, vault)
+				if err != nil {
+					log.Infof("recoverable value: could not query vault %x: %v", vault, err)
+					continue
+				}
+				mu.Lock()
+				total.Add(total, value)
+				mu.Unlock()
+			}
+		}()
+	}
+	for vault := range cheques {
+		vaults <- vault
+	}
+	close(vaults)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return total, nil
+}
+
+// recoverableValue returns the uncashed amount of the vault capped by its balance
+func (s *cashoutService) recoverableValue(ctx context.Context, vault common.Address) (*big.Int, error) {
+	status, err := s.CashoutStatus(ctx, vault)
+	if err != nil {
+		return nil, err
+	}
+	if status.UncashedAmount.Sign() <= 0 {
+		return big.NewInt(0), nil
+	}
+
+	balance, err := newVaultContract(vault, s.transactionService).TotalBalance(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if balance.Cmp(status.UncashedAmount) < 0 {
+		return balance, nil
+	}
+	return new(big.Int).Set(status.UncashedAmount), nil
+}
@@ -0,0 +1,165 @@
+package vault
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bittorrent/go-btfs/statestore"
+	"github.com/bittorrent/go-btfs/transaction/storage"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// CashoutRepository persists the cashout actions and results of the CashoutService. The default implementation
+// keeps them in the state store, others can be plugged in with WithCashoutRepository, e.g. to keep the cashout
+// history in a database for reporting.
+type CashoutRepository interface {
+	// Action returns the last cashout action of the vault or storage.ErrNotFound
+	Action(vault common.Address) (*CashoutAction, error)
+	// PutAction stores the last cashout action of the vault
+	PutAction(vault common.Address, action *CashoutAction) error
+	// DeleteAction removes the last cashout action of the vault
+	DeleteAction(vault common.Address) error
+	// PutResult stores the result of a cashout
+	PutResult(result *CashOutResult) error
+	// DeleteResult removes a stored cashout result
+	DeleteResult(result *CashOutResult) error
+	// IterateResults calls fn for every stored cashout result until it returns stop or an error
+	IterateResults(fn func(result CashOutResult) (stop bool, err error)) error
+	// ResultsForDay returns the cashout results of the given day, the day of the daily totals
+	ResultsForDay(day time.Time) ([]CashOutResult, error)
+	// Vaults returns the distinct vaults with a cashout action or result, sorted by address
+	Vaults() ([]common.Address, error)
+}
+
+// batchingRepository is implemented by repositories which can write through a store batch, so results are
+// committed together with the cashout totals.
+type batchingRepository interface {
+	withWriter(writer storeReadWriter) CashoutRepository
+}
+
+// WithCashoutRepository replaces the state store backed persistence of cashout actions and results
+func WithCashoutRepository(repository CashoutRepository) CashoutServiceOption {
+	return cashoutServiceOptionFunc(func(s *cashoutService) {
+		s.repository = repository
+	})
+}
+
+// statestoreRepository is the default CashoutRepository keeping actions and results in the state store
+type statestoreRepository struct {
+	store  storage.StateStorer
+	writer storeReadWriter // receives the writes, the store itself unless writing through a batch
+	codec  Codec
+}
+
+func newStatestoreRepository(store storage.StateStorer, codec Codec) *statestoreRepository {
+	return &statestoreRepository{
+		store:  store,
+		writer: store,
+		codec:  codec,
+	}
+}
+
+func (r *statestoreRepository) withWriter(writer storeReadWriter) CashoutRepository {
+	return &statestoreRepository{
+		store:  r.store,
+		writer: writer,
+		codec:  r.codec,
+	}
+}
+
+func (r *statestoreRepository) Action(vault common.Address) (*CashoutAction, error) {
+	var action CashoutAction
+	err := r.writer.Get(cashoutActionKey(vault), encodedValue{codec: r.codec, v: &action})
+	if err != nil {
+		return nil, err
+	}
+	return &action, nil
+}
+
+func (r *statestoreRepository) PutAction(vault common.Address, action *CashoutAction) error {
+	return r.writer.Put(cashoutActionKey(vault), encodedValue{codec: r.codec, v: action})
+}
+
+func (r *statestoreRepository) DeleteAction(vault common.Address) error {
+	return r.writer.Delete(cashoutActionKey(vault))
+}
+
+func (r *statestoreRepository) PutResult(result *CashOutResult) error {
+	return r.writer.Put(statestore.CashoutResultKeyByTime(result.Vault, result.CashTime), encodedValue{codec: r.codec, v: result})
+}
+
+func (r *statestoreRepository) DeleteResult(result *CashOutResult) error {
+	return r.writer.Delete(statestore.CashoutResultKeyByTime(result.Vault, result.CashTime))
+}
+
+func (r *statestoreRepository) IterateResults(fn func(result CashOutResult) (stop bool, err error)) error {
+	return r.iterateResults(statestore.CashoutResultPrefixKey(), fn)
+}
+
+func (r *statestoreRepository) ResultsForDay(day time.Time) ([]CashOutResult, error) {
+	// results are partitioned by day in the store so only the day's own keys are iterated
+	result := make([]CashOutResult, 0)
+	err := r.iterateResults(statestore.CashoutResultDayPrefixKey(dayUnix(day)), func(cashOutResult CashOutResult) (bool, error) {
+		result = append(result, cashOutResult)
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *statestoreRepository) iterateResults(prefix string, fn func(result CashOutResult) (stop bool, err error)) error {
+	return r.store.Iterate(prefix, func(key, val []byte) (stop bool, err error) {
+		cashOutResult := CashOutResult{}
+		err = r.codec.Unmarshal(val, &cashOutResult)
+		if err != nil {
+			return false, err
+		}
+		return fn(cashOutResult)
+	})
+}
+
+// Vaults extracts the vaults from the store keys so no values need to be deserialized
+func (r *statestoreRepository) Vaults() ([]common.Address, error) {
+	vaults := make(map[common.Address]struct{})
+	resultPrefix := statestore.CashoutResultPrefixKey()
+
+	err := r.store.Iterate(cashoutActionPrefix, func(key, val []byte) (stop bool, err error) {
+		k := string(key)
+		var hexVault string
+		if strings.HasPrefix(k, resultPrefix) {
+			hexVault, _ = resultKeyVault(k)
+		} else {
+			// other keys under the action prefix (e.g. the write-ahead log) are not exact addresses and skipped here
+			hexVault = strings.TrimPrefix(k, cashoutActionPrefix)
+		}
+		if len(hexVault) != 2*common.AddressLength || !common.IsHexAddress(hexVault) {
+			return false, nil
+		}
+		vaults[common.HexToAddress(hexVault)] = struct{}{}
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]common.Address, 0, len(vaults))
+	for vault := range vaults {
+		result = append(result, vault)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return bytes.Compare(result[i].Bytes(), result[j].Bytes()) < 0
+	})
+	return result, nil
+}
+
+// batchRepository returns the repository writing through batch if the repository supports it
+func (s *cashoutService) batchRepository(batch storeReadWriter) CashoutRepository {
+	if r, ok := s.repository.(batchingRepository); ok {
+		return r.withWriter(batch)
+	}
+	return s.repository
+}
@@ -0,0 +1,102 @@
+package vault
+
+import (
+	"context"
+	"sync"
+)
+
+// retryQueue holds the cashouts resumed from the write-ahead log which are retried in the background.
+// While it is paused they wait before every receipt check instead of querying the backend.
+type retryQueue struct {
+	mu      sync.Mutex
+	depth   int
+	resumed chan struct{} // closed while the retries are not paused
+}
+
+func newRetryQueue() *retryQueue {
+	q := &retryQueue{resumed: make(chan struct{})}
+	close(q.resumed)
+	return q
+}
+
+func (q *retryQueue) add(delta int) {
+	q.mu.Lock()
+	q.depth += delta
+	q.mu.Unlock()
+}
+
+// wait blocks while the queue is paused or until ctx is done
+func (q *retryQueue) wait(ctx context.Context) error {
+	q.mu.Lock()
+	resumed := q.resumed
+	q.mu.Unlock()
+
+	select {
+	case <-resumed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type retryContextKey struct{}
+
+// withRetry marks ctx as belonging to a retried cashout
+func withRetry(ctx context.Context) context.Context {
+	return context.WithValue(ctx, retryContextKey{}, true)
+}
+
+// waitRetriesResumed blocks while the retries are paused if ctx belongs to a retried cashout
+func (s *cashoutService) waitRetriesResumed(ctx context.Context) error {
+	if retry, _ := ctx.Value(retryContextKey{}).(bool); !retry {
+		return nil
+	}
+	return s.retries.wait(ctx)
+}
+
+func (q *retryQueue) pause() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	select {
+	case <-q.resumed:
+		q.resumed = make(chan struct{})
+	default:
+	}
+}
+
+func (q *retryQueue) resume() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	select {
+	case <-q.resumed:
+	default:
+		close(q.resumed)
+	}
+}
+
+func (q *retryQueue) status() (paused bool, depth int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	select {
+	case <-q.resumed:
+	default:
+		paused = true
+	}
+	return paused, q.depth
+}
+
+// PauseRetries halts the cashouts retried in the background before their next receipt check, e.g. during a known
+// outage of the backend. They stay queued and continue on ResumeRetries.
+func (s *cashoutService) PauseRetries() {
+	s.retries.pause()
+}
+
+// ResumeRetries continues the retries halted by PauseRetries
+func (s *cashoutService) ResumeRetries() {
+	s.retries.resume()
+}
+
+// RetryQueueStatus reports whether the retries are paused and how many cashouts are being retried
+func (s *cashoutService) RetryQueueStatus() (paused bool, depth int) {
+	return s.retries.status()
+}
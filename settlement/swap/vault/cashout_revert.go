@@ -0,0 +1,54 @@
+package vault
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var (
+	// revertErrorSelector is the selector of Error(string) used by require and revert with a reason
+	revertErrorSelector = []byte{0x08, 0xc3, 0x79, 0xa0}
+	// revertPanicSelector is the selector of Panic(uint256) used by failing asserts and arithmetic checks
+	revertPanicSelector = []byte{0x4e, 0x48, 0x7b, 0x71}
+)
+
+// decodeRevert turns the data returned by a reverted call into a readable reason
+func decodeRevert(data []byte) string {
+	if len(data) == 0 {
+		return "reverted without reason"
+	}
+	if len(data) >= 4 && bytes.Equal(data[:4], revertErrorSelector) {
+		reason, err := abi.UnpackRevert(data)
+		if err == nil {
+			return reason
+		}
+	}
+	if len(data) == 4+32 && bytes.Equal(data[:4], revertPanicSelector) {
+		return fmt.Sprintf("panic code 0x%x", new(big.Int).SetBytes(data[4:]))
+	}
+	return fmt.Sprintf("unknown revert data %s", hexutil.Encode(data))
+}
+
+// revertReason returns the decoded revert reason of an error returned by eth_call or eth_estimateGas. It reports
+// false if the error carries no revert data, e.g. because the node could not be reached.
+func revertReason(err error) (string, bool) {
+	var dataErr rpc.DataError
+	if !errors.As(err, &dataErr) {
+		return "", false
+	}
+	hexData, ok := dataErr.ErrorData().(string)
+	if !ok {
+		return "", false
+	}
+	data, err := hexutil.Decode(hexData)
+	if err != nil {
+		return "", false
+	}
+	return decodeRevert(data), true
+}
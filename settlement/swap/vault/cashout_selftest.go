@@ -0,0 +1,95 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// defaultSelfTestTimeout bounds a self test if the config does not set a timeout
+const defaultSelfTestTimeout = 5 * time.Minute
+
+var (
+	// ErrSelfTestNotConfigured is the error if SelfTest is called without a self test configuration
+	ErrSelfTestNotConfigured = errors.New("self test not configured")
+	// ErrSelfTestFailed is the error if the self test cashout was not recorded correctly
+	ErrSelfTestFailed = errors.New("self test failed")
+)
+
+// SelfTestConfig configures the cashout self test
+type SelfTestConfig struct {
+	ChainID   int64          // testnet the self test may run on, the backend must be connected to it
+	Vault     common.Address // test vault holding a cheque for the node
+	Recipient common.Address // recipient of the test cashout, zero to use the default recipient of the vault
+	Timeout   time.Duration  // maximum duration of the self test, 0 uses defaultSelfTestTimeout
+}
+
+// WithSelfTest configures the test vault used by SelfTest
+func WithSelfTest(config SelfTestConfig) CashoutServiceOption {
+	return cashoutServiceOptionFunc(func(s *cashoutService) {
+		s.selfTest = &config
+	})
+}
+
+// SelfTest cashes the last cheque of the configured test vault and checks that the result is recorded correctly.
+// It exercises sending, waiting for the receipt, parsing it and storing the result, so a wrong chain configuration
+// or ABI is found before the node is relied on. It refuses to run if the backend is not on the configured testnet.
+func (s *cashoutService) SelfTest(ctx context.Context) error {
+	config := s.selfTest
+	if config == nil {
+		return ErrSelfTestNotConfigured
+	}
+
+	backendChainID, err := s.backendChainID(ctx)
+	if err != nil {
+		return err
+	}
+	if backendChainID == nil || backendChainID.Cmp(big.NewInt(config.ChainID)) != 0 {
+		return fmt.Errorf("%w: backend chain %v, self test chain %d", ErrChainIDMismatch, backendChainID, config.ChainID)
+	}
+
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = defaultSelfTestTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// subscribe before sending so the result cannot be missed
+	results, unsubscribe := s.SubscribeCashoutResults()
+	defer unsubscribe()
+
+	txHash, err := s.CashCheque(ctx, config.Vault, config.Recipient)
+	if err != nil {
+		return fmt.Errorf("%w: send: %v", ErrSelfTestFailed, err)
+	}
+
+	var result CashOutResult
+	for result.TxHash != txHash {
+		select {
+		case result = <-results:
+		case <-ctx.Done():
+			return fmt.Errorf("%w: no result for %x: %v", ErrSelfTestFailed, txHash, ctx.Err())
+		}
+	}
+	if result.Status != "success" {
+		return fmt.Errorf("%w: cashout %x recorded as %s", ErrSelfTestFailed, txHash, result.Status)
+	}
+
+	status, err := s.CashoutStatus(ctx, config.Vault)
+	if err != nil {
+		return fmt.Errorf("%w: status: %v", ErrSelfTestFailed, err)
+	}
+	if status.State != CashoutStateConfirmed || status.Last == nil || status.Last.TxHash != txHash || status.Last.Result == nil {
+		return fmt.Errorf("%w: cashout %x not confirmed, state %s", ErrSelfTestFailed, txHash, status.State)
+	}
+	if status.Last.Result.TotalPayout == nil || result.Amount == nil || status.Last.Result.TotalPayout.Cmp(result.Amount) != 0 {
+		return fmt.Errorf("%w: recorded amount %v does not match payout %v", ErrSelfTestFailed, result.Amount, status.Last.Result.TotalPayout)
+	}
+	log.Infof("cashout self test passed with transaction %x", txHash)
+	return nil
+}
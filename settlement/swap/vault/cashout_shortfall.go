@@ -0,0 +1,83 @@
+package vault
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ProlongedShortfallFunc is notified when the balance of a vault has not covered its uncashed amount for longer
+// than the configured duration. shortfall is the uncashed amount exceeding the vault balance.
+type ProlongedShortfallFunc func(vault common.Address, shortfall *big.Int, duration time.Duration)
+
+// WithProlongedShortfallAlert calls onShortfall once a vault has been unable to cover its uncashed amount for
+// longer than after. Transient dips are ignored, a chronically under-funded vault is reported once per shortfall
+// period. The vaults are checked by the auto cashout loop.
+func WithProlongedShortfallAlert(after time.Duration, onShortfall ProlongedShortfallFunc) CashoutServiceOption {
+	return cashoutServiceOptionFunc(func(s *cashoutService) {
+		s.shortfallMonitor = &shortfallMonitor{
+			after:       after,
+			onShortfall: onShortfall,
+			since:       make(map[common.Address]time.Time),
+			alerted:     make(map[common.Address]bool),
+		}
+	})
+}
+
+// shortfallMonitor tracks since when the vaults have not been covering their uncashed amount
+type shortfallMonitor struct {
+	after       time.Duration
+	onShortfall ProlongedShortfallFunc
+
+	mu      sync.Mutex
+	since   map[common.Address]time.Time
+	alerted map[common.Address]bool
+}
+
+// observe records the current shortfall of the vault and reports whether the alert is due
+func (m *shortfallMonitor) observe(vault common.Address, shortfall *big.Int, now time.Time) (time.Duration, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if shortfall.Sign() <= 0 {
+		delete(m.since, vault)
+		delete(m.alerted, vault)
+		return 0, false
+	}
+
+	since, ok := m.since[vault]
+	if !ok {
+		m.since[vault] = now
+		return 0, false
+	}
+	duration := now.Sub(since)
+	if duration < m.after || m.alerted[vault] {
+		return duration, false
+	}
+	m.alerted[vault] = true
+	return duration, true
+}
+
+// checkShortfall compares the uncashed amount of the vault with its balance and alerts on a prolonged shortfall
+func (s *cashoutService) checkShortfall(ctx context.Context, vault common.Address, uncashed *big.Int) {
+	if s.shortfallMonitor == nil {
+		return
+	}
+
+	balance, err := newVaultContract(vault, s.transactionService).TotalBalance(ctx)
+	if err != nil {
+		log.Errorf("cashout: could not get balance of vault %x: %v", vault, err)
+		return
+	}
+
+	shortfall := new(big.Int).Sub(uncashed, balance)
+	duration, alert := s.shortfallMonitor.observe(vault, shortfall, s.now())
+	if !alert {
+		return
+	}
+	log.Warnf("cashout: vault %x has been short of %d for %v", vault, shortfall, duration)
+	s.shortfallMonitor.onShortfall(vault, shortfall, duration)
+}
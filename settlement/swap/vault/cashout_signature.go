@@ -0,0 +1,119 @@
+package vault
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// signatureCacheSize is the number of verified cheque signatures kept in memory
+const signatureCacheSize = 1024
+
+// signatureVerifier verifies cheque signatures before cashing and caches the recovered signers so repeated
+// validation of the same cheque does not repeat the ECDSA recovery
+type signatureVerifier struct {
+	recoverCheque RecoverChequeFunc
+	chainID       int64
+	signers       *lru.Cache // cheque hash -> signer
+
+	mu       sync.Mutex
+	issuers  map[common.Address]common.Address // vault -> issuer, the issuer of a vault never changes
+	lastHash map[common.Address]common.Hash    // vault -> hash of the last verified cheque
+}
+
+// WithSignatureVerification makes CashCheque verify that the cheque was signed by the vault issuer before
+// the cashout is sent
+func WithSignatureVerification(recoverCheque RecoverChequeFunc, chainID int64) CashoutServiceOption {
+	return cashoutServiceOptionFunc(func(s *cashoutService) {
+		signers, err := lru.New(signatureCacheSize)
+		if err != nil {
+			// only fails for a non positive size
+			panic(err)
+		}
+		s.signatureVerifier = &signatureVerifier{
+			recoverCheque: recoverCheque,
+			chainID:       chainID,
+			signers:       signers,
+			issuers:       make(map[common.Address]common.Address),
+			lastHash:      make(map[common.Address]common.Hash),
+		}
+	})
+}
+
+// chequeHash identifies a signed cheque in the signature cache
+func chequeHash(cheque *SignedCheque) common.Hash {
+	return crypto.Keccak256Hash(
+		cheque.Vault.Bytes(),
+		cheque.Beneficiary.Bytes(),
+		cheque.CumulativePayout.Bytes(),
+		cheque.Signature,
+	)
+}
+
+// signer returns the signer of the cheque. A new cheque for a vault evicts the cached signer of its predecessor.
+func (v *signatureVerifier) signer(cheque *SignedCheque) (common.Address, error) {
+	hash := chequeHash(cheque)
+	if signer, ok := v.signers.Get(hash); ok {
+		return signer.(common.Address), nil
+	}
+
+	signer, err := v.recoverCheque(cheque, v.chainID)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	v.mu.Lock()
+	if last, ok := v.lastHash[cheque.Vault]; ok && last != hash {
+		v.signers.Remove(last)
+	}
+	v.lastHash[cheque.Vault] = hash
+	v.mu.Unlock()
+
+	v.signers.Add(hash, signer)
+	return signer, nil
+}
+
+// vaultIssuer returns the issuer of the vault, asking the contract only once per vault
+func (s *cashoutService) vaultIssuer(ctx context.Context, vault common.Address) (common.Address, error) {
+	v := s.signatureVerifier
+	v.mu.Lock()
+	issuer, ok := v.issuers[vault]
+	v.mu.Unlock()
+	if ok {
+		return issuer, nil
+	}
+
+	issuer, err := newVaultContract(vault, s.transactionService).Issuer(ctx)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	v.mu.Lock()
+	v.issuers[vault] = issuer
+	v.mu.Unlock()
+	return issuer, nil
+}
+
+// verifyChequeSignature returns ErrChequeInvalid if signature verification is enabled and the cheque was not
+// signed by the vault issuer
+func (s *cashoutService) verifyChequeSignature(ctx context.Context, cheque *SignedCheque) error {
+	if s.signatureVerifier == nil {
+		return nil
+	}
+
+	issuer, err := s.vaultIssuer(ctx, cheque.Vault)
+	if err != nil {
+		return err
+	}
+	signer, err := s.signatureVerifier.signer(cheque)
+	if err != nil {
+		return err
+	}
+	if signer != issuer {
+		return ErrChequeInvalid
+	}
+	return nil
+}
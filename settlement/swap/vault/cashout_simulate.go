@@ -0,0 +1,81 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/bittorrent/go-btfs/transaction"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	// ErrCashoutSimulationReverted is the error if the simulated cashout transaction reverts
+	ErrCashoutSimulationReverted = errors.New("simulated cashout reverted")
+)
+
+// SimulateCashout simulates cashing the last cheque of the vault with an eth_call and returns the projected result
+// without sending a transaction. cashChequeBeneficiary does not return anything, so the payout is projected from the
+// on-chain paidOut and the vault balance: whatever exceeds the balance bounces.
+func (s *cashoutService) SimulateCashout(ctx context.Context, vault, recipient common.Address) (*CashChequeResult, error) {
+	cheque, err := s.lastReceivedCheque(vault)
+	if err != nil {
+		return nil, newCashoutError(CashoutPhaseLookup, err)
+	}
+
+	recipient, err = s.resolveRecipient(vault, recipient)
+	if err != nil {
+		return nil, newCashoutError(CashoutPhaseLookup, err)
+	}
+
+	effectiveRecipient, err := s.recipientPolicy(vault, recipient)
+	if err != nil {
+		return nil, newCashoutError(CashoutPhasePolicy, err)
+	}
+
+	err = s.checkBeneficiary(cheque)
+	if err != nil {
+		return nil, newCashoutError(CashoutPhaseValidate, err)
+	}
+
+	callData, err := vaultABI.Pack("cashChequeBeneficiary", effectiveRecipient, cheque.CumulativePayout, cheque.Signature)
+	if err != nil {
+		return nil, newCashoutError(CashoutPhasePack, err)
+	}
+	_, err = s.transactionService.Call(ctx, &transaction.TxRequest{
+		To:   &vault,
+		Data: callData,
+	})
+	if err != nil {
+		if reason, ok := revertReason(err); ok {
+			return nil, fmt.Errorf("%w: %s", ErrCashoutSimulationReverted, reason)
+		}
+		return nil, fmt.Errorf("%w: %v", ErrCashoutSimulationReverted, err)
+	}
+
+	paidOut, err := s.paidOut(ctx, vault, cheque.Beneficiary)
+	if err != nil {
+		return nil, err
+	}
+	totalBalance, err := newVaultContract(vault, s.transactionService).TotalBalance(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	payout := uncashedAmount(vault, cheque.CumulativePayout, paidOut)
+	bounced := payout.Cmp(totalBalance) > 0
+	if bounced {
+		payout = new(big.Int).Set(totalBalance)
+	}
+
+	return &CashChequeResult{
+		Beneficiary:      cheque.Beneficiary,
+		Recipient:        effectiveRecipient,
+		Caller:           cheque.Beneficiary,
+		TotalPayout:      payout,
+		CumulativePayout: cheque.CumulativePayout,
+		CallerPayout:     big.NewInt(0),
+		Bounced:          bounced,
+	}, nil
+}
@@ -0,0 +1,148 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/bittorrent/go-btfs/settlement/swap/erc20"
+	"github.com/bittorrent/go-btfs/transaction"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// totalBasisPoints is the sum the basis points of a split have to add up to
+const totalBasisPoints = 10000
+
+var (
+	// ErrInvalidSplit is the error if the recipient splits of a cashout are malformed
+	ErrInvalidSplit = errors.New("invalid cashout split")
+	// ErrNoBeneficiary is the error if an operation needs the sending account but it is not configured
+	ErrNoBeneficiary = errors.New("cashout beneficiary not configured")
+)
+
+// RecipientSplit is the share of a cashout paid to a single recipient
+type RecipientSplit struct {
+	Recipient   common.Address
+	BasisPoints uint64 // share of the cashed amount in 1/10000
+}
+
+// SplitTransfer is the token transfer paying a single split
+type SplitTransfer struct {
+	Recipient common.Address
+	Amount    *big.Int
+	TxHash    common.Hash
+}
+
+// CashChequeSplitResult tracks the transactions sent by CashChequeSplit
+type CashChequeSplitResult struct {
+	CashoutTxHash common.Hash     // transaction cashing the cheque to the node
+	Amount        *big.Int        // amount which was cashed and split
+	Transfers     []SplitTransfer // transfers sent so far, in the order of the splits
+}
+
+// validateSplits checks that every split has a recipient and the basis points add up to totalBasisPoints
+func validateSplits(splits []RecipientSplit) error {
+	if len(splits) == 0 {
+		return fmt.Errorf("%w: no splits", ErrInvalidSplit)
+	}
+	var sum uint64
+	for _, split := range splits {
+		if split.Recipient == (common.Address{}) {
+			return fmt.Errorf("%w: zero recipient", ErrInvalidSplit)
+		}
+		if split.BasisPoints == 0 || split.BasisPoints > totalBasisPoints {
+			return fmt.Errorf("%w: %d basis points for %x", ErrInvalidSplit, split.BasisPoints, split.Recipient)
+		}
+		sum += split.BasisPoints
+	}
+	if sum != totalBasisPoints {
+		return fmt.Errorf("%w: basis points add up to %d", ErrInvalidSplit, sum)
+	}
+	return nil
+}
+
+// splitAmounts divides amount according to splits. The remainder left by rounding goes to the last split.
+func splitAmounts(amount *big.Int, splits []RecipientSplit) []*big.Int {
+	amounts := make([]*big.Int, len(splits))
+	remaining := new(big.Int).Set(amount)
+	for i, split := range splits {
+		if i == len(splits)-1 {
+			amounts[i] = remaining
+			break
+		}
+		amounts[i] = new(big.Int).Mul(amount, new(big.Int).SetUint64(split.BasisPoints))
+		amounts[i].Div(amounts[i], big.NewInt(totalBasisPoints))
+		remaining.Sub(remaining, amounts[i])
+	}
+	return amounts
+}
+
+// CashChequeSplit cashes the last cheque of vault to the node and, once the cashout confirmed without bouncing,
+// pays the cashed amount out to the splits with one token transfer each. The contract only supports a single
+// recipient, hence the fan out happens afterwards from the node's account.
+func (s *cashoutService) CashChequeSplit(ctx context.Context, vault common.Address, splits []RecipientSplit) (*CashChequeSplitResult, error) {
+	if err := validateSplits(splits); err != nil {
+		return nil, err
+	}
+	if s.beneficiary == (common.Address{}) {
+		return nil, ErrNoBeneficiary
+	}
+
+	txHash, err := s.CashCheque(ctx, vault, s.beneficiary)
+	if err != nil {
+		return nil, err
+	}
+	result := &CashChequeSplitResult{
+		CashoutTxHash: txHash,
+	}
+
+	receipt, err := s.waitForReceipt(ctx, txHash)
+	if err != nil {
+		return result, err
+	}
+	if receipt.Status == types.ReceiptStatusFailed {
+		return result, transaction.ErrTransactionReverted
+	}
+
+	cashResult, err := s.parseCashChequeBeneficiaryReceipt(vault, receipt)
+	if err != nil {
+		return result, err
+	}
+	if cashResult.Bounced {
+		return result, ErrCashoutBounced
+	}
+	if cashResult.Recipient != s.beneficiary {
+		return result, fmt.Errorf("cashout paid to %x instead of the node", cashResult.Recipient)
+	}
+
+	// the node is both recipient and caller so it received the whole payout
+	result.Amount = cashResult.TotalPayout
+	if result.Amount.Sign() <= 0 {
+		return result, nil
+	}
+
+	token, err := s.vaultToken(ctx, vault)
+	if err != nil {
+		return result, err
+	}
+	tokenContract := erc20.New(s.backend, s.transactionService, token)
+
+	for i, amount := range splitAmounts(result.Amount, splits) {
+		if amount.Sign() == 0 {
+			continue
+		}
+		transferTxHash, err := tokenContract.Transfer(ctx, splits[i].Recipient, amount)
+		if err != nil {
+			return result, fmt.Errorf("transfer to %x: %w", splits[i].Recipient, err)
+		}
+		log.Infof("cashout split: transferred %d to %x in %x", amount, splits[i].Recipient, transferTxHash)
+		result.Transfers = append(result.Transfers, SplitTransfer{
+			Recipient: splits[i].Recipient,
+			Amount:    amount,
+			TxHash:    transferTxHash,
+		})
+	}
+	return result, nil
+}
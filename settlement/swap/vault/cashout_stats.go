@@ -0,0 +1,156 @@
+package vault
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/bittorrent/go-btfs/statestore"
+	"github.com/bittorrent/go-btfs/transaction/storage"
+)
+
+// CashoutStatsSnapshot summarizes the cashout statistics of the node
+type CashoutStatsSnapshot struct {
+	TotalReceivedCashed      *big.Int // total amount cashed out
+	TotalReceivedCashedCount int      // total number of cashed cheques
+	TotalCallerPayout        *big.Int // total payout received as caller of the cashouts
+	DailyReceivedCashed      *big.Int // amount cashed out today
+	PendingCount             int      // number of cashouts whose result has not been stored yet
+	LastCashoutTime          int64    // unix time of the last stored cashout result, 0 if there is none
+}
+
+// StatsSnapshot returns the cashout statistics in one call. The maintained totals are read directly, only the last
+// cashout time falls back to scanning the results for stores written before it was recorded.
+func (s *cashoutService) StatsSnapshot() (CashoutStatsSnapshot, error) {
+	snapshot := CashoutStatsSnapshot{
+		TotalReceivedCashed: big.NewInt(0),
+		TotalCallerPayout:   big.NewInt(0),
+		DailyReceivedCashed: big.NewInt(0),
+	}
+
+	for key, value := range map[string]interface{}{
+		statestore.TotalReceivedCashedKey:                &snapshot.TotalReceivedCashed,
+		statestore.TotalReceivedCashedCountKey:           &snapshot.TotalReceivedCashedCount,
+		statestore.TotalCallerPayoutKey:                  &snapshot.TotalCallerPayout,
+		statestore.GetTodayTotalDailyReceivedCashedKey(): &snapshot.DailyReceivedCashed,
+	} {
+		err := s.store.Get(key, value)
+		if err != nil && err != storage.ErrNotFound {
+			return CashoutStatsSnapshot{}, err
+		}
+	}
+
+	pending, err := s.pendingCashouts()
+	if err != nil {
+		return CashoutStatsSnapshot{}, err
+	}
+	snapshot.PendingCount = pending
+
+	err = s.store.Get(statestore.LastCashoutTimeKey, &snapshot.LastCashoutTime)
+	if err == storage.ErrNotFound {
+		results, err := s.CashoutResults()
+		if err != nil {
+			return CashoutStatsSnapshot{}, err
+		}
+		for _, result := range results {
+			if result.CashTime > snapshot.LastCashoutTime {
+				snapshot.LastCashoutTime = result.CashTime
+			}
+		}
+	} else if err != nil {
+		return CashoutStatsSnapshot{}, err
+	}
+
+	return snapshot, nil
+}
+
+const (
+	// dailyTotalsWorkers bounds the number of concurrent store reads of DailyTotalsRange
+	dailyTotalsWorkers = 8
+	secondsPerDay      = 24 * 60 * 60
+)
+
+// ErrInvalidRange is the error if the end of a range is before its start
+var ErrInvalidRange = errors.New("invalid range")
+
+// DailyTotal are the totals of a single day
+type DailyTotal struct {
+	Day            time.Time // start of the day in UTC
+	Received       *big.Int  // amount received in cheques
+	ReceivedCashed *big.Int  // amount cashed out
+	CashoutGas     *big.Int  // gas cost in wei spent on cashouts
+}
+
+// DailyTotalsRange returns the totals of every day from the day of from to the day of to, both included. Days
+// without any records are returned with zero totals so the series is dense.
+func (s *cashoutService) DailyTotalsRange(from, to time.Time) ([]DailyTotal, error) {
+	first, last := dayUnix(from), dayUnix(to)
+	if last < first {
+		return nil, fmt.Errorf("%w: %v is before %v", ErrInvalidRange, to, from)
+	}
+
+	totals := make([]DailyTotal, (last-first)/secondsPerDay+1)
+	days := make(chan int)
+	errs := make(chan error, dailyTotalsWorkers)
+	var wg sync.WaitGroup
+	for i := 0; i < dailyTotalsWorkers && i < len(totals); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range days {
+				total, err := s.dailyTotal(first + int64(i)*secondsPerDay)
+				if err != nil {
+					errs <- err
+					// drain the remaining days so the producer is not blocked
+					for range days {
+					}
+					return
+				}
+				totals[i] = total
+			}
+		}()
+	}
+	for i := range totals {
+		days <- i
+	}
+	close(days)
+	wg.Wait()
+	close(errs)
+
+	if err := <-errs; err != nil {
+		return nil, err
+	}
+	return totals, nil
+}
+
+// dailyTotal reads the totals of the day starting at the given unix time
+func (s *cashoutService) dailyTotal(day int64) (DailyTotal, error) {
+	total := DailyTotal{
+		Day:            time.Unix(day, 0).UTC(),
+		Received:       big.NewInt(0),
+		ReceivedCashed: big.NewInt(0),
+		CashoutGas:     big.NewInt(0),
+	}
+	for key, value := range map[string]*big.Int{
+		statestore.GetTotalDailyReceivedCashedKeyByTime(day): total.ReceivedCashed,
+		statestore.GetTotalDailyCashoutGasKeyByTime(day):     total.CashoutGas,
+	} {
+		err := s.store.Get(key, value)
+		if err != nil && err != storage.ErrNotFound {
+			return DailyTotal{}, err
+		}
+	}
+
+	// the received amount is kept together with the count of received cheques
+	var received DailyReceivedStats
+	err := s.store.Get(statestore.GetTotalDailyReceivedKeyByTime(day), &received)
+	if err != nil && err != storage.ErrNotFound {
+		return DailyTotal{}, err
+	}
+	if received.Amount != nil {
+		total.Received = received.Amount
+	}
+	return total, nil
+}
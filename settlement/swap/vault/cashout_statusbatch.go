@@ -0,0 +1,127 @@
+package vault
+
+import (
+	"context"
+	"errors"
+
+	"github.com/bittorrent/go-btfs/transaction/storage"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// receiptBatchSize bounds the number of receipts requested in a single batch
+const receiptBatchSize = 100
+
+// batchCaller is implemented by backends which can send several rpc calls in one request, e.g. the rpc client
+type batchCaller interface {
+	BatchCallContext(ctx context.Context, b []rpc.BatchElem) error
+}
+
+// VaultCashoutStatus is the cashout status of a single vault of CashoutStatusBatch
+type VaultCashoutStatus struct {
+	Vault  common.Address
+	Status *CashoutStatus // nil if Err is set
+	Err    error
+}
+
+type prefetchedReceiptsContextKey struct{}
+
+// withPrefetchedReceipts lets the status computations with ctx use the receipts instead of fetching them
+func withPrefetchedReceipts(ctx context.Context, receipts map[common.Hash]*types.Receipt) context.Context {
+	return context.WithValue(ctx, prefetchedReceiptsContextKey{}, receipts)
+}
+
+// prefetchedReceipt returns the receipt of the transaction if it was prefetched for ctx
+func prefetchedReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, bool) {
+	receipts, _ := ctx.Value(prefetchedReceiptsContextKey{}).(map[common.Hash]*types.Receipt)
+	receipt, ok := receipts[txHash]
+	return receipt, ok
+}
+
+func hasPrefetchedReceipt(ctx context.Context, txHash common.Hash) bool {
+	_, ok := prefetchedReceipt(ctx, txHash)
+	return ok
+}
+
+// batchReceipts fetches the receipts of the transactions in as few requests as possible. Transactions which are not
+// mined yet or whose receipt could not be fetched are left out. It returns nil if the backend cannot batch calls.
+func (s *cashoutService) batchReceipts(ctx context.Context, hashes []common.Hash) (map[common.Hash]*types.Receipt, error) {
+	caller, ok := s.backend.(batchCaller)
+	if !ok {
+		return nil, nil
+	}
+
+	ctx, span := s.startSpan(ctx, "backend.BatchTransactionReceipts")
+	defer span.End()
+
+	result := make(map[common.Hash]*types.Receipt)
+	for start := 0; start < len(hashes); start += receiptBatchSize {
+		end := start + receiptBatchSize
+		if end > len(hashes) {
+			end = len(hashes)
+		}
+
+		receipts := make([]*types.Receipt, end-start)
+		batch := make([]rpc.BatchElem, end-start)
+		for i, hash := range hashes[start:end] {
+			batch[i] = rpc.BatchElem{
+				Method: "eth_getTransactionReceipt",
+				Args:   []interface{}{hash},
+				Result: &receipts[i],
+			}
+		}
+		err := caller.BatchCallContext(ctx, batch)
+		if err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+
+		for i, elem := range batch {
+			if elem.Error != nil || receipts[i] == nil {
+				continue
+			}
+			result[hashes[start+i]] = receipts[i]
+		}
+	}
+	return result, nil
+}
+
+// CashoutStatusBatch gets the cashout status of every vault like CashoutStatus. If the backend can batch calls, the
+// receipts of the last cashouts are fetched together first, otherwise every status is fetched on its own. A failed
+// status is reported in the entry of its vault, the error is only set if ctx is done.
+func (s *cashoutService) CashoutStatusBatch(ctx context.Context, vaults []common.Address) ([]VaultCashoutStatus, error) {
+	hashes := make([]common.Hash, 0, len(vaults))
+	for _, vault := range vaults {
+		action, err := s.repository.Action(vault)
+		if err != nil {
+			if !errors.Is(err, storage.ErrNotFound) {
+				log.Infof("cashout status batch: could not get cashout action of vault %x: %v", vault, err)
+			}
+			continue
+		}
+		hashes = append(hashes, action.TxHash)
+	}
+
+	receipts, err := s.batchReceipts(ctx, hashes)
+	if err != nil {
+		log.Infof("cashout status batch: could not fetch receipts, fetching them one by one: %v", err)
+	}
+	if len(receipts) > 0 {
+		ctx = withPrefetchedReceipts(ctx, receipts)
+	}
+
+	result := make([]VaultCashoutStatus, len(vaults))
+	for i, vault := range vaults {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		status, err := s.CashoutStatus(ctx, vault)
+		result[i] = VaultCashoutStatus{
+			Vault:  vault,
+			Status: status,
+			Err:    err,
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,148 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// resultSubscriptionBuffer is the number of results buffered for every subscriber
+const resultSubscriptionBuffer = 16
+
+// resultSubscribers fans out stored cashout results to all subscribers. Every subscriber has a missed channel
+// which is signalled when a result had to be dropped for it.
+type resultSubscribers struct {
+	mu   sync.Mutex
+	subs map[chan CashOutResult]chan struct{}
+}
+
+// SubscribeCashoutResults returns a channel receiving every cashout result as it is stored and a function to
+// cancel the subscription. Results are dropped for subscribers which do not keep up.
+func (s *cashoutService) SubscribeCashoutResults() (<-chan CashOutResult, func()) {
+	results, _, unsubscribe := s.subscribeCashoutResults(resultSubscriptionBuffer)
+	return results, unsubscribe
+}
+
+// subscribeCashoutResults subscribes to the stored cashout results buffering up to buffer results. missed is
+// signalled whenever a result was dropped because the buffer was full.
+func (s *cashoutService) subscribeCashoutResults(buffer int) (results <-chan CashOutResult, missed <-chan struct{}, unsubscribe func()) {
+	c := make(chan CashOutResult, buffer)
+	m := make(chan struct{}, 1)
+
+	s.resultSubscribers.mu.Lock()
+	if s.resultSubscribers.subs == nil {
+		s.resultSubscribers.subs = make(map[chan CashOutResult]chan struct{})
+	}
+	s.resultSubscribers.subs[c] = m
+	s.resultSubscribers.mu.Unlock()
+
+	var once sync.Once
+	return c, m, func() {
+		once.Do(func() {
+			s.resultSubscribers.mu.Lock()
+			delete(s.resultSubscribers.subs, c)
+			s.resultSubscribers.mu.Unlock()
+		})
+	}
+}
+
+// publishCashoutResult notifies all subscribers of a stored cashout result
+func (s *cashoutService) publishCashoutResult(result CashOutResult) {
+	s.resultSubscribers.mu.Lock()
+	defer s.resultSubscribers.mu.Unlock()
+
+	for c, missed := range s.resultSubscribers.subs {
+		select {
+		case c <- result:
+		default:
+			log.Infof("cashout: subscriber too slow, dropping result of transaction %x", result.TxHash)
+			select {
+			case missed <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// WaitForCashouts waits until results of all the given cashout transactions are stored and returns them by
+// transaction hash. If ctx is done first the results collected so far are returned together with the context error.
+// The stored results are looked at again whenever a published result was missed, so a full buffer cannot make it
+// wait for a result which is already stored.
+func (s *cashoutService) WaitForCashouts(ctx context.Context, txHashes []common.Hash) (map[common.Hash]*CashOutResult, error) {
+	waiting := make(map[common.Hash]struct{}, len(txHashes))
+	for _, txHash := range txHashes {
+		waiting[txHash] = struct{}{}
+	}
+	results := make(map[common.Hash]*CashOutResult, len(waiting))
+	collect := func(result CashOutResult) {
+		if _, ok := waiting[result.TxHash]; ok {
+			delete(waiting, result.TxHash)
+			results[result.TxHash] = &result
+		}
+	}
+
+	scan := func() error {
+		return s.repository.IterateResults(func(result CashOutResult) (bool, error) {
+			collect(result)
+			return len(waiting) == 0, nil
+		})
+	}
+
+	// subscribe before looking at the stored results so no result stored in between is missed
+	updates, missed, unsubscribe := s.subscribeCashoutResults(resultSubscriptionBuffer + len(waiting))
+	defer unsubscribe()
+
+	err := scan()
+	if err != nil {
+		return nil, err
+	}
+
+	for len(waiting) > 0 {
+		select {
+		case result := <-updates:
+			collect(result)
+		case <-missed:
+			err = scan()
+			if err != nil {
+				return results, err
+			}
+		case <-ctx.Done():
+			return results, ctx.Err()
+		}
+	}
+	return results, nil
+}
+
+// flusher is implemented by buffered writers such as bufio.Writer
+type flusher interface {
+	Flush() error
+}
+
+// TailCashoutResults writes every new cashout result as a JSON line to w until ctx is cancelled.
+// If w is buffered it is flushed after each line.
+func (s *cashoutService) TailCashoutResults(ctx context.Context, w io.Writer) error {
+	results, unsubscribe := s.SubscribeCashoutResults()
+	defer unsubscribe()
+
+	encoder := json.NewEncoder(w)
+	for {
+		select {
+		case result := <-results:
+			err := encoder.Encode(&result)
+			if err != nil {
+				return err
+			}
+			if f, ok := w.(flusher); ok {
+				err = f.Flush()
+				if err != nil {
+					return err
+				}
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
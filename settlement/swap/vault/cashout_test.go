@@ -1,19 +1,43 @@
 package vault_test
 
 import (
+	"bytes"
 	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
 	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	conabi "github.com/bittorrent/go-btfs/chain/abi"
 	chequestoremock "github.com/bittorrent/go-btfs/settlement/swap/chequestore/mock"
 	"github.com/bittorrent/go-btfs/settlement/swap/vault"
+	"github.com/bittorrent/go-btfs/settlement/swap/vault/internal/faultinject"
+	"github.com/bittorrent/go-btfs/statestore"
+	leveldbstore "github.com/bittorrent/go-btfs/statestore/leveldb"
 	storemock "github.com/bittorrent/go-btfs/statestore/mock"
 	"github.com/bittorrent/go-btfs/transaction"
 	"github.com/bittorrent/go-btfs/transaction/backendmock"
+	"github.com/bittorrent/go-btfs/transaction/crypto"
 	transactionmock "github.com/bittorrent/go-btfs/transaction/mock"
+	"github.com/bittorrent/go-btfs/transaction/sctx"
+	"github.com/bittorrent/go-btfs/transaction/storage"
+	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 )
 
 var (
@@ -113,6 +137,8 @@ func TestCashout(t *testing.T) {
 			Reverted: false,
 		},
 		UncashedAmount: big.NewInt(0),
+		State:          vault.CashoutStateConfirmed,
+		Settled:        true,
 	})
 }
 
@@ -211,6 +237,7 @@ func TestCashoutBounced(t *testing.T) {
 			Reverted: false,
 		},
 		UncashedAmount: big.NewInt(0),
+		State:          vault.CashoutStateConfirmed,
 	})
 }
 
@@ -285,6 +312,7 @@ func TestCashoutStatusReverted(t *testing.T) {
 			Cheque:   *cheque,
 		},
 		UncashedAmount: new(big.Int).Sub(cheque.CumulativePayout, onChainPaidOut),
+		State:          vault.CashoutStateReverted,
 	})
 }
 
@@ -349,37 +377,8134 @@ func TestCashoutStatusPending(t *testing.T) {
 			Result:   nil,
 		},
 		UncashedAmount: big.NewInt(0),
+		State:          vault.CashoutStatePending,
 	})
 
 }
 
+func TestCashoutStatusStaleCheque(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	txHash := common.HexToHash("dddd")
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: big.NewInt(500),
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+	staleCheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: big.NewInt(400),
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	lastCheque := cheque
+	cashoutService := vault.NewCashoutService(
+		storemock.NewStateStore(),
+		backendmock.New(
+			backendmock.WithTransactionByHashFunc(func(ctx context.Context, hash common.Hash) (tx *types.Transaction, isPending bool, err error) {
+				return nil, true, nil
+			}),
+		),
+		transactionmock.New(
+			transactionmock.WithABISend(&vaultABI, txHash, vaultAddress, big.NewInt(0), "cashChequeBeneficiary", recipientAddress, cheque.CumulativePayout, cheque.Signature),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return lastCheque, nil
+			}),
+		),
+	)
+
+	_, err := cashoutService.CashCheque(context.Background(), vaultAddress, recipientAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the cheque store now returns a cheque older than the one cashed
+	lastCheque = staleCheque
+
+	status, err := cashoutService.CashoutStatus(context.Background(), vaultAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verifyStatus(t, status, vault.CashoutStatus{
+		Last: &vault.LastCashout{
+			Reverted: false,
+			TxHash:   txHash,
+			Cheque:   *cheque,
+			Result:   nil,
+		},
+		UncashedAmount: big.NewInt(0),
+		State:          vault.CashoutStatePending,
+	})
+}
+
+func TestCashoutErrorPhase(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+
+	cashoutService := vault.NewCashoutService(
+		storemock.NewStateStore(),
+		backendmock.New(),
+		transactionmock.New(),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return nil, vault.ErrNoCheque
+			}),
+		),
+	)
+
+	_, err := cashoutService.CashCheque(context.Background(), vaultAddress, recipientAddress)
+	if !errors.Is(err, vault.ErrNoCheque) {
+		t.Fatalf("wrong error. wanted %v, got %v", vault.ErrNoCheque, err)
+	}
+
+	var cashoutErr *vault.CashoutError
+	if !errors.As(err, &cashoutErr) {
+		t.Fatalf("expected cashout error, got %v", err)
+	}
+	if cashoutErr.Phase != vault.CashoutPhaseLookup {
+		t.Fatalf("wrong phase. wanted %s, got %s", vault.CashoutPhaseLookup, cashoutErr.Phase)
+	}
+}
+
+func TestCashoutRecipientPolicy(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	coldWallet := common.HexToAddress("cccc")
+	txHash := common.HexToHash("dddd")
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: big.NewInt(500),
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	cashoutService := vault.NewCashoutService(
+		storemock.NewStateStore(),
+		backendmock.New(),
+		transactionmock.New(
+			transactionmock.WithABISend(&vaultABI, txHash, vaultAddress, big.NewInt(0), "cashChequeBeneficiary", coldWallet, cheque.CumulativePayout, cheque.Signature),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheque, nil
+			}),
+		),
+		vault.WithRecipientPolicy(func(v, requested common.Address) (common.Address, error) {
+			if requested != recipientAddress {
+				t.Fatalf("wrong requested recipient. wanted %v, got %v", recipientAddress, requested)
+			}
+			return coldWallet, nil
+		}),
+	)
+
+	returnedTxHash, err := cashoutService.CashCheque(context.Background(), vaultAddress, recipientAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if returnedTxHash != txHash {
+		t.Fatalf("returned wrong transaction hash. wanted %v, got %v", txHash, returnedTxHash)
+	}
+}
+
+func TestTotalReceivedCashedByToken(t *testing.T) {
+	tokenA := common.HexToAddress("1111")
+	tokenB := common.HexToAddress("2222")
+
+	store := storemock.NewStateStore()
+	if err := store.Put(statestore.TotalReceivedCashedByTokenKey(tokenA), big.NewInt(100)); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put(statestore.TotalReceivedCashedByTokenKey(tokenB), big.NewInt(200)); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put(statestore.GetTodayTotalDailyReceivedCashedByTokenKey(tokenB), big.NewInt(50)); err != nil {
+		t.Fatal(err)
+	}
+
+	cashoutService := vault.NewCashoutService(store, backendmock.New(), transactionmock.New(), chequestoremock.NewChequeStore())
+
+	totals, err := cashoutService.TotalReceivedCashedByToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(totals) != 2 || totals[tokenA].Cmp(big.NewInt(100)) != 0 || totals[tokenB].Cmp(big.NewInt(200)) != 0 {
+		t.Fatalf("wrong totals by token: %v", totals)
+	}
+
+	daily, err := cashoutService.DailyReceivedCashedByToken(time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(daily) != 1 || daily[tokenB].Cmp(big.NewInt(50)) != 0 {
+		t.Fatalf("wrong daily totals by token: %v", daily)
+	}
+}
+
+func TestAutoCashoutLoop(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	txHash := common.HexToHash("dddd")
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: big.NewInt(500),
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	sent := make(chan struct{}, 1)
+	cashoutService := vault.NewCashoutService(
+		storemock.NewStateStore(),
+		backendmock.New(),
+		transactionmock.New(
+			transactionmock.WithCallFunc(func(ctx context.Context, request *transaction.TxRequest) ([]byte, error) {
+				return big.NewInt(0).FillBytes(make([]byte, 32)), nil
+			}),
+			transactionmock.WithSendFunc(func(ctx context.Context, request *transaction.TxRequest) (common.Hash, error) {
+				select {
+				case sent <- struct{}{}:
+				default:
+				}
+				return txHash, nil
+			}),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheque, nil
+			}),
+			chequestoremock.WithLastChequesFunc(func() (map[common.Address]*vault.SignedCheque, error) {
+				return map[common.Address]*vault.SignedCheque{vaultAddress: cheque}, nil
+			}),
+		),
+		vault.WithAutoCashout(vault.AutoCashoutConfig{
+			Recipient:    recipientAddress,
+			LoopInterval: 10 * time.Millisecond,
+			LoopJitter:   10 * time.Millisecond,
+			MinAmount:    big.NewInt(100),
+		}),
+	)
+	cashoutService.Start()
+	defer cashoutService.Close()
+
+	select {
+	case <-sent:
+	case <-time.After(time.Second):
+		t.Fatal("auto cashout did not cash the cheque")
+	}
+}
+
+func TestAutoCashoutExclusions(t *testing.T) {
+	excludedVault := common.HexToAddress("abcd")
+	includedVault := common.HexToAddress("bcde")
+
+	cheques := make(map[common.Address]*vault.SignedCheque)
+	for _, v := range []common.Address{excludedVault, includedVault} {
+		cheques[v] = &vault.SignedCheque{
+			Cheque: vault.Cheque{
+				Beneficiary:      common.HexToAddress("aaaa"),
+				CumulativePayout: big.NewInt(500),
+				Vault:            v,
+			},
+			Signature: []byte{},
+		}
+	}
+
+	store := storemock.NewStateStore()
+	sent := make(chan common.Address, 10)
+	newService := func() vault.CashoutService {
+		return vault.NewCashoutService(
+			store,
+			backendmock.New(),
+			transactionmock.New(
+				transactionmock.WithCallFunc(func(ctx context.Context, request *transaction.TxRequest) ([]byte, error) {
+					return big.NewInt(0).FillBytes(make([]byte, 32)), nil
+				}),
+				transactionmock.WithSendFunc(func(ctx context.Context, request *transaction.TxRequest) (common.Hash, error) {
+					sent <- *request.To
+					return common.BytesToHash(request.To.Bytes()), nil
+				}),
+			),
+			chequestoremock.NewChequeStore(
+				chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+					return cheques[c], nil
+				}),
+				chequestoremock.WithLastChequesFunc(func() (map[common.Address]*vault.SignedCheque, error) {
+					return cheques, nil
+				}),
+			),
+			vault.WithAutoCashout(vault.AutoCashoutConfig{
+				Recipient:    common.HexToAddress("efff"),
+				LoopInterval: 10 * time.Millisecond,
+				MinAmount:    big.NewInt(100),
+			}),
+		)
+	}
+
+	err := newService().ExcludeVaultFromAutoCashout(excludedVault)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the exclusion is persisted and picked up by a new service
+	cashoutService := newService()
+	exclusions, err := cashoutService.AutoCashoutExclusions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exclusions) != 1 || exclusions[0] != excludedVault {
+		t.Fatalf("wrong exclusions %v", exclusions)
+	}
+
+	cashoutService.Start()
+	select {
+	case v := <-sent:
+		if v != includedVault {
+			t.Fatalf("cashed out excluded vault %x", v)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("auto cashout did not cash the included vault")
+	}
+	// the included vault is pending now, so nothing else may be sent
+	select {
+	case v := <-sent:
+		t.Fatalf("unexpected cashout of vault %x", v)
+	case <-time.After(50 * time.Millisecond):
+	}
+	cashoutService.Close()
+
+	err = cashoutService.IncludeVaultInAutoCashout(excludedVault)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exclusions, err = cashoutService.AutoCashoutExclusions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exclusions) != 0 {
+		t.Fatalf("exclusion not removed %v", exclusions)
+	}
+}
+
+type gasOracleFunc func(ctx context.Context) (*big.Int, error)
+
+func (f gasOracleFunc) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	return f(ctx)
+}
+
+func TestAutoCashoutLoopGasCeiling(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	txHash := common.HexToHash("dddd")
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: big.NewInt(500),
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	var gasPrice int64 = 200
+	var lock sync.Mutex
+	sent := make(chan int64, 1)
+	cashoutService := vault.NewCashoutService(
+		storemock.NewStateStore(),
+		backendmock.New(),
+		transactionmock.New(
+			transactionmock.WithCallFunc(func(ctx context.Context, request *transaction.TxRequest) ([]byte, error) {
+				return big.NewInt(0).FillBytes(make([]byte, 32)), nil
+			}),
+			transactionmock.WithSendFunc(func(ctx context.Context, request *transaction.TxRequest) (common.Hash, error) {
+				lock.Lock()
+				defer lock.Unlock()
+				select {
+				case sent <- gasPrice:
+				default:
+				}
+				return txHash, nil
+			}),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheque, nil
+			}),
+			chequestoremock.WithLastChequesFunc(func() (map[common.Address]*vault.SignedCheque, error) {
+				return map[common.Address]*vault.SignedCheque{vaultAddress: cheque}, nil
+			}),
+		),
+		vault.WithGasOracle(gasOracleFunc(func(ctx context.Context) (*big.Int, error) {
+			lock.Lock()
+			defer lock.Unlock()
+			return big.NewInt(gasPrice), nil
+		})),
+		vault.WithAutoCashout(vault.AutoCashoutConfig{
+			Recipient:    common.HexToAddress("efff"),
+			LoopInterval: 10 * time.Millisecond,
+			MinAmount:    big.NewInt(100),
+			MaxGasPrice:  big.NewInt(100),
+		}),
+	)
+	cashoutService.Start()
+	defer cashoutService.Close()
+
+	select {
+	case <-sent:
+		t.Fatal("auto cashout sent while gas price was above the ceiling")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	lock.Lock()
+	gasPrice = 50
+	lock.Unlock()
+
+	select {
+	case price := <-sent:
+		if price != 50 {
+			t.Fatalf("sent at gas price %d, wanted 50", price)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("auto cashout did not resume once gas price dropped")
+	}
+}
+
+func TestCashoutStatusFullyCashedExternally(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	beneficiary := common.HexToAddress("aaaa")
+	cumulativePayout := big.NewInt(500)
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      beneficiary,
+			CumulativePayout: cumulativePayout,
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	cashoutService := vault.NewCashoutService(
+		storemock.NewStateStore(),
+		backendmock.New(),
+		transactionmock.New(
+			transactionmock.WithABICall(&vaultABI, vaultAddress, cumulativePayout.FillBytes(make([]byte, 32)), "paidOut", beneficiary),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheque, nil
+			}),
+		),
+	)
+
+	status, err := cashoutService.CashoutStatus(context.Background(), vaultAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verifyStatus(t, status, vault.CashoutStatus{
+		UncashedAmount: big.NewInt(0),
+		State:          vault.CashoutStateFullyCashedExternally,
+	})
+}
+
+func TestCashoutStatusChainDerived(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+
+	// the balance of the vault is the collateral of the issuer, without cheque records none of it is owed to us
+	cashoutService := vault.NewCashoutService(
+		storemock.NewStateStore(),
+		backendmock.New(),
+		transactionmock.New(
+			transactionmock.WithABICall(&vaultABI, vaultAddress, big.NewInt(300).FillBytes(make([]byte, 32)), "totalbalance"),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return nil, vault.ErrNoCheque
+			}),
+		),
+	)
+
+	status, err := cashoutService.CashoutStatus(context.Background(), vaultAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !status.ChainDerived {
+		t.Fatal("status without cheque not flagged as chain derived")
+	}
+	verifyStatus(t, status, vault.CashoutStatus{
+		UncashedAmount: big.NewInt(0),
+		State:          vault.CashoutStateNeverCashed,
+	})
+}
+
+func TestCashoutStatusDropped(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	txHash := common.HexToHash("dddd")
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: big.NewInt(500),
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	var lock sync.Mutex
+	now := time.Unix(1000, 0)
+	store := storemock.NewStateStore()
+	cashoutService := vault.NewCashoutService(
+		store,
+		backendmock.New(
+			backendmock.WithTransactionByHashFunc(func(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+				return nil, false, ethereum.NotFound
+			}),
+		),
+		transactionmock.New(
+			transactionmock.WithCallFunc(func(ctx context.Context, request *transaction.TxRequest) ([]byte, error) {
+				return big.NewInt(0).FillBytes(make([]byte, 32)), nil
+			}),
+			transactionmock.WithSendFunc(func(ctx context.Context, request *transaction.TxRequest) (common.Hash, error) {
+				return txHash, nil
+			}),
+			// the transaction is never mined
+			transactionmock.WithWaitForReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			}),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheque, nil
+			}),
+		),
+		vault.WithClock(func() time.Time {
+			lock.Lock()
+			defer lock.Unlock()
+			return now
+		}),
+		vault.WithPendingStaleAfter(time.Hour),
+	)
+
+	tracked := make(chan error, 1)
+	_, err := cashoutService.CashChequeWithCallback(context.Background(), vaultAddress, recipientAddress, func(result *vault.CashOutResult, err error) {
+		tracked <- err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status, err := cashoutService.CashoutStatus(context.Background(), vaultAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.State != vault.CashoutStatePending {
+		t.Fatalf("wrong state. wanted %v, got %v", vault.CashoutStatePending, status.State)
+	}
+
+	lock.Lock()
+	now = now.Add(2 * time.Hour)
+	lock.Unlock()
+
+	status, err = cashoutService.CashoutStatus(context.Background(), vaultAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.State != vault.CashoutStateDropped {
+		t.Fatalf("wrong state. wanted %v, got %v", vault.CashoutStateDropped, status.State)
+	}
+	if status.UncashedAmount.Cmp(cheque.CumulativePayout) != 0 {
+		t.Fatalf("wrong uncashed amount. wanted %d, got %d", cheque.CumulativePayout, status.UncashedAmount)
+	}
+
+	hasAction, err := cashoutService.HasCashoutAction(context.Background(), vaultAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hasAction {
+		t.Fatal("dropped cashout action was not cleared")
+	}
+
+	// the tracker of the dropped transaction is stopped without storing a result
+	select {
+	case err := <-tracked:
+		if !errors.Is(err, vault.ErrCashoutDropped) {
+			t.Fatalf("wrong error. wanted %v, got %v", vault.ErrCashoutDropped, err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("tracking of the dropped cashout was not stopped")
+	}
+	results, err := cashoutService.CashoutResults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 0 {
+		t.Fatalf("stored results %+v for the dropped cashout", results)
+	}
+
+	// the write-ahead log record went with the action, so a restart does not restore it
+	err = store.Iterate("swap_cashout_wal_", func(key, val []byte) (bool, error) {
+		t.Fatalf("write-ahead log record %s of the dropped cashout was kept", key)
+		return true, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCashoutReplayIntents(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	txHash := common.HexToHash("dddd")
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: big.NewInt(500),
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+	chequeStore := chequestoremock.NewChequeStore(
+		chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+			return cheque, nil
+		}),
+	)
+
+	// the first service never finishes waiting for the receipt, simulating a crash before the result is stored
+	block := make(chan struct{})
+	defer close(block)
+	store := storemock.NewStateStore()
+	cashoutService := vault.NewCashoutService(
+		store,
+		backendmock.New(),
+		transactionmock.New(
+			transactionmock.WithABISend(&vaultABI, txHash, vaultAddress, big.NewInt(0), "cashChequeBeneficiary", recipientAddress, cheque.CumulativePayout, cheque.Signature),
+			transactionmock.WithWaitForReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				<-block
+				return nil, context.Canceled
+			}),
+		),
+		chequeStore,
+		vault.WithClock(func() time.Time { return now }),
+	)
+
+	_, err := cashoutService.CashCheque(context.Background(), vaultAddress, recipientAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the intent is stamped with the clock of the service
+	var intent struct{ TxHash common.Hash }
+	err = store.Get(vault.CashoutIntentKey(vaultAddress, now.UnixNano()), &intent)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the stored action still points at an earlier cashout
+	err = store.Put(vault.CashoutActionKey(vaultAddress), &vault.CashoutAction{
+		TxHash:    common.HexToHash("eeee"),
+		Submitted: now.Add(-time.Hour).UnixNano(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	waited := make(chan common.Hash, 1)
+	restarted := vault.NewCashoutService(
+		store,
+		backendmock.New(),
+		transactionmock.New(
+			transactionmock.WithWaitForReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				waited <- hash
+				return nil, context.Canceled
+			}),
+		),
+		chequeStore,
+	)
+	restarted.Start()
+	defer restarted.Close()
+
+	select {
+	case hash := <-waited:
+		if hash != txHash {
+			t.Fatalf("resumed wrong transaction. wanted %v, got %v", txHash, hash)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("cashout was not resumed")
+	}
+
+	var action vault.CashoutAction
+	err = store.Get(vault.CashoutActionKey(vaultAddress), &action)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if action.TxHash != txHash {
+		t.Fatalf("stale action was not replaced. wanted %v, got %v", txHash, action.TxHash)
+	}
+}
+
+func TestCashoutDailyGasBudget(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	txHash := common.HexToHash("dddd")
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: big.NewInt(500),
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	store := storemock.NewStateStore()
+	err := store.Put(statestore.GetTotalDailyCashoutGasKeyByTime(time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC).Unix()), big.NewInt(1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cashoutService := vault.NewCashoutService(
+		store,
+		backendmock.New(),
+		transactionmock.New(
+			transactionmock.WithABISend(&vaultABI, txHash, vaultAddress, big.NewInt(0), "cashChequeBeneficiary", recipientAddress, cheque.CumulativePayout, cheque.Signature),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheque, nil
+			}),
+		),
+		vault.WithDailyGasBudget(big.NewInt(1000)),
+		vault.WithClock(func() time.Time { return now }),
+	)
+
+	_, err = cashoutService.CashCheque(context.Background(), vaultAddress, recipientAddress)
+	if !errors.Is(err, vault.ErrDailyBudgetExceeded) {
+		t.Fatalf("wrong error. wanted %v, got %v", vault.ErrDailyBudgetExceeded, err)
+	}
+
+	// the budget resets on the next day
+	now = now.Add(24 * time.Hour)
+	_, err = cashoutService.CashCheque(context.Background(), vaultAddress, recipientAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCashoutGasSpentDay(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	txHash := common.HexToHash("dddd")
+	// the daily keys are the local date at midnight UTC
+	day := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: big.NewInt(500),
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	var mu sync.Mutex
+	now := time.Date(2021, 6, 1, 23, 59, 0, 0, time.Local)
+	clock := func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	store := storemock.NewStateStore()
+	cashoutService := vault.NewCashoutService(
+		store,
+		backendmock.New(),
+		transactionmock.New(
+			transactionmock.WithABISend(&vaultABI, txHash, vaultAddress, big.NewInt(0), "cashChequeBeneficiary", recipientAddress, cheque.CumulativePayout, cheque.Signature),
+			transactionmock.WithStoredTransactionFunc(func(hash common.Hash) (*transaction.StoredTransaction, error) {
+				return &transaction.StoredTransaction{GasPrice: big.NewInt(10)}, nil
+			}),
+			// the transaction is mined after midnight
+			transactionmock.WithWaitForReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				mu.Lock()
+				now = now.Add(2 * time.Minute)
+				mu.Unlock()
+				return &types.Receipt{TxHash: hash, Status: types.ReceiptStatusFailed, GasUsed: 100}, nil
+			}),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheque, nil
+			}),
+		),
+		vault.WithClock(clock),
+	)
+
+	done := make(chan *vault.CashOutResult, 1)
+	_, err := cashoutService.CashChequeWithCallback(context.Background(), vaultAddress, recipientAddress, func(result *vault.CashOutResult, err error) {
+		done <- result
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result *vault.CashOutResult
+	select {
+	case result = <-done:
+	case <-time.After(time.Second):
+		t.Fatal("cashout result not stored")
+	}
+	if result == nil || time.Unix(result.CashTime, 0).Day() != day.Day() {
+		t.Fatalf("unexpected result %+v", result)
+	}
+
+	// the gas counts on the day of the cashout like its other totals
+	var spent *big.Int
+	err = store.Get(statestore.GetTotalDailyCashoutGasKeyByTime(day.Unix()), &spent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spent.Cmp(big.NewInt(1000)) != 0 {
+		t.Fatalf("wrong gas spent. wanted 1000, got %d", spent)
+	}
+	err = store.Get(statestore.GetTotalDailyCashoutGasKeyByTime(day.Add(24*time.Hour).Unix()), &spent)
+	if !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("gas spent recorded on the next day: %v", err)
+	}
+}
+
+func TestCashedVaults(t *testing.T) {
+	vaultA := common.HexToAddress("abcd")
+	vaultB := common.HexToAddress("bcde")
+
+	store := storemock.NewStateStore()
+	if err := store.Put(vault.CashoutActionKey(vaultA), struct{}{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put(statestore.CashoutResultKey(vaultB), &vault.CashOutResult{Vault: vaultB}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put(statestore.CashoutResultKey(vaultA), &vault.CashOutResult{Vault: vaultA}); err != nil {
+		t.Fatal(err)
+	}
+
+	cashoutService := vault.NewCashoutService(store, backendmock.New(), transactionmock.New(), chequestoremock.NewChequeStore())
+
+	vaults, err := cashoutService.CashedVaults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vaults) != 2 || vaults[0] != vaultA || vaults[1] != vaultB {
+		t.Fatalf("wrong cashed vaults. wanted %v, got %v", []common.Address{vaultA, vaultB}, vaults)
+	}
+}
+
+func TestCashoutBeneficiaryMismatch(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: big.NewInt(500),
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	cashoutService := vault.NewCashoutService(
+		storemock.NewStateStore(),
+		backendmock.New(),
+		transactionmock.New(),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheque, nil
+			}),
+		),
+		vault.WithBeneficiary(common.HexToAddress("bbbb")),
+	)
+
+	_, err := cashoutService.CashCheque(context.Background(), vaultAddress, recipientAddress)
+	if !errors.Is(err, vault.ErrBeneficiaryMismatch) {
+		t.Fatalf("wrong error. wanted %v, got %v", vault.ErrBeneficiaryMismatch, err)
+	}
+}
+
+func TestCashoutAllowArbitraryBeneficiary(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	txHash := common.HexToHash("dddd")
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: big.NewInt(500),
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	cashoutService := vault.NewCashoutService(
+		storemock.NewStateStore(),
+		backendmock.New(),
+		transactionmock.New(
+			transactionmock.WithABISend(&vaultABI, txHash, vaultAddress, big.NewInt(0), "cashChequeBeneficiary", recipientAddress, cheque.CumulativePayout, cheque.Signature),
+			transactionmock.WithWaitForReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				return nil, context.Canceled
+			}),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheque, nil
+			}),
+		),
+		vault.WithBeneficiary(common.HexToAddress("bbbb")),
+		vault.WithAllowArbitraryBeneficiary(true),
+	)
+	defer cashoutService.Close()
+
+	returnedTxHash, err := cashoutService.CashCheque(context.Background(), vaultAddress, recipientAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if returnedTxHash != txHash {
+		t.Fatalf("returned wrong transaction hash. wanted %v, got %v", txHash, returnedTxHash)
+	}
+}
+
+func TestCashoutMaxInFlight(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	txHash := common.HexToHash("dddd")
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: big.NewInt(500),
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	block := make(chan struct{})
+	defer close(block)
+	cashoutService := vault.NewCashoutService(
+		storemock.NewStateStore(),
+		backendmock.New(),
+		transactionmock.New(
+			transactionmock.WithABISend(&vaultABI, txHash, vaultAddress, big.NewInt(0), "cashChequeBeneficiary", recipientAddress, cheque.CumulativePayout, cheque.Signature),
+			transactionmock.WithWaitForReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				<-block
+				return nil, context.Canceled
+			}),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheque, nil
+			}),
+		),
+		vault.WithMaxInFlightCashouts(1),
+	)
+
+	_, err := cashoutService.CashCheque(context.Background(), vaultAddress, recipientAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = cashoutService.CashCheque(context.Background(), vaultAddress, recipientAddress)
+	if !errors.Is(err, vault.ErrTooManyInFlight) {
+		t.Fatalf("wrong error. wanted %v, got %v", vault.ErrTooManyInFlight, err)
+	}
+}
+
+func TestCashAndWithdrawBounced(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	txHash := common.HexToHash("dddd")
+	totalPayout := big.NewInt(100)
+	cumulativePayout := big.NewInt(500)
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: cumulativePayout,
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	sends := 0
+	cashoutService := vault.NewCashoutService(
+		storemock.NewStateStore(),
+		backendmock.New(),
+		transactionmock.New(
+			transactionmock.WithSendFunc(func(ctx context.Context, request *transaction.TxRequest) (common.Hash, error) {
+				sends++
+				return txHash, nil
+			}),
+			transactionmock.WithWaitForReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				logData, err := chequeCashedEventType.Inputs.NonIndexed().Pack(totalPayout, cumulativePayout, big.NewInt(0))
+				if err != nil {
+					t.Fatal(err)
+				}
+				return &types.Receipt{
+					Status: types.ReceiptStatusSuccessful,
+					Logs: []*types.Log{
+						{
+							Address: vaultAddress,
+							Topics:  []common.Hash{chequeCashedEventType.ID, cheque.Beneficiary.Hash(), recipientAddress.Hash(), cheque.Beneficiary.Hash()},
+							Data:    logData,
+						},
+						{
+							Address: vaultAddress,
+							Topics:  []common.Hash{chequeBouncedEventType.ID},
+						},
+					},
+				}, nil
+			}),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheque, nil
+			}),
+		),
+	)
+
+	result, err := cashoutService.CashAndWithdraw(context.Background(), vaultAddress, recipientAddress, common.Address{})
+	if !errors.Is(err, vault.ErrCashoutBounced) {
+		t.Fatalf("wrong error. wanted %v, got %v", vault.ErrCashoutBounced, err)
+	}
+	if result.CashoutTxHash != txHash {
+		t.Fatalf("wrong cashout transaction. wanted %v, got %v", txHash, result.CashoutTxHash)
+	}
+	if sends != 1 {
+		t.Fatalf("expected only the cashout to be sent, got %d transactions", sends)
+	}
+}
+
+func TestCashAndWithdrawRecipientPolicy(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	coldWallet := common.HexToAddress("cccc")
+	txHash := common.HexToHash("dddd")
+	withdrawTxHash := common.HexToHash("eeee")
+	totalPayout := big.NewInt(100)
+	cumulativePayout := big.NewInt(500)
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: cumulativePayout,
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	var mu sync.Mutex
+	var requests []*transaction.TxRequest
+	cashoutService := vault.NewCashoutService(
+		storemock.NewStateStore(),
+		backendmock.New(),
+		transactionmock.New(
+			transactionmock.WithSendFunc(func(ctx context.Context, request *transaction.TxRequest) (common.Hash, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				requests = append(requests, request)
+				if len(requests) == 1 {
+					return txHash, nil
+				}
+				return withdrawTxHash, nil
+			}),
+			transactionmock.WithWaitForReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				logData, err := chequeCashedEventType.Inputs.NonIndexed().Pack(totalPayout, cumulativePayout, big.NewInt(0))
+				if err != nil {
+					t.Fatal(err)
+				}
+				return &types.Receipt{
+					Status: types.ReceiptStatusSuccessful,
+					Logs: []*types.Log{
+						{
+							Address: vaultAddress,
+							Topics:  []common.Hash{chequeCashedEventType.ID, cheque.Beneficiary.Hash(), coldWallet.Hash(), cheque.Beneficiary.Hash()},
+							Data:    logData,
+						},
+					},
+				}, nil
+			}),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheque, nil
+			}),
+		),
+		vault.WithRecipientPolicy(func(v, requested common.Address) (common.Address, error) {
+			return coldWallet, nil
+		}),
+	)
+
+	result, err := cashoutService.CashAndWithdraw(context.Background(), vaultAddress, recipientAddress, common.Address{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.WithdrawTxHash != withdrawTxHash {
+		t.Fatalf("wrong withdraw transaction. wanted %v, got %v", withdrawTxHash, result.WithdrawTxHash)
+	}
+	if result.Amount.Cmp(totalPayout) != 0 {
+		t.Fatalf("wrong withdrawn amount. wanted %d, got %d", totalPayout, result.Amount)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(requests) != 2 {
+		t.Fatalf("expected the cashout and the withdrawal to be sent, got %d transactions", len(requests))
+	}
+	if *requests[1].To != coldWallet {
+		t.Fatalf("withdrawal sent to wrong vault. wanted %v, got %v", coldWallet, *requests[1].To)
+	}
+}
+
+func TestCashChequeSplit(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	beneficiary := common.HexToAddress("aaaa")
+	tokenAddress := common.HexToAddress("eeee")
+	txHash := common.HexToHash("dddd")
+	totalPayout := big.NewInt(1001)
+	cumulativePayout := big.NewInt(1001)
+	erc20ABI := transaction.ParseABIUnchecked(conabi.Erc20ABI)
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      beneficiary,
+			CumulativePayout: cumulativePayout,
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	splits := []vault.RecipientSplit{
+		{Recipient: common.HexToAddress("1111"), BasisPoints: 7000},
+		{Recipient: common.HexToAddress("2222"), BasisPoints: 3000},
+	}
+	wantAmounts := []*big.Int{big.NewInt(700), big.NewInt(301)}
+
+	var lock sync.Mutex
+	var transfers [][]byte
+	cashoutService := vault.NewCashoutService(
+		storemock.NewStateStore(),
+		backendmock.New(),
+		transactionmock.New(
+			transactionmock.WithCallFunc(func(ctx context.Context, request *transaction.TxRequest) ([]byte, error) {
+				return tokenAddress.Hash().Bytes(), nil
+			}),
+			transactionmock.WithSendFunc(func(ctx context.Context, request *transaction.TxRequest) (common.Hash, error) {
+				lock.Lock()
+				defer lock.Unlock()
+				if *request.To == tokenAddress {
+					transfers = append(transfers, request.Data)
+					return common.BigToHash(big.NewInt(int64(len(transfers)))), nil
+				}
+				return txHash, nil
+			}),
+			transactionmock.WithWaitForReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				logData, err := chequeCashedEventType.Inputs.NonIndexed().Pack(totalPayout, cumulativePayout, big.NewInt(0))
+				if err != nil {
+					t.Fatal(err)
+				}
+				return &types.Receipt{
+					Status: types.ReceiptStatusSuccessful,
+					Logs: []*types.Log{
+						{
+							Address: vaultAddress,
+							Topics:  []common.Hash{chequeCashedEventType.ID, beneficiary.Hash(), beneficiary.Hash(), beneficiary.Hash()},
+							Data:    logData,
+						},
+					},
+				}, nil
+			}),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheque, nil
+			}),
+		),
+		vault.WithBeneficiary(beneficiary),
+	)
+
+	_, err := cashoutService.CashChequeSplit(context.Background(), vaultAddress, []vault.RecipientSplit{
+		{Recipient: common.HexToAddress("1111"), BasisPoints: 7000},
+	})
+	if !errors.Is(err, vault.ErrInvalidSplit) {
+		t.Fatalf("wrong error. wanted %v, got %v", vault.ErrInvalidSplit, err)
+	}
+
+	result, err := cashoutService.CashChequeSplit(context.Background(), vaultAddress, splits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.CashoutTxHash != txHash {
+		t.Fatalf("wrong cashout transaction. wanted %v, got %v", txHash, result.CashoutTxHash)
+	}
+	if result.Amount.Cmp(totalPayout) != 0 {
+		t.Fatalf("wrong amount. wanted %d, got %d", totalPayout, result.Amount)
+	}
+	if len(result.Transfers) != len(splits) {
+		t.Fatalf("wrong number of transfers. wanted %d, got %d", len(splits), len(result.Transfers))
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	for i, transfer := range result.Transfers {
+		if transfer.Recipient != splits[i].Recipient {
+			t.Fatalf("wrong recipient for split %d. wanted %x, got %x", i, splits[i].Recipient, transfer.Recipient)
+		}
+		if transfer.Amount.Cmp(wantAmounts[i]) != 0 {
+			t.Fatalf("wrong amount for split %d. wanted %d, got %d", i, wantAmounts[i], transfer.Amount)
+		}
+		expectedData, err := erc20ABI.Pack("transfer", splits[i].Recipient, wantAmounts[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(transfers[i]) != string(expectedData) {
+			t.Fatalf("wrong transfer data for split %d", i)
+		}
+	}
+}
+
+func TestCashChequeResultEqualNil(t *testing.T) {
+	populated := &vault.CashChequeResult{
+		TotalPayout:      big.NewInt(100),
+		CumulativePayout: big.NewInt(500),
+		CallerPayout:     big.NewInt(0),
+	}
+
+	if (&vault.CashChequeResult{}).Equal(populated) {
+		t.Fatal("zero value result equal to populated result")
+	}
+	if populated.Equal(&vault.CashChequeResult{}) {
+		t.Fatal("populated result equal to zero value result")
+	}
+	if !(&vault.CashChequeResult{}).Equal(&vault.CashChequeResult{}) {
+		t.Fatal("zero value results not equal")
+	}
+}
+
+func TestSubscribeCashoutResults(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	txHash := common.HexToHash("dddd")
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: big.NewInt(500),
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	cashoutService := vault.NewCashoutService(
+		storemock.NewStateStore(),
+		backendmock.New(),
+		transactionmock.New(
+			transactionmock.WithABISend(&vaultABI, txHash, vaultAddress, big.NewInt(0), "cashChequeBeneficiary", recipientAddress, cheque.CumulativePayout, cheque.Signature),
+			transactionmock.WithWaitForReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				return nil, context.DeadlineExceeded
+			}),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheque, nil
+			}),
+		),
+	)
+
+	results, unsubscribe := cashoutService.SubscribeCashoutResults()
+	defer unsubscribe()
+
+	_, err := cashoutService.CashCheque(context.Background(), vaultAddress, recipientAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case result := <-results:
+		if result.TxHash != txHash || result.Vault != vaultAddress || result.Status != "fail" {
+			t.Fatalf("wrong result %+v", result)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no result published")
+	}
+}
+
+func TestCashoutStoreResultBatched(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	beneficiary := common.HexToAddress("aaaa")
+	txHash := common.HexToHash("dddd")
+	totalPayout := big.NewInt(100)
+	cumulativePayout := big.NewInt(500)
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      beneficiary,
+			CumulativePayout: cumulativePayout,
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	logData, err := chequeCashedEventType.Inputs.NonIndexed().Pack(totalPayout, cumulativePayout, big.NewInt(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	receipt := &types.Receipt{
+		Status: types.ReceiptStatusSuccessful,
+		Logs: []*types.Log{
+			{
+				Address: vaultAddress,
+				Topics:  []common.Hash{chequeCashedEventType.ID, beneficiary.Hash(), recipientAddress.Hash(), beneficiary.Hash()},
+				Data:    logData,
+			},
+		},
+	}
+
+	store, err := leveldbstore.NewInMemoryStateStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	cashoutService := vault.NewCashoutService(
+		store,
+		backendmock.New(
+			backendmock.WithTransactionByHashFunc(func(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+				return nil, false, nil
+			}),
+			backendmock.WithTransactionReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				return receipt, nil
+			}),
+		),
+		transactionmock.New(
+			transactionmock.WithCallFunc(func(ctx context.Context, request *transaction.TxRequest) ([]byte, error) {
+				return common.HexToAddress("eeee").Hash().Bytes(), nil
+			}),
+			transactionmock.WithSendFunc(func(ctx context.Context, request *transaction.TxRequest) (common.Hash, error) {
+				return txHash, nil
+			}),
+			transactionmock.WithWaitForReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				return receipt, nil
+			}),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheque, nil
+			}),
+		),
+	)
+
+	results, unsubscribe := cashoutService.SubscribeCashoutResults()
+	defer unsubscribe()
+
+	_, err = cashoutService.CashCheque(context.Background(), vaultAddress, recipientAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case result := <-results:
+		if result.Status != "success" {
+			t.Fatalf("wrong result %+v", result)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no result published")
+	}
+
+	var stored vault.CashOutResult
+	err = store.Get(statestore.CashoutResultKey(vaultAddress), &stored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.TxHash != txHash || stored.Amount.Cmp(totalPayout) != 0 {
+		t.Fatalf("wrong stored result %+v", stored)
+	}
+
+	total := big.NewInt(0)
+	err = store.Get(statestore.TotalReceivedCashedKey, &total)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total.Cmp(totalPayout) != 0 {
+		t.Fatalf("wrong total received cashed. wanted %d, got %d", totalPayout, total)
+	}
+}
+
+func TestCashoutExplicitNonce(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	beneficiary := common.HexToAddress("aaaa")
+	txHash := common.HexToHash("dddd")
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      beneficiary,
+			CumulativePayout: big.NewInt(500),
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	cashoutService := vault.NewCashoutService(
+		storemock.NewStateStore(),
+		backendmock.New(
+			backendmock.WithNonceAtFunc(func(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+				return 5, nil
+			}),
+		),
+		transactionmock.New(
+			transactionmock.WithSendFunc(func(ctx context.Context, request *transaction.TxRequest) (common.Hash, error) {
+				nonce, ok := sctx.GetNonce(ctx)
+				if !ok || nonce != 7 {
+					t.Fatalf("wrong nonce. wanted 7, got %d", nonce)
+				}
+				return txHash, nil
+			}),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheque, nil
+			}),
+		),
+		vault.WithBeneficiary(beneficiary),
+	)
+
+	lowNonce := uint64(4)
+	_, err := cashoutService.CashChequeWithOptions(context.Background(), vaultAddress, recipientAddress, vault.CashoutOptions{Nonce: &lowNonce})
+	if !errors.Is(err, vault.ErrNonceTooLow) {
+		t.Fatalf("wrong error. wanted %v, got %v", vault.ErrNonceTooLow, err)
+	}
+
+	nonce := uint64(7)
+	returnedTxHash, err := cashoutService.CashChequeWithOptions(context.Background(), vaultAddress, recipientAddress, vault.CashoutOptions{Nonce: &nonce})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if returnedTxHash != txHash {
+		t.Fatalf("returned wrong transaction hash. wanted %v, got %v", txHash, returnedTxHash)
+	}
+}
+
+func TestCashoutChequeFreshness(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	txHash := common.HexToHash("dddd")
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: big.NewInt(500),
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+	newerCheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: big.NewInt(700),
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{1},
+	}
+
+	lookups := 0
+	cashoutService := vault.NewCashoutService(
+		storemock.NewStateStore(),
+		backendmock.New(),
+		transactionmock.New(
+			transactionmock.WithABISend(&vaultABI, txHash, vaultAddress, big.NewInt(0), "cashChequeBeneficiary", recipientAddress, newerCheque.CumulativePayout, newerCheque.Signature),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				lookups++
+				if lookups == 1 {
+					return cheque, nil
+				}
+				return newerCheque, nil
+			}),
+		),
+		vault.WithChequeFreshnessCheck(true),
+	)
+
+	_, err := cashoutService.CashCheque(context.Background(), vaultAddress, recipientAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
 func verifyStatus(t *testing.T, status *vault.CashoutStatus, expected vault.CashoutStatus) {
 	if expected.Last == nil {
 		if status.Last != nil {
 			t.Fatal("unexpected last cashout")
 		}
-	} else {
-		if status.Last == nil {
-			t.Fatal("no last cashout")
+	} else {
+		if status.Last == nil {
+			t.Fatal("no last cashout")
+		}
+		if status.Last.Reverted != expected.Last.Reverted {
+			t.Fatalf("wrong reverted value. wanted %v, got %v", expected.Last.Reverted, status.Last.Reverted)
+		}
+		if status.Last.TxHash != expected.Last.TxHash {
+			t.Fatalf("wrong transaction hash. wanted %v, got %v", expected.Last.TxHash, status.Last.TxHash)
+		}
+		if !status.Last.Cheque.Equal(&expected.Last.Cheque) {
+			t.Fatalf("wrong cheque in status. wanted %v, got %v", expected.Last.Cheque, status.Last.Cheque)
+		}
+
+		if expected.Last.Result != nil {
+			if !expected.Last.Result.Equal(status.Last.Result) {
+				t.Fatalf("wrong result. wanted %v, got %v", expected.Last.Result, status.Last.Result)
+			}
+		}
+	}
+
+	if status.UncashedAmount.Cmp(expected.UncashedAmount) != 0 {
+		t.Fatalf("wrong uncashed amount. wanted %d, got %d", expected.UncashedAmount, status.UncashedAmount)
+	}
+
+	if status.State != expected.State {
+		t.Fatalf("wrong state. wanted %v, got %v", expected.State, status.State)
+	}
+
+	if status.Settled != expected.Settled {
+		t.Fatalf("wrong settled value. wanted %v, got %v", expected.Settled, status.Settled)
+	}
+}
+
+func TestCashoutDeadlineCancel(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	txHash := common.HexToHash("dddd")
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: big.NewInt(500),
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	cancelled := make(chan common.Hash, 1)
+	cashoutService := vault.NewCashoutService(
+		storemock.NewStateStore(),
+		backendmock.New(
+			backendmock.WithTransactionReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				return nil, ethereum.NotFound
+			}),
+		),
+		transactionmock.New(
+			transactionmock.WithSendFunc(func(ctx context.Context, request *transaction.TxRequest) (common.Hash, error) {
+				return txHash, nil
+			}),
+			transactionmock.WithWaitForReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				return nil, context.DeadlineExceeded
+			}),
+			transactionmock.WithCancelTransactionFunc(func(ctx context.Context, originalTxHash common.Hash) (common.Hash, error) {
+				cancelled <- originalTxHash
+				return common.HexToHash("ffff"), nil
+			}),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheque, nil
+			}),
+		),
+	)
+	defer cashoutService.Close()
+
+	_, err := cashoutService.CashChequeWithOptions(context.Background(), vaultAddress, recipientAddress, vault.CashoutOptions{
+		Deadline: time.Now().Add(-time.Second),
+	})
+	if !errors.Is(err, vault.ErrDeadlinePassed) {
+		t.Fatalf("wrong error. wanted %v, got %v", vault.ErrDeadlinePassed, err)
+	}
+
+	_, err = cashoutService.CashChequeWithOptions(context.Background(), vaultAddress, recipientAddress, vault.CashoutOptions{
+		Deadline: time.Now().Add(20 * time.Millisecond),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case hash := <-cancelled:
+		if hash != txHash {
+			t.Fatalf("cancelled wrong transaction. wanted %x, got %x", txHash, hash)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("cashout was not cancelled after its deadline")
+	}
+}
+
+func TestCashoutPendingMetric(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	txHash := common.HexToHash("dddd")
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: big.NewInt(500),
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	mined := make(chan struct{})
+	cashoutService := vault.NewCashoutService(
+		storemock.NewStateStore(),
+		backendmock.New(),
+		transactionmock.New(
+			transactionmock.WithSendFunc(func(ctx context.Context, request *transaction.TxRequest) (common.Hash, error) {
+				return txHash, nil
+			}),
+			transactionmock.WithWaitForReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				<-mined
+				return nil, context.DeadlineExceeded
+			}),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheque, nil
+			}),
+		),
+	)
+	pending := cashoutService.Metrics()[0]
+
+	results, unsubscribe := cashoutService.SubscribeCashoutResults()
+	defer unsubscribe()
+
+	_, err := cashoutService.CashCheque(context.Background(), vaultAddress, recipientAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := testutil.ToFloat64(pending); got != 1 {
+		t.Fatalf("wrong pending cashouts. wanted 1, got %v", got)
+	}
+
+	close(mined)
+	select {
+	case <-results:
+	case <-time.After(time.Second):
+		t.Fatal("no result published")
+	}
+
+	// the write-ahead log record is cleared right after the result was published
+	for i := 0; testutil.ToFloat64(pending) != 0; i++ {
+		if i == 100 {
+			t.Fatalf("wrong pending cashouts. wanted 0, got %v", testutil.ToFloat64(pending))
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRebuildHistoryFromChain(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	beneficiary := common.HexToAddress("aaaa")
+	recipientAddress := common.HexToAddress("efff")
+	txHash := common.HexToHash("dddd")
+	totalPayout := big.NewInt(100)
+
+	logData, err := chequeCashedEventType.Inputs.NonIndexed().Pack(totalPayout, big.NewInt(500), big.NewInt(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cashoutService := vault.NewCashoutService(
+		storemock.NewStateStore(),
+		backendmock.New(
+			backendmock.WithFilterLogsFunc(func(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+				if len(query.Addresses) != 1 || query.Addresses[0] != vaultAddress {
+					t.Fatalf("wrong addresses in query %v", query.Addresses)
+				}
+				if query.FromBlock.Uint64() != 10 {
+					t.Fatalf("wrong from block. wanted 10, got %d", query.FromBlock)
+				}
+				return []types.Log{
+					{
+						Address:     vaultAddress,
+						Topics:      []common.Hash{chequeCashedEventType.ID, beneficiary.Hash(), recipientAddress.Hash(), beneficiary.Hash()},
+						Data:        logData,
+						BlockNumber: 12,
+						TxHash:      txHash,
+					},
+				}, nil
+			}),
+			backendmock.WithHeaderbyNumberFunc(func(ctx context.Context, number *big.Int) (*types.Header, error) {
+				return &types.Header{Time: 5000}, nil
+			}),
+		),
+		transactionmock.New(),
+		chequestoremock.NewChequeStore(),
+		vault.WithBeneficiary(beneficiary),
+	)
+
+	// rebuilding twice must not duplicate the results
+	for i := 0; i < 2; i++ {
+		err = cashoutService.RebuildHistoryFromChain(context.Background(), vaultAddress, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results, err := cashoutService.CashoutResults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("wrong number of results. wanted 1, got %d", len(results))
+	}
+	result := results[0]
+	if result.TxHash != txHash || result.Vault != vaultAddress || result.Amount.Cmp(totalPayout) != 0 || result.CashTime != 5000 || result.Status != "success" || !result.Rebuilt {
+		t.Fatalf("wrong result %+v", result)
+	}
+
+	// the totals were not updated by the rebuild, so the rebuilt result must not count as drift
+	diff, err := cashoutService.VerifyTotals(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff.Drifted() {
+		t.Fatalf("rebuilt result counted as drift %+v", diff)
+	}
+}
+
+func TestStoreCashResultFailures(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	beneficiary := common.HexToAddress("aaaa")
+	tokenAddress := common.HexToAddress("eeee")
+	txHash := common.HexToHash("dddd")
+	totalPayout := big.NewInt(100)
+	cumulativePayout := big.NewInt(500)
+	errFault := errors.New("injected fault")
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      beneficiary,
+			CumulativePayout: cumulativePayout,
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	logData, err := chequeCashedEventType.Inputs.NonIndexed().Pack(totalPayout, cumulativePayout, big.NewInt(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	receipt := &types.Receipt{
+		Status:  types.ReceiptStatusSuccessful,
+		GasUsed: 10,
+		Logs: []*types.Log{
+			{
+				Address: vaultAddress,
+				Topics:  []common.Hash{chequeCashedEventType.ID, beneficiary.Hash(), recipientAddress.Hash(), beneficiary.Hash()},
+				Data:    logData,
+			},
+		},
+	}
+
+	for _, tc := range []struct {
+		name        string
+		method      string
+		call        int
+		wantStatus  string
+		wantAmount  *big.Int
+		wantTokened bool
+	}{
+		{name: "wait for receipt", method: "WaitForReceipt", call: 1, wantStatus: "fail", wantAmount: cumulativePayout},
+		{name: "stored transaction", method: "StoredTransaction", call: 2, wantStatus: "success", wantAmount: totalPayout, wantTokened: true},
+		{name: "token lookup", method: "Call", call: 1, wantStatus: "success", wantAmount: totalPayout},
+		{name: "none", wantStatus: "success", wantAmount: totalPayout, wantTokened: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			faults := faultinject.New()
+			if tc.method != "" {
+				faults.FailOn(tc.method, errFault, tc.call)
+			}
+
+			store := storemock.NewStateStore()
+			cashoutService := vault.NewCashoutService(
+				store,
+				faultinject.NewBackend(backendmock.New(
+					backendmock.WithTransactionByHashFunc(func(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+						return nil, false, nil
+					}),
+					backendmock.WithTransactionReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+						return receipt, nil
+					}),
+				), faults),
+				faultinject.NewService(transactionmock.New(
+					transactionmock.WithCallFunc(func(ctx context.Context, request *transaction.TxRequest) ([]byte, error) {
+						return tokenAddress.Hash().Bytes(), nil
+					}),
+					transactionmock.WithSendFunc(func(ctx context.Context, request *transaction.TxRequest) (common.Hash, error) {
+						return txHash, nil
+					}),
+					transactionmock.WithWaitForReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+						return receipt, nil
+					}),
+					transactionmock.WithStoredTransactionFunc(func(hash common.Hash) (*transaction.StoredTransaction, error) {
+						return &transaction.StoredTransaction{GasPrice: big.NewInt(2)}, nil
+					}),
+				), faults),
+				chequestoremock.NewChequeStore(
+					chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+						return cheque, nil
+					}),
+				),
+			)
+
+			results, unsubscribe := cashoutService.SubscribeCashoutResults()
+			defer unsubscribe()
+
+			_, err := cashoutService.CashCheque(context.Background(), vaultAddress, recipientAddress)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			select {
+			case result := <-results:
+				if result.Status != tc.wantStatus {
+					t.Fatalf("wrong status. wanted %s, got %s", tc.wantStatus, result.Status)
+				}
+				if result.Amount.Cmp(tc.wantAmount) != 0 {
+					t.Fatalf("wrong amount. wanted %d, got %d", tc.wantAmount, result.Amount)
+				}
+				if tc.wantTokened != (result.Token == tokenAddress) {
+					t.Fatalf("wrong token %x recorded on the result", result.Token)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("no result published")
+			}
+
+			if tc.method != "" && faults.Calls(tc.method) < tc.call {
+				t.Fatalf("fault on %s was not triggered", tc.method)
+			}
+
+			totals, err := cashoutService.TotalReceivedCashedByToken()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.wantTokened != (totals[tokenAddress] != nil) {
+				t.Fatalf("unexpected token totals %v", totals)
+			}
+		})
+	}
+}
+
+func TestCashoutResultsForDay(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	day := time.Date(2021, 6, 1, 0, 0, 0, 0, time.Local)
+	cashTime := day.Add(3 * time.Hour).Unix()
+
+	store := storemock.NewStateStore()
+	// a result stored before results were partitioned by day
+	legacyKey := fmt.Sprintf("%s%x_%d", statestore.CashoutResultPrefixKey(), vaultAddress, cashTime)
+	err := store.Put(legacyKey, &vault.CashOutResult{
+		TxHash:   common.HexToHash("dddd"),
+		Vault:    vaultAddress,
+		Amount:   big.NewInt(100),
+		CashTime: cashTime,
+		Status:   "success",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.Put(statestore.CashoutResultKeyByTime(vaultAddress, day.Add(-time.Hour).Unix()), &vault.CashOutResult{
+		TxHash:   common.HexToHash("cccc"),
+		Vault:    vaultAddress,
+		Amount:   big.NewInt(50),
+		CashTime: day.Add(-time.Hour).Unix(),
+		Status:   "success",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cashoutService := vault.NewCashoutService(store, backendmock.New(), transactionmock.New(), chequestoremock.NewChequeStore())
+	cashoutService.Start()
+	defer cashoutService.Close()
+
+	results, err := cashoutService.CashoutResultsForDay(day.Add(12 * time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].TxHash != common.HexToHash("dddd") {
+		t.Fatalf("wrong results for day %v", results)
+	}
+
+	err = store.Get(legacyKey, &vault.CashOutResult{})
+	if !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("legacy result key not migrated: %v", err)
+	}
+
+	all, err := cashoutService.CashoutResults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 {
+		t.Fatalf("wrong number of results. wanted 2, got %d", len(all))
+	}
+
+	vaults, err := cashoutService.CashedVaults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vaults) != 1 || vaults[0] != vaultAddress {
+		t.Fatalf("wrong cashed vaults %v", vaults)
+	}
+}
+
+func TestCashoutResultsForDayLocalMidnight(t *testing.T) {
+	// the daily totals use the local date, the result partitions must agree with them in any zone
+	local := time.Local
+	time.Local = time.FixedZone("UTC+8", 8*60*60)
+	defer func() { time.Local = local }()
+
+	vaultAddress := common.HexToAddress("abcd")
+	// cashed just after local midnight, which is still the previous day in UTC
+	cashTime := time.Date(2021, 6, 2, 0, 1, 0, 0, time.Local).Unix()
+	localDay := time.Date(2021, 6, 2, 0, 0, 0, 0, time.UTC).Unix()
+	utcDay := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC).Unix()
+
+	key := statestore.CashoutResultKeyByTime(vaultAddress, cashTime)
+	if !strings.HasPrefix(key, statestore.CashoutResultDayPrefixKey(localDay)) {
+		t.Fatalf("result key %s not in the partition of the local day %d", key, localDay)
+	}
+
+	store := storemock.NewStateStore()
+	// a result stored while results were partitioned by the UTC date
+	utcKey := fmt.Sprintf("%s%x_%d", statestore.CashoutResultDayPrefixKey(utcDay), vaultAddress, cashTime)
+	err := store.Put(utcKey, &vault.CashOutResult{
+		TxHash:   common.HexToHash("dddd"),
+		Vault:    vaultAddress,
+		Amount:   big.NewInt(100),
+		CashTime: cashTime,
+		Status:   "success",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cashoutService := vault.NewCashoutService(store, backendmock.New(), transactionmock.New(), chequestoremock.NewChequeStore())
+	cashoutService.Start()
+	defer cashoutService.Close()
+
+	results, err := cashoutService.CashoutResultsForDay(time.Date(2021, 6, 2, 12, 0, 0, 0, time.Local))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].TxHash != common.HexToHash("dddd") {
+		t.Fatalf("wrong results for the local day %v", results)
+	}
+	results, err = cashoutService.CashoutResultsForDay(time.Date(2021, 6, 1, 12, 0, 0, 0, time.Local))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 0 {
+		t.Fatalf("wrong results for the previous local day %v", results)
+	}
+
+	err = store.Get(utcKey, &vault.CashOutResult{})
+	if !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("result in the UTC partition not moved: %v", err)
+	}
+}
+
+type storageBackend struct {
+	transaction.Backend
+	storage map[common.Hash][]byte
+}
+
+func (b *storageBackend) StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error) {
+	return b.storage[key], nil
+}
+
+func TestResolveImplementation(t *testing.T) {
+	proxy := common.HexToAddress("abcd")
+	slotImplementation := common.HexToAddress("1111")
+	viewImplementation := common.HexToAddress("2222")
+	implementationSlot := common.HexToHash("0x360894a13ba1a3210667c828492db98dca3e2076cc3735a920a3ca505d382bbc")
+
+	for _, tc := range []struct {
+		name    string
+		backend transaction.Backend
+		want    common.Address
+	}{
+		{
+			name: "storage slot",
+			backend: &storageBackend{
+				Backend: backendmock.New(),
+				storage: map[common.Hash][]byte{implementationSlot: slotImplementation.Hash().Bytes()},
+			},
+			want: slotImplementation,
+		},
+		{
+			name:    "empty storage slot",
+			backend: &storageBackend{Backend: backendmock.New()},
+			want:    viewImplementation,
+		},
+		{
+			name:    "no storage access",
+			backend: backendmock.New(),
+			want:    viewImplementation,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cashoutService := vault.NewCashoutService(
+				storemock.NewStateStore(),
+				tc.backend,
+				transactionmock.New(
+					transactionmock.WithABICall(&vaultABI, proxy, viewImplementation.Hash().Bytes(), "implementation"),
+				),
+				chequestoremock.NewChequeStore(),
+			)
+
+			implementation, err := cashoutService.ResolveImplementation(context.Background(), proxy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if implementation != tc.want {
+				t.Fatalf("wrong implementation. wanted %x, got %x", tc.want, implementation)
+			}
+		})
+	}
+}
+
+func TestStatsSnapshot(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+
+	store := storemock.NewStateStore()
+	for key, value := range map[string]interface{}{
+		statestore.TotalReceivedCashedKey:                big.NewInt(300),
+		statestore.TotalReceivedCashedCountKey:           3,
+		statestore.TotalCallerPayoutKey:                  big.NewInt(20),
+		statestore.GetTodayTotalDailyReceivedCashedKey(): big.NewInt(100),
+		statestore.CashoutResultKeyByTime(vaultAddress, 1234): &vault.CashOutResult{
+			Vault:    vaultAddress,
+			Amount:   big.NewInt(100),
+			CashTime: 1234,
+			Status:   "success",
+		},
+	} {
+		if err := store.Put(key, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cashoutService := vault.NewCashoutService(store, backendmock.New(), transactionmock.New(), chequestoremock.NewChequeStore())
+
+	snapshot, err := cashoutService.StatsSnapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snapshot.TotalReceivedCashed.Cmp(big.NewInt(300)) != 0 ||
+		snapshot.TotalReceivedCashedCount != 3 ||
+		snapshot.TotalCallerPayout.Cmp(big.NewInt(20)) != 0 ||
+		snapshot.DailyReceivedCashed.Cmp(big.NewInt(100)) != 0 ||
+		snapshot.PendingCount != 0 ||
+		snapshot.LastCashoutTime != 1234 {
+		t.Fatalf("wrong snapshot %+v", snapshot)
+	}
+
+	if err := store.Put(statestore.LastCashoutTimeKey, int64(2000)); err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err = cashoutService.StatsSnapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snapshot.LastCashoutTime != 2000 {
+		t.Fatalf("wrong last cashout time. wanted 2000, got %d", snapshot.LastCashoutTime)
+	}
+}
+
+func TestCashoutSignatureVerification(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	issuer := common.HexToAddress("1111")
+
+	newCheque := func(cumulativePayout int64) *vault.SignedCheque {
+		return &vault.SignedCheque{
+			Cheque: vault.Cheque{
+				Beneficiary:      common.HexToAddress("aaaa"),
+				CumulativePayout: big.NewInt(cumulativePayout),
+				Vault:            vaultAddress,
+			},
+			Signature: []byte{byte(cumulativePayout)},
+		}
+	}
+
+	var lock sync.Mutex
+	cheque := newCheque(500)
+	signer := issuer
+	recovered := 0
+	issuerCalls := 0
+
+	cashoutService := vault.NewCashoutService(
+		storemock.NewStateStore(),
+		backendmock.New(),
+		transactionmock.New(
+			transactionmock.WithCallFunc(func(ctx context.Context, request *transaction.TxRequest) ([]byte, error) {
+				lock.Lock()
+				defer lock.Unlock()
+				issuerCalls++
+				return issuer.Hash().Bytes(), nil
+			}),
+			transactionmock.WithSendFunc(func(ctx context.Context, request *transaction.TxRequest) (common.Hash, error) {
+				return common.HexToHash("dddd"), nil
+			}),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				lock.Lock()
+				defer lock.Unlock()
+				return cheque, nil
+			}),
+		),
+		vault.WithSignatureVerification(func(c *vault.SignedCheque, chainID int64) (common.Address, error) {
+			lock.Lock()
+			defer lock.Unlock()
+			recovered++
+			return signer, nil
+		}, 5),
+	)
+
+	cash := func() error {
+		_, err := cashoutService.CashCheque(context.Background(), vaultAddress, recipientAddress)
+		return err
+	}
+	expectCounts := func(wantRecovered, wantIssuerCalls int) {
+		t.Helper()
+		lock.Lock()
+		defer lock.Unlock()
+		if recovered != wantRecovered {
+			t.Fatalf("wrong number of recoveries. wanted %d, got %d", wantRecovered, recovered)
+		}
+		if issuerCalls != wantIssuerCalls {
+			t.Fatalf("wrong number of issuer calls. wanted %d, got %d", wantIssuerCalls, issuerCalls)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := cash(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expectCounts(1, 1)
+
+	// a new cheque for the vault needs a new recovery
+	lock.Lock()
+	cheque = newCheque(600)
+	lock.Unlock()
+	if err := cash(); err != nil {
+		t.Fatal(err)
+	}
+	expectCounts(2, 1)
+
+	lock.Lock()
+	cheque = newCheque(700)
+	signer = common.HexToAddress("2222")
+	lock.Unlock()
+	err := cash()
+	if !errors.Is(err, vault.ErrChequeInvalid) {
+		t.Fatalf("wrong error. wanted %v, got %v", vault.ErrChequeInvalid, err)
+	}
+}
+
+func TestNetPosition(t *testing.T) {
+	peerVault := common.HexToAddress("abcd")
+	ownVault := common.HexToAddress("bcde")
+	peer := common.HexToAddress("1111")
+	beneficiary := common.HexToAddress("aaaa")
+
+	received := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      beneficiary,
+			CumulativePayout: big.NewInt(500),
+			Vault:            peerVault,
+		},
+		Signature: []byte{},
+	}
+	sent := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      peer,
+			CumulativePayout: big.NewInt(800),
+			Vault:            ownVault,
+		},
+		Signature: []byte{},
+	}
+
+	store := storemock.NewStateStore()
+	if err := store.Put(vault.VaultKey, ownVault); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put(vault.LastIssuedChequeKey(peer), sent); err != nil {
+		t.Fatal(err)
+	}
+
+	cashoutService := vault.NewCashoutService(
+		store,
+		backendmock.New(),
+		transactionmock.New(
+			transactionmock.WithABICallSequence(
+				transactionmock.ABICall(&vaultABI, peerVault, big.NewInt(100).FillBytes(make([]byte, 32)), "paidOut", beneficiary),
+				transactionmock.ABICall(&vaultABI, peerVault, peer.Hash().Bytes(), "issuer"),
+				transactionmock.ABICall(&vaultABI, ownVault, big.NewInt(600).FillBytes(make([]byte, 32)), "paidOut", peer),
+			),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return received, nil
+			}),
+		),
+	)
+
+	// the peer owes 500 - 100 while the node owes 800 - 600
+	net, err := cashoutService.NetPosition(context.Background(), peerVault)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if net.Cmp(big.NewInt(200)) != 0 {
+		t.Fatalf("wrong net position. wanted 200, got %d", net)
+	}
+}
+
+func TestCashoutStoreRawReceipts(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	beneficiary := common.HexToAddress("aaaa")
+	txHash := common.HexToHash("dddd")
+	totalPayout := big.NewInt(100)
+	cumulativePayout := big.NewInt(500)
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      beneficiary,
+			CumulativePayout: cumulativePayout,
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	logData, err := chequeCashedEventType.Inputs.NonIndexed().Pack(totalPayout, cumulativePayout, big.NewInt(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	receipt := &types.Receipt{
+		Status:            types.ReceiptStatusSuccessful,
+		CumulativeGasUsed: 70000,
+		TxHash:            txHash,
+		GasUsed:           50000,
+		BlockHash:         common.HexToHash("bbbb"),
+		BlockNumber:       big.NewInt(42),
+		TransactionIndex:  3,
+		Logs: []*types.Log{
+			{
+				Address: vaultAddress,
+				Topics:  []common.Hash{chequeCashedEventType.ID, beneficiary.Hash(), recipientAddress.Hash(), beneficiary.Hash()},
+				Data:    logData,
+			},
+		},
+	}
+
+	var clockMu sync.Mutex
+	now := time.Unix(1000000, 0)
+	clock := func() time.Time {
+		clockMu.Lock()
+		defer clockMu.Unlock()
+		return now
+	}
+
+	store, err := leveldbstore.NewInMemoryStateStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	cashoutService := vault.NewCashoutService(
+		store,
+		backendmock.New(
+			backendmock.WithTransactionByHashFunc(func(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+				return nil, false, nil
+			}),
+			backendmock.WithTransactionReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				return receipt, nil
+			}),
+		),
+		transactionmock.New(
+			transactionmock.WithCallFunc(func(ctx context.Context, request *transaction.TxRequest) ([]byte, error) {
+				return common.HexToAddress("eeee").Hash().Bytes(), nil
+			}),
+			transactionmock.WithSendFunc(func(ctx context.Context, request *transaction.TxRequest) (common.Hash, error) {
+				return txHash, nil
+			}),
+			transactionmock.WithWaitForReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				return receipt, nil
+			}),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheque, nil
+			}),
+		),
+		vault.WithStoreRawReceipts(true),
+		vault.WithClock(clock),
+	)
+
+	results, unsubscribe := cashoutService.SubscribeCashoutResults()
+	defer unsubscribe()
+
+	_, err = cashoutService.CashCheque(context.Background(), vaultAddress, recipientAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-results:
+	case <-time.After(time.Second):
+		t.Fatal("no result published")
+	}
+
+	stored, err := cashoutService.RawReceipt(txHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.TxHash != txHash || stored.GasUsed != receipt.GasUsed || stored.BlockNumber.Cmp(receipt.BlockNumber) != 0 || stored.TransactionIndex != receipt.TransactionIndex {
+		t.Fatalf("wrong stored receipt %+v", stored)
+	}
+	if len(stored.Logs) != 1 || stored.Logs[0].Address != vaultAddress || stored.Logs[0].TxHash != txHash {
+		t.Fatalf("wrong stored logs %+v", stored.Logs)
+	}
+
+	pruned, err := cashoutService.PruneRawReceipts(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pruned != 0 {
+		t.Fatalf("pruned fresh receipt")
+	}
+
+	clockMu.Lock()
+	now = now.Add(2 * time.Hour)
+	clockMu.Unlock()
+
+	pruned, err = cashoutService.PruneRawReceipts(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pruned != 1 {
+		t.Fatalf("expected 1 pruned receipt, got %d", pruned)
+	}
+	_, err = cashoutService.RawReceipt(txHash)
+	if !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+}
+
+func TestEstimateCashoutRecentAverage(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	beneficiary := common.HexToAddress("aaaa")
+	txHash := common.HexToHash("dddd")
+	totalPayout := big.NewInt(100)
+	cumulativePayout := big.NewInt(500)
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      beneficiary,
+			CumulativePayout: cumulativePayout,
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	logData, err := chequeCashedEventType.Inputs.NonIndexed().Pack(totalPayout, cumulativePayout, big.NewInt(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	receipt := &types.Receipt{
+		Status:  types.ReceiptStatusSuccessful,
+		GasUsed: 50000,
+		Logs: []*types.Log{
+			{
+				Address: vaultAddress,
+				Topics:  []common.Hash{chequeCashedEventType.ID, beneficiary.Hash(), recipientAddress.Hash(), beneficiary.Hash()},
+				Data:    logData,
+			},
+		},
+	}
+
+	estimateErr := errors.New("estimate unavailable")
+	cashoutService := vault.NewCashoutService(
+		storemock.NewStateStore(),
+		backendmock.New(
+			backendmock.WithTransactionByHashFunc(func(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+				return nil, false, nil
+			}),
+			backendmock.WithTransactionReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				return receipt, nil
+			}),
+			backendmock.WithEstimateGasFunc(func(ctx context.Context, call ethereum.CallMsg) (uint64, error) {
+				return 0, estimateErr
+			}),
+		),
+		transactionmock.New(
+			transactionmock.WithCallFunc(func(ctx context.Context, request *transaction.TxRequest) ([]byte, error) {
+				return common.HexToAddress("eeee").Hash().Bytes(), nil
+			}),
+			transactionmock.WithSendFunc(func(ctx context.Context, request *transaction.TxRequest) (common.Hash, error) {
+				return txHash, nil
+			}),
+			transactionmock.WithWaitForReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				return receipt, nil
+			}),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheque, nil
+			}),
+		),
+	)
+
+	_, err = cashoutService.EstimateCashout(context.Background(), vaultAddress, recipientAddress)
+	if !errors.Is(err, vault.ErrNoGasEstimate) {
+		t.Fatalf("wrong error. wanted %v, got %v", vault.ErrNoGasEstimate, err)
+	}
+
+	results, unsubscribe := cashoutService.SubscribeCashoutResults()
+	defer unsubscribe()
+
+	_, err = cashoutService.CashCheque(context.Background(), vaultAddress, recipientAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case result := <-results:
+		if result.GasUsed != receipt.GasUsed {
+			t.Fatalf("wrong gas used. wanted %d, got %d", receipt.GasUsed, result.GasUsed)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no result published")
+	}
+
+	if average := cashoutService.RecentAverageGas(); average != receipt.GasUsed {
+		t.Fatalf("wrong average gas. wanted %d, got %d", receipt.GasUsed, average)
+	}
+
+	gas, err := cashoutService.EstimateCashout(context.Background(), vaultAddress, recipientAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gas != receipt.GasUsed {
+		t.Fatalf("wrong estimate. wanted %d, got %d", receipt.GasUsed, gas)
+	}
+}
+
+func TestSimulateCashout(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	beneficiary := common.HexToAddress("aaaa")
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      beneficiary,
+			CumulativePayout: big.NewInt(500),
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	cashoutService := vault.NewCashoutService(
+		storemock.NewStateStore(),
+		backendmock.New(),
+		transactionmock.New(
+			transactionmock.WithABICallSequence(
+				transactionmock.ABICall(&vaultABI, vaultAddress, nil, "cashChequeBeneficiary", recipientAddress, cheque.CumulativePayout, cheque.Signature),
+				transactionmock.ABICall(&vaultABI, vaultAddress, big.NewInt(100).FillBytes(make([]byte, 32)), "paidOut", beneficiary),
+				transactionmock.ABICall(&vaultABI, vaultAddress, big.NewInt(300).FillBytes(make([]byte, 32)), "totalbalance"),
+			),
+			transactionmock.WithSendFunc(func(ctx context.Context, request *transaction.TxRequest) (common.Hash, error) {
+				t.Fatal("simulation sent a transaction")
+				return common.Hash{}, nil
+			}),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheque, nil
+			}),
+		),
+	)
+
+	// 400 are uncashed but only 300 are left in the vault
+	result, err := cashoutService.SimulateCashout(context.Background(), vaultAddress, recipientAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Bounced || result.TotalPayout.Cmp(big.NewInt(300)) != 0 || result.Recipient != recipientAddress {
+		t.Fatalf("wrong simulated result %+v", result)
+	}
+}
+
+func TestCashChequeMultiLargestFirst(t *testing.T) {
+	smallVault := common.HexToAddress("abcd")
+	largeVault := common.HexToAddress("bcde")
+	recipientAddress := common.HexToAddress("efff")
+	beneficiary := common.HexToAddress("aaaa")
+
+	cheques := map[common.Address]*vault.SignedCheque{
+		smallVault: {
+			Cheque: vault.Cheque{
+				Beneficiary:      beneficiary,
+				CumulativePayout: big.NewInt(100),
+				Vault:            smallVault,
+			},
+			Signature: []byte{},
+		},
+		largeVault: {
+			Cheque: vault.Cheque{
+				Beneficiary:      beneficiary,
+				CumulativePayout: big.NewInt(500),
+				Vault:            largeVault,
+			},
+			Signature: []byte{},
+		},
+	}
+
+	block := make(chan struct{})
+	defer close(block)
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	store := storemock.NewStateStore()
+	var batchErr error
+	cashoutService := vault.NewCashoutService(
+		store,
+		backendmock.New(),
+		transactionmock.New(
+			transactionmock.WithCallFunc(func(ctx context.Context, request *transaction.TxRequest) ([]byte, error) {
+				// nothing was paid out yet
+				return make([]byte, 32), nil
+			}),
+			transactionmock.WithSendFunc(func(ctx context.Context, request *transaction.TxRequest) (common.Hash, error) {
+				// the batch record is stamped with the clock of the service
+				batchErr = store.Get(vault.CashoutBatchKey(now.UnixNano()), &vault.CashoutBatch{})
+				return request.To.Hash(), nil
+			}),
+			transactionmock.WithWaitForReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				<-block
+				return nil, context.Canceled
+			}),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheques[c], nil
+			}),
+		),
+		vault.WithMaxInFlightCashouts(1),
+		vault.WithClock(func() time.Time { return now }),
+	)
+
+	results, err := cashoutService.CashChequeMulti(context.Background(), []common.Address{smallVault, largeVault}, recipientAddress, vault.BatchOrderingLargestFirst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if batchErr != nil {
+		t.Fatalf("batch record not stamped with the clock: %v", batchErr)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	if results[0].Vault != largeVault || results[0].Err != nil || results[0].TxHash != largeVault.Hash() {
+		t.Fatalf("largest cashout not submitted first %+v", results[0])
+	}
+	if results[1].Vault != smallVault || !errors.Is(results[1].Err, vault.ErrTooManyInFlight) {
+		t.Fatalf("expected in-flight limit for the smaller cashout %+v", results[1])
+	}
+}
+
+func TestCashoutStatusMultipleCashedEvents(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	beneficiary := common.HexToAddress("aaaa")
+	otherBeneficiary := common.HexToAddress("bbbb")
+	txHash := common.HexToHash("dddd")
+	cumulativePayout := big.NewInt(500)
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      beneficiary,
+			CumulativePayout: cumulativePayout,
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	ourLogData, err := chequeCashedEventType.Inputs.NonIndexed().Pack(big.NewInt(100), cumulativePayout, big.NewInt(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherLogData, err := chequeCashedEventType.Inputs.NonIndexed().Pack(big.NewInt(700), big.NewInt(900), big.NewInt(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	receipt := &types.Receipt{
+		Status: types.ReceiptStatusSuccessful,
+		Logs: []*types.Log{
+			{
+				Address: vaultAddress,
+				Topics:  []common.Hash{chequeCashedEventType.ID, otherBeneficiary.Hash(), recipientAddress.Hash(), otherBeneficiary.Hash()},
+				Data:    otherLogData,
+			},
+			{
+				Address: vaultAddress,
+				Topics:  []common.Hash{chequeCashedEventType.ID, beneficiary.Hash(), recipientAddress.Hash(), beneficiary.Hash()},
+				Data:    ourLogData,
+			},
+		},
+	}
+
+	store := storemock.NewStateStore()
+	newService := func(opts ...vault.CashoutServiceOption) vault.CashoutService {
+		return vault.NewCashoutService(
+			store,
+			backendmock.New(
+				backendmock.WithTransactionByHashFunc(func(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+					return nil, false, nil
+				}),
+				backendmock.WithTransactionReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+					return receipt, nil
+				}),
+			),
+			transactionmock.New(
+				transactionmock.WithABISend(&vaultABI, txHash, vaultAddress, big.NewInt(0), "cashChequeBeneficiary", recipientAddress, cheque.CumulativePayout, cheque.Signature),
+			),
+			chequestoremock.NewChequeStore(
+				chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+					return cheque, nil
+				}),
+			),
+			opts...,
+		)
+	}
+
+	cashoutService := newService(vault.WithBeneficiary(beneficiary))
+	_, err = cashoutService.CashCheque(context.Background(), vaultAddress, recipientAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status, err := cashoutService.CashoutStatus(context.Background(), vaultAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Last.Result.Beneficiary != beneficiary || status.Last.Result.TotalPayout.Cmp(big.NewInt(100)) != 0 {
+		t.Fatalf("selected wrong event %+v", status.Last.Result)
+	}
+
+	// without a known beneficiary the events cannot be told apart
+	_, err = newService().CashoutStatus(context.Background(), vaultAddress)
+	if !errors.Is(err, vault.ErrMultipleCashedEvents) {
+		t.Fatalf("wrong error. wanted %v, got %v", vault.ErrMultipleCashedEvents, err)
+	}
+}
+
+type memoryCashoutRepository struct {
+	mu      sync.Mutex
+	actions map[common.Address]*vault.CashoutAction
+	results []vault.CashOutResult
+}
+
+func (r *memoryCashoutRepository) Action(v common.Address) (*vault.CashoutAction, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	action, ok := r.actions[v]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return action, nil
+}
+
+func (r *memoryCashoutRepository) PutAction(v common.Address, action *vault.CashoutAction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.actions[v] = action
+	return nil
+}
+
+func (r *memoryCashoutRepository) DeleteAction(v common.Address) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.actions, v)
+	return nil
+}
+
+func (r *memoryCashoutRepository) PutResult(result *vault.CashOutResult) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.results = append(r.results, *result)
+	return nil
+}
+
+func (r *memoryCashoutRepository) DeleteResult(result *vault.CashOutResult) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, stored := range r.results {
+		if stored.TxHash == result.TxHash && stored.Vault == result.Vault {
+			r.results = append(r.results[:i], r.results[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (r *memoryCashoutRepository) IterateResults(fn func(result vault.CashOutResult) (bool, error)) error {
+	r.mu.Lock()
+	results := append([]vault.CashOutResult(nil), r.results...)
+	r.mu.Unlock()
+	for _, result := range results {
+		stop, err := fn(result)
+		if err != nil || stop {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *memoryCashoutRepository) ResultsForDay(day time.Time) ([]vault.CashOutResult, error) {
+	return nil, nil
+}
+
+func (r *memoryCashoutRepository) Vaults() ([]common.Address, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var vaults []common.Address
+	for v := range r.actions {
+		vaults = append(vaults, v)
+	}
+	return vaults, nil
+}
+
+func TestCashoutRepository(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	beneficiary := common.HexToAddress("aaaa")
+	txHash := common.HexToHash("dddd")
+	totalPayout := big.NewInt(100)
+	cumulativePayout := big.NewInt(500)
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      beneficiary,
+			CumulativePayout: cumulativePayout,
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	logData, err := chequeCashedEventType.Inputs.NonIndexed().Pack(totalPayout, cumulativePayout, big.NewInt(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	receipt := &types.Receipt{
+		Status: types.ReceiptStatusSuccessful,
+		Logs: []*types.Log{
+			{
+				Address: vaultAddress,
+				Topics:  []common.Hash{chequeCashedEventType.ID, beneficiary.Hash(), recipientAddress.Hash(), beneficiary.Hash()},
+				Data:    logData,
+			},
+		},
+	}
+
+	repository := &memoryCashoutRepository{
+		actions: make(map[common.Address]*vault.CashoutAction),
+	}
+	store := storemock.NewStateStore()
+	cashoutService := vault.NewCashoutService(
+		store,
+		backendmock.New(
+			backendmock.WithTransactionByHashFunc(func(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+				return nil, false, nil
+			}),
+			backendmock.WithTransactionReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				return receipt, nil
+			}),
+		),
+		transactionmock.New(
+			transactionmock.WithSendFunc(func(ctx context.Context, request *transaction.TxRequest) (common.Hash, error) {
+				return txHash, nil
+			}),
+			transactionmock.WithWaitForReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				return receipt, nil
+			}),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheque, nil
+			}),
+		),
+		vault.WithCashoutRepository(repository),
+	)
+
+	results, unsubscribe := cashoutService.SubscribeCashoutResults()
+	defer unsubscribe()
+
+	_, err = cashoutService.CashCheque(context.Background(), vaultAddress, recipientAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-results:
+	case <-time.After(time.Second):
+		t.Fatal("no result published")
+	}
+
+	if action, err := repository.Action(vaultAddress); err != nil || action.TxHash != txHash {
+		t.Fatalf("action not stored in repository: %+v %v", action, err)
+	}
+	if err := store.Get(vault.CashoutActionKey(vaultAddress), &vault.CashoutAction{}); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("action stored in state store: %v", err)
+	}
+
+	stored, err := cashoutService.CashoutResults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 1 || stored[0].TxHash != txHash || stored[0].Status != "success" {
+		t.Fatalf("wrong results %+v", stored)
+	}
+
+	vaults, err := cashoutService.CashedVaults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vaults) != 1 || vaults[0] != vaultAddress {
+		t.Fatalf("wrong cashed vaults %v", vaults)
+	}
+}
+
+type revertDataError struct {
+	data string
+}
+
+func (e *revertDataError) Error() string          { return "execution reverted" }
+func (e *revertDataError) ErrorData() interface{} { return e.data }
+
+func TestDecodeRevert(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		data string
+		want string
+	}{
+		{
+			name: "empty",
+			data: "0x",
+			want: "reverted without reason",
+		},
+		{
+			name: "reason",
+			// Error("cheque already cashed")
+			data: "0x08c379a0" +
+				"0000000000000000000000000000000000000000000000000000000000000020" +
+				"0000000000000000000000000000000000000000000000000000000000000015" +
+				"63686571756520616c726561647920636173686564000000000000000000000000",
+			want: "cheque already cashed",
+		},
+		{
+			name: "panic",
+			data: "0x4e487b71" + "0000000000000000000000000000000000000000000000000000000000000011",
+			want: "panic code 0x11",
+		},
+		{
+			name: "custom",
+			data: "0xdeadbeef",
+			want: "unknown revert data 0xdeadbeef",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := vault.DecodeRevert(common.FromHex(tc.data)); got != tc.want {
+				t.Fatalf("wrong reason. wanted %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestEstimateCashoutRevertReason(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: big.NewInt(500),
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	cashoutService := vault.NewCashoutService(
+		storemock.NewStateStore(),
+		backendmock.New(
+			backendmock.WithEstimateGasFunc(func(ctx context.Context, call ethereum.CallMsg) (uint64, error) {
+				// Error("cheque already cashed")
+				return 0, &revertDataError{data: "0x08c379a0" +
+					"0000000000000000000000000000000000000000000000000000000000000020" +
+					"0000000000000000000000000000000000000000000000000000000000000015" +
+					"63686571756520616c726561647920636173686564000000000000000000000000"}
+			}),
+		),
+		transactionmock.New(),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheque, nil
+			}),
+		),
+	)
+
+	_, err := cashoutService.EstimateCashout(context.Background(), vaultAddress, recipientAddress)
+	if !errors.Is(err, vault.ErrCashoutSimulationReverted) {
+		t.Fatalf("wrong error. wanted %v, got %v", vault.ErrCashoutSimulationReverted, err)
+	}
+	if !strings.Contains(err.Error(), "cheque already cashed") {
+		t.Fatalf("revert reason missing in %v", err)
+	}
+}
+
+func TestCashoutDefaultRecipient(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	txHash := common.HexToHash("dddd")
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: big.NewInt(500),
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	cashoutService := vault.NewCashoutService(
+		storemock.NewStateStore(),
+		backendmock.New(),
+		transactionmock.New(
+			transactionmock.WithABISend(&vaultABI, txHash, vaultAddress, big.NewInt(0), "cashChequeBeneficiary", recipientAddress, cheque.CumulativePayout, cheque.Signature),
+			transactionmock.WithWaitForReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				return nil, context.Canceled
+			}),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheque, nil
+			}),
+		),
+	)
+	defer cashoutService.Close()
+
+	_, err := cashoutService.CashCheque(context.Background(), vaultAddress, common.Address{})
+	if !errors.Is(err, vault.ErrNoRecipient) {
+		t.Fatalf("wrong error. wanted %v, got %v", vault.ErrNoRecipient, err)
+	}
+
+	err = cashoutService.SetDefaultRecipient(vaultAddress, recipientAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	returnedTxHash, err := cashoutService.CashCheque(context.Background(), vaultAddress, common.Address{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if returnedTxHash != txHash {
+		t.Fatalf("returned wrong transaction hash. wanted %v, got %v", txHash, returnedTxHash)
+	}
+
+	err = cashoutService.SetDefaultRecipient(vaultAddress, common.Address{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = cashoutService.DefaultRecipient(vaultAddress)
+	if !errors.Is(err, vault.ErrNoRecipient) {
+		t.Fatalf("default recipient not removed: %v", err)
+	}
+}
+
+func TestCashoutETA(t *testing.T) {
+	txHash := common.HexToHash("dddd")
+	tx := types.NewTransaction(0, common.HexToAddress("abcd"), big.NewInt(0), 100000, big.NewInt(100), nil)
+
+	for _, tc := range []struct {
+		suggested  int64
+		eta        time.Duration
+		confidence vault.ETAConfidence
+	}{
+		{suggested: 100, eta: 2 * time.Second, confidence: vault.ETAConfidenceHigh},
+		{suggested: 120, eta: 6 * time.Second, confidence: vault.ETAConfidenceMedium},
+		{suggested: 200, eta: 40 * time.Second, confidence: vault.ETAConfidenceLow},
+	} {
+		suggested := big.NewInt(tc.suggested)
+		cashoutService := vault.NewCashoutService(
+			storemock.NewStateStore(),
+			backendmock.New(
+				backendmock.WithTransactionByHashFunc(func(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+					return tx, true, nil
+				}),
+				backendmock.WithHeaderbyNumberFunc(func(ctx context.Context, number *big.Int) (*types.Header, error) {
+					// 20 blocks in 40 seconds
+					if number == nil {
+						return &types.Header{Number: big.NewInt(100), Time: 1040}, nil
+					}
+					return &types.Header{Number: number, Time: 1000}, nil
+				}),
+			),
+			transactionmock.New(),
+			chequestoremock.NewChequeStore(),
+			vault.WithGasOracle(gasOracleFunc(func(ctx context.Context) (*big.Int, error) {
+				return suggested, nil
+			})),
+		)
+
+		eta, confidence, err := cashoutService.CashoutETA(context.Background(), txHash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if eta != tc.eta || confidence != tc.confidence {
+			t.Fatalf("suggested gas price %d: wanted %v with %v confidence, got %v with %v confidence", tc.suggested, tc.eta, tc.confidence, eta, confidence)
+		}
+	}
+}
+
+func TestCashoutTxChain(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	txHash := common.HexToHash("dddd")
+	cancelTxHash := common.HexToHash("ffff")
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: big.NewInt(500),
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	cashoutService := vault.NewCashoutService(
+		storemock.NewStateStore(),
+		backendmock.New(
+			backendmock.WithTransactionReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				return nil, ethereum.NotFound
+			}),
+		),
+		transactionmock.New(
+			transactionmock.WithSendFunc(func(ctx context.Context, request *transaction.TxRequest) (common.Hash, error) {
+				return txHash, nil
+			}),
+			transactionmock.WithWaitForReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				return nil, context.DeadlineExceeded
+			}),
+			transactionmock.WithCancelTransactionFunc(func(ctx context.Context, originalTxHash common.Hash) (common.Hash, error) {
+				return cancelTxHash, nil
+			}),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheque, nil
+			}),
+		),
+	)
+	defer cashoutService.Close()
+
+	_, err := cashoutService.CashChequeWithOptions(context.Background(), vaultAddress, recipientAddress, vault.CashoutOptions{
+		Deadline: time.Now().Add(20 * time.Millisecond),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chain, err := cashoutService.CashoutTxChain(vaultAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chain) != 1 || chain[0] != txHash {
+		t.Fatalf("wrong chain before the deadline %v", chain)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		chain, err = cashoutService.CashoutTxChain(vaultAddress)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(chain) == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("cancellation not recorded, chain %v", chain)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if chain[0] != txHash || chain[1] != cancelTxHash {
+		t.Fatalf("wrong chain %v", chain)
+	}
+}
+
+func TestCashChequeBatchTx(t *testing.T) {
+	firstVault := common.HexToAddress("abcd")
+	secondVault := common.HexToAddress("bcde")
+	recipientAddress := common.HexToAddress("efff")
+	beneficiary := common.HexToAddress("aaaa")
+	txHashes := map[common.Address]common.Hash{
+		firstVault:  common.HexToHash("dddd"),
+		secondVault: common.HexToHash("eeee"),
+	}
+
+	cheques := make(map[common.Address]*vault.SignedCheque)
+	receipts := make(map[common.Hash]*types.Receipt)
+	for i, v := range []common.Address{firstVault, secondVault} {
+		cumulativePayout := big.NewInt(int64(500 * (i + 1)))
+		cheques[v] = &vault.SignedCheque{
+			Cheque: vault.Cheque{
+				Beneficiary:      beneficiary,
+				CumulativePayout: cumulativePayout,
+				Vault:            v,
+			},
+			Signature: []byte{},
+		}
+		logData, err := chequeCashedEventType.Inputs.NonIndexed().Pack(cumulativePayout, cumulativePayout, big.NewInt(0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		receipts[txHashes[v]] = &types.Receipt{
+			Status: types.ReceiptStatusSuccessful,
+			Logs: []*types.Log{{
+				Address: v,
+				Topics:  []common.Hash{chequeCashedEventType.ID, beneficiary.Hash(), recipientAddress.Hash(), beneficiary.Hash()},
+				Data:    logData,
+			}},
+		}
+	}
+
+	var sendLock sync.Mutex
+	sent := make(map[common.Address]int)
+	cashoutService := vault.NewCashoutService(
+		storemock.NewStateStore(),
+		backendmock.New(
+			backendmock.WithTransactionByHashFunc(func(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+				return nil, false, nil
+			}),
+			backendmock.WithTransactionReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				return receipts[hash], nil
+			}),
+		),
+		transactionmock.New(
+			transactionmock.WithSendFunc(func(ctx context.Context, request *transaction.TxRequest) (common.Hash, error) {
+				sendLock.Lock()
+				defer sendLock.Unlock()
+				sent[*request.To]++
+				return txHashes[*request.To], nil
+			}),
+			transactionmock.WithWaitForReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				return receipts[hash], nil
+			}),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheques[c], nil
+			}),
+		),
+	)
+
+	_, err := cashoutService.CashChequeBatchTx(context.Background(), []vault.CashoutRequest{
+		{Vault: firstVault, Recipient: recipientAddress},
+		{Vault: firstVault, Recipient: recipientAddress},
+	})
+	if !errors.Is(err, vault.ErrDuplicateBatchVault) {
+		t.Fatalf("got error %v, wanted %v", err, vault.ErrDuplicateBatchVault)
+	}
+	if len(sent) != 0 {
+		t.Fatalf("batch with duplicate vault sent %v", sent)
+	}
+
+	results, unsubscribe := cashoutService.SubscribeCashoutResults()
+	defer unsubscribe()
+
+	returnedTxHashes, err := cashoutService.CashChequeBatchTx(context.Background(), []vault.CashoutRequest{
+		{Vault: firstVault, Recipient: recipientAddress, Label: "batch"},
+		{Vault: secondVault, Recipient: recipientAddress, Label: "batch"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(returnedTxHashes) != 2 || returnedTxHashes[0] != txHashes[firstVault] || returnedTxHashes[1] != txHashes[secondVault] {
+		t.Fatalf("wrong transaction hashes %v", returnedTxHashes)
+	}
+	sendLock.Lock()
+	if sent[firstVault] != 1 || sent[secondVault] != 1 {
+		t.Fatalf("expected one cashout transaction per vault, got %v", sent)
+	}
+	sendLock.Unlock()
+
+	cashed := make(map[common.Address]*big.Int)
+	for len(cashed) < 2 {
+		select {
+		case result := <-results:
+			if result.TxHash != txHashes[result.Vault] || result.Status != "success" || result.Label != "batch" {
+				t.Fatalf("wrong result %+v", result)
+			}
+			cashed[result.Vault] = result.Amount
+		case <-time.After(time.Second):
+			t.Fatal("not every result of the batch was published")
+		}
+	}
+	if cashed[firstVault].Cmp(big.NewInt(500)) != 0 || cashed[secondVault].Cmp(big.NewInt(1000)) != 0 {
+		t.Fatalf("wrong cashed amounts %v", cashed)
+	}
+}
+
+func TestAutoCashoutProlongedShortfall(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: big.NewInt(500),
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	type alert struct {
+		vault     common.Address
+		shortfall *big.Int
+		duration  time.Duration
+	}
+	alerts := make(chan alert, 10)
+	cashoutService := vault.NewCashoutService(
+		storemock.NewStateStore(),
+		backendmock.New(),
+		transactionmock.New(
+			transactionmock.WithCallFunc(func(ctx context.Context, request *transaction.TxRequest) ([]byte, error) {
+				// nothing was paid out yet and only 200 are left in the vault
+				if bytes.HasPrefix(request.Data, vaultABI.Methods["totalbalance"].ID) {
+					return big.NewInt(200).FillBytes(make([]byte, 32)), nil
+				}
+				return big.NewInt(0).FillBytes(make([]byte, 32)), nil
+			}),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheque, nil
+			}),
+			chequestoremock.WithLastChequesFunc(func() (map[common.Address]*vault.SignedCheque, error) {
+				return map[common.Address]*vault.SignedCheque{vaultAddress: cheque}, nil
+			}),
+		),
+		vault.WithAutoCashout(vault.AutoCashoutConfig{
+			Recipient:    common.HexToAddress("efff"),
+			LoopInterval: 10 * time.Millisecond,
+			MinAmount:    big.NewInt(1000),
+		}),
+		vault.WithProlongedShortfallAlert(50*time.Millisecond, func(v common.Address, shortfall *big.Int, duration time.Duration) {
+			alerts <- alert{vault: v, shortfall: shortfall, duration: duration}
+		}),
+	)
+	cashoutService.Start()
+
+	select {
+	case a := <-alerts:
+		if a.vault != vaultAddress || a.shortfall.Cmp(big.NewInt(300)) != 0 || a.duration < 50*time.Millisecond {
+			t.Fatalf("wrong alert %+v", a)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("prolonged shortfall not reported")
+	}
+
+	// the shortfall is only reported once while it lasts
+	time.Sleep(100 * time.Millisecond)
+	cashoutService.Close()
+	if len(alerts) != 0 {
+		t.Fatalf("shortfall reported %d more times", len(alerts))
+	}
+}
+
+func TestLastAutoCashoutDecision(t *testing.T) {
+	smallVault := common.HexToAddress("abcd")
+	excludedVault := common.HexToAddress("bcde")
+
+	cheques := make(map[common.Address]*vault.SignedCheque)
+	for _, v := range []common.Address{smallVault, excludedVault} {
+		cheques[v] = &vault.SignedCheque{
+			Cheque: vault.Cheque{
+				Beneficiary:      common.HexToAddress("aaaa"),
+				CumulativePayout: big.NewInt(500),
+				Vault:            v,
+			},
+			Signature: []byte{},
+		}
+	}
+
+	cashoutService := vault.NewCashoutService(
+		storemock.NewStateStore(),
+		backendmock.New(),
+		transactionmock.New(
+			transactionmock.WithCallFunc(func(ctx context.Context, request *transaction.TxRequest) ([]byte, error) {
+				return big.NewInt(0).FillBytes(make([]byte, 32)), nil
+			}),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheques[c], nil
+			}),
+			chequestoremock.WithLastChequesFunc(func() (map[common.Address]*vault.SignedCheque, error) {
+				return cheques, nil
+			}),
+		),
+		vault.WithAutoCashout(vault.AutoCashoutConfig{
+			Recipient:    common.HexToAddress("efff"),
+			LoopInterval: 10 * time.Millisecond,
+			MinAmount:    big.NewInt(1000),
+		}),
+	)
+
+	_, err := cashoutService.LastAutoCashoutDecision(smallVault)
+	if !errors.Is(err, vault.ErrNoAutoCashoutDecision) {
+		t.Fatalf("wrong error. wanted %v, got %v", vault.ErrNoAutoCashoutDecision, err)
+	}
+
+	err = cashoutService.ExcludeVaultFromAutoCashout(excludedVault)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cashoutService.Start()
+	defer cashoutService.Close()
+
+	for v, want := range map[common.Address]vault.AutoDecisionReason{
+		smallVault:    vault.AutoDecisionBelowThreshold,
+		excludedVault: vault.AutoDecisionExcluded,
+	} {
+		deadline := time.Now().Add(time.Second)
+		for {
+			decision, err := cashoutService.LastAutoCashoutDecision(v)
+			if err == nil {
+				if decision.Reason != want || !decision.Skipped() {
+					t.Fatalf("wrong decision for vault %x. wanted %q, got %+v", v, want, decision)
+				}
+				break
+			}
+			if !errors.Is(err, vault.ErrNoAutoCashoutDecision) {
+				t.Fatal(err)
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("no decision recorded for vault %x", v)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+}
+
+func TestCashoutCodecMigration(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	beneficiary := common.HexToAddress("aaaa")
+	txHash := common.HexToHash("dddd")
+	totalPayout := big.NewInt(100)
+	cumulativePayout := big.NewInt(500)
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      beneficiary,
+			CumulativePayout: cumulativePayout,
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	logData, err := chequeCashedEventType.Inputs.NonIndexed().Pack(totalPayout, cumulativePayout, big.NewInt(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	receipt := &types.Receipt{
+		Status: types.ReceiptStatusSuccessful,
+		Logs: []*types.Log{
+			{
+				Address: vaultAddress,
+				Topics:  []common.Hash{chequeCashedEventType.ID, beneficiary.Hash(), recipientAddress.Hash(), beneficiary.Hash()},
+				Data:    logData,
+			},
+		},
+	}
+
+	store := storemock.NewStateStore()
+	newService := func(opts ...vault.CashoutServiceOption) vault.CashoutService {
+		return vault.NewCashoutService(
+			store,
+			backendmock.New(
+				backendmock.WithTransactionByHashFunc(func(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+					return nil, false, nil
+				}),
+				backendmock.WithTransactionReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+					return receipt, nil
+				}),
+			),
+			transactionmock.New(
+				transactionmock.WithSendFunc(func(ctx context.Context, request *transaction.TxRequest) (common.Hash, error) {
+					return txHash, nil
+				}),
+				transactionmock.WithWaitForReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+					return receipt, nil
+				}),
+			),
+			chequestoremock.NewChequeStore(
+				chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+					return cheque, nil
+				}),
+			),
+			opts...,
+		)
+	}
+
+	gobService := newService(vault.WithCodec(vault.GobCodec{}))
+	results, unsubscribe := gobService.SubscribeCashoutResults()
+	defer unsubscribe()
+
+	_, err = gobService.CashCheque(context.Background(), vaultAddress, recipientAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-results:
+	case <-time.After(time.Second):
+		t.Fatal("no result published")
+	}
+
+	// the default JSON codec cannot read the gob records
+	if err := store.Get(vault.CashoutActionKey(vaultAddress), &vault.CashoutAction{}); err == nil {
+		t.Fatal("action stored as JSON")
+	}
+
+	converted, err := vault.MigrateCodec(store, vault.GobCodec{}, vault.JSONCodec{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if converted != 2 {
+		t.Fatalf("expected the action and the result to be converted, got %d", converted)
+	}
+
+	jsonService := newService()
+	status, err := jsonService.CashoutStatus(context.Background(), vaultAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Last == nil || status.Last.TxHash != txHash {
+		t.Fatalf("wrong status after migration %+v", status)
+	}
+	stored, err := jsonService.CashoutResults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 1 || stored[0].TxHash != txHash || stored[0].Amount.Cmp(totalPayout) != 0 {
+		t.Fatalf("wrong results after migration %+v", stored)
+	}
+}
+
+func TestCashableVaults(t *testing.T) {
+	coveredVault := common.HexToAddress("abcd")
+	bouncingVault := common.HexToAddress("bcde")
+	smallVault := common.HexToAddress("cdef")
+
+	cheques := make(map[common.Address]*vault.SignedCheque)
+	for v, amount := range map[common.Address]int64{coveredVault: 500, bouncingVault: 900, smallVault: 50} {
+		cheques[v] = &vault.SignedCheque{
+			Cheque: vault.Cheque{
+				Beneficiary:      common.HexToAddress("aaaa"),
+				CumulativePayout: big.NewInt(amount),
+				Vault:            v,
+			},
+			Signature: []byte{},
+		}
+	}
+
+	cashoutService := vault.NewCashoutService(
+		storemock.NewStateStore(),
+		backendmock.New(
+			backendmock.WithEstimateGasFunc(func(ctx context.Context, call ethereum.CallMsg) (uint64, error) {
+				return 60000, nil
+			}),
+		),
+		transactionmock.New(
+			transactionmock.WithCallFunc(func(ctx context.Context, request *transaction.TxRequest) ([]byte, error) {
+				// every vault holds 600 and nothing was paid out yet
+				if bytes.HasPrefix(request.Data, vaultABI.Methods["totalbalance"].ID) {
+					return big.NewInt(600).FillBytes(make([]byte, 32)), nil
+				}
+				return big.NewInt(0).FillBytes(make([]byte, 32)), nil
+			}),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheques[c], nil
+			}),
+			chequestoremock.WithLastChequesFunc(func() (map[common.Address]*vault.SignedCheque, error) {
+				return cheques, nil
+			}),
+		),
+	)
+
+	cashable, err := cashoutService.CashableVaults(context.Background(), big.NewInt(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cashable) != 1 {
+		t.Fatalf("expected only the covered vault, got %+v", cashable)
+	}
+	if cashable[0].Vault != coveredVault || cashable[0].Amount.Cmp(big.NewInt(500)) != 0 || cashable[0].EstimatedGas != 60000 {
+		t.Fatalf("wrong cashable vault %+v", cashable[0])
+	}
+}
+
+func TestCashoutResultsOrdering(t *testing.T) {
+	store := storemock.NewStateStore()
+	day := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC).Unix()
+	for _, result := range []vault.CashOutResult{
+		{TxHash: common.HexToHash("01"), Vault: common.HexToAddress("aaaa"), Amount: big.NewInt(1), CashTime: day + 20, Status: "success"},
+		{TxHash: common.HexToHash("03"), Vault: common.HexToAddress("bbbb"), Amount: big.NewInt(1), CashTime: day + 10, Status: "success"},
+		{TxHash: common.HexToHash("02"), Vault: common.HexToAddress("cccc"), Amount: big.NewInt(1), CashTime: day + 10, Status: "success"},
+	} {
+		result := result
+		if err := store.Put(statestore.CashoutResultKeyByTime(result.Vault, result.CashTime), &result); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cashoutService := vault.NewCashoutService(store, backendmock.New(), transactionmock.New(), chequestoremock.NewChequeStore())
+
+	want := []common.Hash{common.HexToHash("02"), common.HexToHash("03"), common.HexToHash("01")}
+	for i := 0; i < 5; i++ {
+		results, err := cashoutService.CashoutResults()
+		if err != nil {
+			t.Fatal(err)
+		}
+		forDay, err := cashoutService.CashoutResultsForDay(time.Unix(day, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, got := range [][]vault.CashOutResult{results, forDay} {
+			if len(got) != len(want) {
+				t.Fatalf("expected %d results, got %d", len(want), len(got))
+			}
+			for j := range want {
+				if got[j].TxHash != want[j] {
+					t.Fatalf("wrong order at %d. wanted %x, got %x", j, want[j], got[j].TxHash)
+				}
+			}
+		}
+	}
+}
+
+func TestRecoverFailedResults(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	beneficiary := common.HexToAddress("aaaa")
+	txHash := common.HexToHash("dddd")
+	pendingTxHash := common.HexToHash("eeee")
+
+	logData, err := chequeCashedEventType.Inputs.NonIndexed().Pack(big.NewInt(300), big.NewInt(500), big.NewInt(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := storemock.NewStateStore()
+	if err := store.Put(statestore.PeerReceivedUncashRecordsCountKey(vaultAddress), 4); err != nil {
+		t.Fatal(err)
+	}
+	cashTime := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC).Unix()
+	for _, result := range []vault.CashOutResult{
+		{TxHash: txHash, Vault: vaultAddress, Amount: big.NewInt(500), CashTime: cashTime, Status: "fail"},
+		{TxHash: pendingTxHash, Vault: common.HexToAddress("bbbb"), Amount: big.NewInt(500), CashTime: cashTime, Status: "fail"},
+	} {
+		result := result
+		if err := store.Put(statestore.CashoutResultKeyByTime(result.Vault, result.CashTime), &result); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cashoutService := vault.NewCashoutService(
+		store,
+		backendmock.New(
+			backendmock.WithTransactionReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				if hash != txHash {
+					return nil, ethereum.NotFound
+				}
+				return &types.Receipt{
+					Status:  types.ReceiptStatusSuccessful,
+					GasUsed: 21000,
+					Logs: []*types.Log{
+						{
+							Address: vaultAddress,
+							Topics:  []common.Hash{chequeCashedEventType.ID, beneficiary.Hash(), recipientAddress.Hash(), beneficiary.Hash()},
+							Data:    logData,
+						},
+					},
+				}, nil
+			}),
+		),
+		transactionmock.New(),
+		chequestoremock.NewChequeStore(),
+	)
+
+	recovered, err := cashoutService.RecoverFailedResults(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recovered != 1 {
+		t.Fatalf("expected 1 recovered result, got %d", recovered)
+	}
+
+	// a second pass must not count the recovered result again
+	recovered, err = cashoutService.RecoverFailedResults(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recovered != 0 {
+		t.Fatalf("expected no recovered results, got %d", recovered)
+	}
+
+	results, err := cashoutService.CashoutResults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, result := range results {
+		switch result.TxHash {
+		case txHash:
+			if result.Status != "success" || result.Amount.Cmp(big.NewInt(300)) != 0 || result.GasUsed != 21000 {
+				t.Fatalf("result not recovered: %+v", result)
+			}
+		case pendingTxHash:
+			if result.Status != "fail" {
+				t.Fatalf("unconfirmed result was promoted: %+v", result)
+			}
+		}
+	}
+
+	total := big.NewInt(0)
+	if err := store.Get(statestore.TotalReceivedCashedKey, &total); err != nil {
+		t.Fatal(err)
+	}
+	if total.Cmp(big.NewInt(300)) != 0 {
+		t.Fatalf("expected total received cashed of 300, got %d", total)
+	}
+
+	// the recovered amount belongs to the day of the cashout, not to the day of the recovery
+	y, m, d := time.Unix(cashTime, 0).Date()
+	daily := big.NewInt(0)
+	if err := store.Get(statestore.GetTotalDailyReceivedCashedKeyByTime(time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Unix()), &daily); err != nil {
+		t.Fatal(err)
+	}
+	if daily.Cmp(big.NewInt(300)) != 0 {
+		t.Fatalf("expected daily received cashed of 300 on the day of the cashout, got %d", daily)
+	}
+	if err := store.Get(statestore.GetTodayTotalDailyReceivedCashedKey(), &daily); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("recovered amount credited to today: %d, %v", daily, err)
+	}
+
+	uncashed := 0
+	if err := store.Get(statestore.PeerReceivedUncashRecordsCountKey(vaultAddress), &uncashed); err != nil {
+		t.Fatal(err)
+	}
+	if uncashed != 4 {
+		t.Fatalf("uncashed record count changed to %d by recovery", uncashed)
+	}
+}
+
+func TestRecoverFailedResultsResolvesReverted(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	txHash := common.HexToHash("dddd")
+
+	store := storemock.NewStateStore()
+	cashTime := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC).Unix()
+	err := store.Put(statestore.CashoutResultKeyByTime(vaultAddress, cashTime), &vault.CashOutResult{
+		TxHash:   txHash,
+		Vault:    vaultAddress,
+		Amount:   big.NewInt(500),
+		CashTime: cashTime,
+		Status:   "fail",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	receiptCalls := 0
+	cashoutService := vault.NewCashoutService(
+		store,
+		backendmock.New(
+			backendmock.WithTransactionReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				receiptCalls++
+				return &types.Receipt{Status: types.ReceiptStatusFailed}, nil
+			}),
+		),
+		transactionmock.New(),
+		chequestoremock.NewChequeStore(),
+	)
+
+	for pass := 0; pass < 2; pass++ {
+		recovered, err := cashoutService.RecoverFailedResults(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if recovered != 0 {
+			t.Fatalf("pass %d: reverted result was recovered", pass)
+		}
+	}
+	if receiptCalls != 1 {
+		t.Fatalf("receipt of the reverted result fetched %d times, wanted once", receiptCalls)
+	}
+
+	results, err := cashoutService.CashoutResults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Status != "fail" || !results[0].Resolved {
+		t.Fatalf("reverted result not resolved: %+v", results)
+	}
+}
+
+func TestIterateCashoutResults(t *testing.T) {
+	store := storemock.NewStateStore()
+	for i := int64(0); i < 5; i++ {
+		result := vault.CashOutResult{TxHash: common.BigToHash(big.NewInt(i + 1)), Vault: common.HexToAddress("aaaa"), Amount: big.NewInt(i), CashTime: 1000 + i, Status: "success"}
+		if err := store.Put(statestore.CashoutResultKeyByTime(result.Vault, result.CashTime), &result); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cashoutService := vault.NewCashoutService(store, backendmock.New(), transactionmock.New(), chequestoremock.NewChequeStore())
+
+	count := 0
+	err := cashoutService.IterateCashoutResults(context.Background(), func(result vault.CashOutResult) bool {
+		count++
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 5 {
+		t.Fatalf("expected 5 results, got %d", count)
+	}
+
+	count = 0
+	err = cashoutService.IterateCashoutResults(context.Background(), func(result vault.CashOutResult) bool {
+		count++
+		return count < 2
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Fatalf("expected iteration to stop after 2 results, got %d", count)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = cashoutService.IterateCashoutResults(ctx, func(result vault.CashOutResult) bool {
+		t.Fatal("callback called with cancelled context")
+		return true
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+type chainIDBackend struct {
+	transaction.Backend
+	chainID *big.Int
+	calls   int
+}
+
+func (b *chainIDBackend) ChainID(ctx context.Context) (*big.Int, error) {
+	b.calls++
+	return b.chainID, nil
+}
+
+func TestCashoutChainIDValidation(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	txHash := common.HexToHash("dddd")
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: big.NewInt(500),
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	for _, tc := range []struct {
+		name    string
+		chainID int64
+		opts    []vault.CashoutServiceOption
+		err     error
+	}{
+		{name: "matching", chainID: 5},
+		{name: "backend mismatch", chainID: 1, err: vault.ErrChainIDMismatch},
+		{
+			name:    "cheque mismatch",
+			chainID: 5,
+			opts: []vault.CashoutServiceOption{
+				vault.WithSignatureVerification(func(c *vault.SignedCheque, chainID int64) (common.Address, error) {
+					return common.Address{}, nil
+				}, 1),
+			},
+			err: vault.ErrChainIDMismatch,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			backend := &chainIDBackend{Backend: backendmock.New(), chainID: big.NewInt(5)}
+			cashoutService := vault.NewCashoutService(
+				storemock.NewStateStore(),
+				backend,
+				transactionmock.New(
+					transactionmock.WithABISend(&vaultABI, txHash, vaultAddress, big.NewInt(0), "cashChequeBeneficiary", recipientAddress, cheque.CumulativePayout, cheque.Signature),
+					transactionmock.WithWaitForReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+						return nil, context.Canceled
+					}),
+				),
+				chequestoremock.NewChequeStore(
+					chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+						return cheque, nil
+					}),
+				),
+				append(tc.opts, vault.WithChainID(tc.chainID))...,
+			)
+			defer cashoutService.Close()
+
+			_, err := cashoutService.CashCheque(context.Background(), vaultAddress, recipientAddress)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("wrong error. wanted %v, got %v", tc.err, err)
+			}
+			if backend.calls != 1 {
+				t.Fatalf("expected the backend chain id to be read once, got %d", backend.calls)
+			}
+		})
+	}
+}
+
+func TestDumpVaultCashout(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	otherVault := common.HexToAddress("bbbb")
+
+	store := storemock.NewStateStore()
+	for _, result := range []vault.CashOutResult{
+		{TxHash: common.HexToHash("01"), Vault: vaultAddress, Amount: big.NewInt(100), CashTime: 1000, Status: "success"},
+		{TxHash: common.HexToHash("02"), Vault: otherVault, Amount: big.NewInt(100), CashTime: 1000, Status: "success"},
+	} {
+		result := result
+		if err := store.Put(statestore.CashoutResultKeyByTime(result.Vault, result.CashTime), &result); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Put(statestore.PeerReceivedUncashRecordsCountKey(vaultAddress), 3); err != nil {
+		t.Fatal(err)
+	}
+
+	cashoutService := vault.NewCashoutService(
+		store,
+		backendmock.New(),
+		transactionmock.New(),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return nil, vault.ErrNoCheque
+			}),
+		),
+	)
+
+	dump, err := cashoutService.DumpVaultCashout(context.Background(), vaultAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dump.Cheque != nil || dump.Action != nil || dump.PaidOut != nil {
+		t.Fatalf("unexpected cheque, action or paid out in %+v", dump)
+	}
+	if len(dump.Results) != 1 || dump.Results[0].TxHash != common.HexToHash("01") {
+		t.Fatalf("wrong results %v", dump.Results)
+	}
+	if dump.UncashedCount != 3 {
+		t.Fatalf("expected uncashed count 3, got %d", dump.UncashedCount)
+	}
+
+	// a vault without any history
+	dump, err = cashoutService.DumpVaultCashout(context.Background(), common.HexToAddress("cccc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dump.Results) != 0 || len(dump.PendingTxs) != 0 || dump.UncashedCount != 0 {
+		t.Fatalf("unexpected history in %+v", dump)
+	}
+}
+
+// headBackend adds new head subscriptions to a backend
+type headBackend struct {
+	transaction.Backend
+	subscribe func(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error)
+}
+
+func (b *headBackend) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error) {
+	return b.subscribe(ctx, ch)
+}
+
+func TestCashoutReceiptNewHeads(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	txHash := common.HexToHash("dddd")
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: big.NewInt(500),
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	for _, tc := range []struct {
+		name      string
+		subscribe bool          // the backend supports new head subscriptions
+		subErr    error         // error of the subscription
+		interval  time.Duration // receipt polling interval
+	}{
+		// the polling interval is too long to matter, only a new head finds the receipt
+		{name: "head subscription", subscribe: true, interval: time.Hour},
+		{name: "subscription fails", subscribe: true, subErr: errors.New("subscriptions not supported"), interval: time.Millisecond},
+		{name: "polling", interval: time.Millisecond},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var mined int32
+			var backend transaction.Backend = backendmock.New(
+				backendmock.WithTransactionReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+					if atomic.LoadInt32(&mined) == 0 {
+						atomic.StoreInt32(&mined, 1)
+						return nil, ethereum.NotFound
+					}
+					return &types.Receipt{TxHash: hash, Status: types.ReceiptStatusSuccessful}, nil
+				}),
+			)
+			subscribed := make(chan chan<- *types.Header, 1)
+			if tc.subscribe {
+				backend = &headBackend{
+					Backend: backend,
+					subscribe: func(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error) {
+						if tc.subErr != nil {
+							return nil, tc.subErr
+						}
+						subscribed <- ch
+						return event.NewSubscription(func(quit <-chan struct{}) error {
+							<-quit
+							return nil
+						}), nil
+					},
+				}
+			}
+
+			cashoutService := vault.NewCashoutService(
+				storemock.NewStateStore(),
+				backend,
+				transactionmock.New(
+					transactionmock.WithABISend(&vaultABI, txHash, vaultAddress, big.NewInt(0), "cashChequeBeneficiary", recipientAddress, cheque.CumulativePayout, cheque.Signature),
+				),
+				chequestoremock.NewChequeStore(
+					chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+						return cheque, nil
+					}),
+				),
+				vault.WithReceiptPollingInterval(tc.interval),
+			)
+			defer cashoutService.Close()
+
+			done := make(chan error, 1)
+			_, err := cashoutService.CashChequeWithCallback(context.Background(), vaultAddress, recipientAddress, func(result *vault.CashOutResult, err error) {
+				done <- err
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tc.subscribe && tc.subErr == nil {
+				select {
+				case heads := <-subscribed:
+					heads <- &types.Header{Number: big.NewInt(1)}
+				case <-time.After(time.Second):
+					t.Fatal("no new head subscription")
+				}
+			}
+
+			select {
+			case err := <-done:
+				if err != nil {
+					t.Fatal(err)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("receipt not found")
+			}
+		})
+	}
+}
+
+func TestCashoutReceiptCancelled(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	beneficiary := common.HexToAddress("aaaa")
+	txHash := common.HexToHash("dddd")
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      beneficiary,
+			CumulativePayout: big.NewInt(500),
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	// the cashout was sent with nonce 3 and another transaction with that nonce was mined, so it never will be
+	cashoutService := vault.NewCashoutService(
+		storemock.NewStateStore(),
+		backendmock.New(
+			backendmock.WithTransactionReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				return nil, ethereum.NotFound
+			}),
+			backendmock.WithBlockNumberFunc(func(ctx context.Context) (uint64, error) {
+				return 100, nil
+			}),
+			backendmock.WithNonceAtFunc(func(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+				if account != beneficiary {
+					return 0, fmt.Errorf("nonce of unexpected account %x", account)
+				}
+				return 4, nil
+			}),
+		),
+		transactionmock.New(
+			transactionmock.WithABISend(&vaultABI, txHash, vaultAddress, big.NewInt(0), "cashChequeBeneficiary", recipientAddress, cheque.CumulativePayout, cheque.Signature),
+			transactionmock.WithStoredTransactionFunc(func(hash common.Hash) (*transaction.StoredTransaction, error) {
+				return &transaction.StoredTransaction{Nonce: 3}, nil
+			}),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheque, nil
+			}),
+		),
+		vault.WithBeneficiary(beneficiary),
+		vault.WithReceiptPollingInterval(time.Millisecond),
+	)
+	defer cashoutService.Close()
+
+	done := make(chan error, 1)
+	_, err := cashoutService.CashChequeWithCallback(context.Background(), vaultAddress, recipientAddress, func(result *vault.CashOutResult, err error) {
+		if err == nil && (result == nil || result.Status != "fail") {
+			err = fmt.Errorf("wrong result %+v", result)
+		}
+		done <- err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("tracking of the cancelled cashout did not finish")
+	}
+
+	results, err := cashoutService.CashoutResults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Status != "fail" {
+		t.Fatalf("wrong results %+v", results)
+	}
+}
+
+func TestCashoutConfirmationPolicy(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	txHash := common.HexToHash("dddd")
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: big.NewInt(500),
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	var (
+		mu    sync.Mutex
+		calls int
+	)
+	results := make(chan vault.CashOutResult, 1)
+	cashoutService := vault.NewCashoutService(
+		storemock.NewStateStore(),
+		backendmock.New(
+			backendmock.WithTransactionReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				return &types.Receipt{Status: types.ReceiptStatusSuccessful, TxHash: hash}, nil
+			}),
+		),
+		transactionmock.New(
+			transactionmock.WithABISend(&vaultABI, txHash, vaultAddress, big.NewInt(0), "cashChequeBeneficiary", recipientAddress, cheque.CumulativePayout, cheque.Signature),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheque, nil
+			}),
+		),
+		vault.WithReceiptPollingInterval(5*time.Millisecond),
+		vault.WithConfirmationPolicy(func(ctx context.Context, receipt *types.Receipt, backend transaction.Backend) (bool, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			calls++
+			return calls == 3, nil
+		}),
+	)
+	defer cashoutService.Close()
+
+	sub, unsubscribe := cashoutService.SubscribeCashoutResults()
+	defer unsubscribe()
+	go func() {
+		results <- <-sub
+	}()
+
+	_, err := cashoutService.CashCheque(context.Background(), vaultAddress, recipientAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case result := <-results:
+		if result.TxHash != txHash {
+			t.Fatalf("wrong result %+v", result)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("cashout result not stored")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if calls != 3 {
+		t.Fatalf("expected the result to be stored once the policy confirmed, policy asked %d times", calls)
+	}
+}
+
+func TestTrackVaultMetrics(t *testing.T) {
+	trackedVault := common.HexToAddress("abcd")
+	otherVault := common.HexToAddress("bbbb")
+	recipientAddress := common.HexToAddress("efff")
+	beneficiary := common.HexToAddress("aaaa")
+
+	logData, err := chequeCashedEventType.Inputs.NonIndexed().Pack(big.NewInt(300), big.NewInt(500), big.NewInt(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := storemock.NewStateStore()
+	vaults := map[common.Hash]common.Address{
+		common.HexToHash("01"): trackedVault,
+		common.HexToHash("02"): otherVault,
+	}
+	for txHash, v := range vaults {
+		result := vault.CashOutResult{TxHash: txHash, Vault: v, Amount: big.NewInt(500), CashTime: 1000, Status: "fail"}
+		if err := store.Put(statestore.CashoutResultKeyByTime(result.Vault, result.CashTime), &result); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cashoutService := vault.NewCashoutService(
+		store,
+		backendmock.New(
+			backendmock.WithTransactionReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				return &types.Receipt{
+					Status: types.ReceiptStatusSuccessful,
+					Logs: []*types.Log{
+						{
+							Address: vaults[hash],
+							Topics:  []common.Hash{chequeCashedEventType.ID, beneficiary.Hash(), recipientAddress.Hash(), beneficiary.Hash()},
+							Data:    logData,
+						},
+					},
+				}, nil
+			}),
+		),
+		transactionmock.New(),
+		chequestoremock.NewChequeStore(),
+	)
+	cashoutService.TrackVaultMetrics(trackedVault)
+
+	_, err = cashoutService.RecoverFailedResults(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cashed := cashoutService.Metrics()[2].(*prometheus.CounterVec)
+	for _, label := range []string{trackedVault.Hex(), "other"} {
+		if got := testutil.ToFloat64(cashed.WithLabelValues(label)); got != 300 {
+			t.Fatalf("wrong cashed total for %s. wanted 300, got %v", label, got)
+		}
+	}
+	if got := testutil.CollectAndCount(cashed); got != 2 {
+		t.Fatalf("expected 2 series, got %d", got)
+	}
+}
+
+func TestResumeCashoutBatch(t *testing.T) {
+	processedVault := common.HexToAddress("abcd")
+	landedVault := common.HexToAddress("bcde")
+	unsentVault := common.HexToAddress("cdef")
+	recipientAddress := common.HexToAddress("efff")
+	beneficiary := common.HexToAddress("aaaa")
+
+	chequeFor := func(v common.Address) *vault.SignedCheque {
+		return &vault.SignedCheque{
+			Cheque: vault.Cheque{
+				Beneficiary:      beneficiary,
+				CumulativePayout: big.NewInt(500),
+				Vault:            v,
+			},
+			Signature: []byte{},
+		}
+	}
+
+	store := storemock.NewStateStore()
+	key := vault.CashoutBatchKey(1)
+	err := store.Put(key, &vault.CashoutBatch{
+		Vaults:    []common.Address{processedVault, landedVault, unsentVault},
+		Recipient: recipientAddress,
+		Processed: 1,
+		Created:   1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		mu   sync.Mutex
+		sent []common.Address
+	)
+	cashoutService := vault.NewCashoutService(
+		store,
+		backendmock.New(),
+		transactionmock.New(
+			transactionmock.WithCallFunc(func(ctx context.Context, request *transaction.TxRequest) ([]byte, error) {
+				// the cashout of landedVault was mined before the crash
+				if *request.To == landedVault {
+					return big.NewInt(500).FillBytes(make([]byte, 32)), nil
+				}
+				return make([]byte, 32), nil
+			}),
+			transactionmock.WithSendFunc(func(ctx context.Context, request *transaction.TxRequest) (common.Hash, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				sent = append(sent, *request.To)
+				return request.To.Hash(), nil
+			}),
+			transactionmock.WithWaitForReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				return nil, context.Canceled
+			}),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return chequeFor(c), nil
+			}),
+		),
+	)
+	cashoutService.Start()
+	defer cashoutService.Close()
+
+	// the batch is resumed in the background and its record cleared once all vaults were processed
+	deadline := time.Now().Add(time.Second)
+	for {
+		var batch vault.CashoutBatch
+		err := store.Get(key, &batch)
+		if errors.Is(err, storage.ErrNotFound) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected batch record to be cleared, got %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(sent) != 1 || sent[0] != unsentVault {
+		t.Fatalf("expected only the unsent cashout to be resumed, sent %v", sent)
+	}
+}
+
+type publisherMock struct {
+	messages chan []byte
+	topic    string
+}
+
+func (p *publisherMock) Publish(ctx context.Context, topic string, data []byte) error {
+	p.topic = topic
+	p.messages <- data
+	return nil
+}
+
+func TestCashoutResultPublishing(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	txHash := common.HexToHash("dddd")
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: big.NewInt(500),
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	key, err := crypto.GenerateSecp256k1Key()
+	if err != nil {
+		t.Fatal(err)
+	}
+	publisher := &publisherMock{messages: make(chan []byte, 1)}
+	cashoutService := vault.NewCashoutService(
+		storemock.NewStateStore(),
+		backendmock.New(),
+		transactionmock.New(
+			transactionmock.WithABISend(&vaultABI, txHash, vaultAddress, big.NewInt(0), "cashChequeBeneficiary", recipientAddress, cheque.CumulativePayout, cheque.Signature),
+			transactionmock.WithWaitForReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				return nil, context.Canceled
+			}),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheque, nil
+			}),
+		),
+		vault.WithResultPublishing(publisher, "", crypto.NewDefaultSigner(key)),
+	)
+	defer cashoutService.Close()
+
+	_, err = cashoutService.CashCheque(context.Background(), vaultAddress, recipientAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var data []byte
+	select {
+	case data = <-publisher.messages:
+	case <-time.After(time.Second):
+		t.Fatal("no message published")
+	}
+	if publisher.topic != vault.DefaultCashoutTopic {
+		t.Fatalf("published on wrong topic %s", publisher.topic)
+	}
+
+	var signed vault.SignedCashoutMessage
+	if err := json.Unmarshal(data, &signed); err != nil {
+		t.Fatal(err)
+	}
+	pubKey, err := crypto.Recover(signed.Signature, signed.Message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pubKey.Equal(&key.PublicKey) {
+		t.Fatal("message not signed by the node")
+	}
+	var message vault.CashoutMessage
+	if err := json.Unmarshal(signed.Message, &message); err != nil {
+		t.Fatal(err)
+	}
+	if message.Vault != vaultAddress || message.TxHash != txHash || message.Status != "fail" {
+		t.Fatalf("wrong message %+v", message)
+	}
+}
+
+func TestDailyTotalsRange(t *testing.T) {
+	store := storemock.NewStateStore()
+	first := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	third := first.AddDate(0, 0, 2)
+	if err := store.Put(statestore.GetTotalDailyReceivedCashedKeyByTime(first.Unix()), big.NewInt(100)); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put(statestore.GetTotalDailyReceivedKeyByTime(third.Unix()), vault.DailyReceivedStats{Amount: big.NewInt(300), Count: 1, Date: third.Unix()}); err != nil {
+		t.Fatal(err)
+	}
+
+	cashoutService := vault.NewCashoutService(store, backendmock.New(), transactionmock.New(), chequestoremock.NewChequeStore())
+
+	totals, err := cashoutService.DailyTotalsRange(first.Add(12*time.Hour), third.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(totals) != 3 {
+		t.Fatalf("expected 3 days, got %d", len(totals))
+	}
+	for i, want := range []struct {
+		received, cashed int64
+	}{{0, 100}, {0, 0}, {300, 0}} {
+		total := totals[i]
+		if !total.Day.Equal(first.AddDate(0, 0, i)) {
+			t.Fatalf("wrong day at %d: %v", i, total.Day)
+		}
+		if total.Received.Int64() != want.received || total.ReceivedCashed.Int64() != want.cashed || total.CashoutGas.Sign() != 0 {
+			t.Fatalf("wrong totals at %d: %+v", i, total)
+		}
+	}
+
+	_, err = cashoutService.DailyTotalsRange(third, first)
+	if !errors.Is(err, vault.ErrInvalidRange) {
+		t.Fatalf("wrong error. wanted %v, got %v", vault.ErrInvalidRange, err)
+	}
+}
+
+// TestCashoutConcurrentAccess is meant to be run with -race. Cashouts, status lookups and result listings for
+// overlapping vaults run concurrently, afterwards the totals must account for every cashout exactly once.
+func TestCashoutConcurrentAccess(t *testing.T) {
+	vaults := []common.Address{common.HexToAddress("abcd"), common.HexToAddress("bcde"), common.HexToAddress("cdef")}
+	recipientAddress := common.HexToAddress("efff")
+	beneficiary := common.HexToAddress("aaaa")
+
+	logData, err := chequeCashedEventType.Inputs.NonIndexed().Pack(big.NewInt(100), big.NewInt(500), big.NewInt(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	receiptFor := func(hash common.Hash) *types.Receipt {
+		return &types.Receipt{
+			Status: types.ReceiptStatusSuccessful,
+			TxHash: hash,
+			Logs: []*types.Log{
+				{
+					Address: common.BytesToAddress(hash.Bytes()),
+					Topics:  []common.Hash{chequeCashedEventType.ID, beneficiary.Hash(), recipientAddress.Hash(), beneficiary.Hash()},
+					Data:    logData,
+				},
+			},
+		}
+	}
+
+	store := storemock.NewStateStore()
+	var sends uint64
+	cashoutService := vault.NewCashoutService(
+		store,
+		backendmock.New(
+			backendmock.WithTransactionByHashFunc(func(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+				return nil, false, nil
+			}),
+			backendmock.WithTransactionReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				return receiptFor(hash), nil
+			}),
+		),
+		transactionmock.New(
+			transactionmock.WithSendFunc(func(ctx context.Context, request *transaction.TxRequest) (common.Hash, error) {
+				// every cashout gets its own transaction, the vault stays in the low bytes for receiptFor
+				hash := request.To.Hash()
+				binary.BigEndian.PutUint64(hash[:8], atomic.AddUint64(&sends, 1))
+				return hash, nil
+			}),
+			transactionmock.WithWaitForReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				return receiptFor(hash), nil
+			}),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return &vault.SignedCheque{
+					Cheque: vault.Cheque{
+						Beneficiary:      beneficiary,
+						CumulativePayout: big.NewInt(500),
+						Vault:            c,
+					},
+					Signature: []byte{},
+				}, nil
+			}),
+		),
+	)
+	defer cashoutService.Close()
+
+	const rounds = 10
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		cashed  int
+		errList []error
+	)
+	for i := 0; i < rounds; i++ {
+		for _, v := range vaults {
+			v := v
+			wg.Add(3)
+			go func() {
+				defer wg.Done()
+				_, err := cashoutService.CashCheque(context.Background(), v, recipientAddress)
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					errList = append(errList, err)
+					return
+				}
+				cashed++
+			}()
+			go func() {
+				defer wg.Done()
+				_, _ = cashoutService.CashoutStatus(context.Background(), v)
+			}()
+			go func() {
+				defer wg.Done()
+				_, _ = cashoutService.CashoutResults()
+			}()
+		}
+	}
+	wg.Wait()
+	if len(errList) > 0 {
+		t.Fatalf("cashouts failed: %v", errList)
+	}
+
+	for i := 0; ; i++ {
+		snapshot, err := cashoutService.StatsSnapshot()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if snapshot.PendingCount == 0 {
+			break
+		}
+		if i == 200 {
+			t.Fatalf("%d cashouts still pending", snapshot.PendingCount)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	total := big.NewInt(0)
+	if err := store.Get(statestore.TotalReceivedCashedKey, &total); err != nil {
+		t.Fatal(err)
+	}
+	if want := big.NewInt(int64(cashed) * 100); total.Cmp(want) != 0 {
+		t.Fatalf("wrong total received cashed. wanted %d, got %d", want, total)
+	}
+}
+
+func TestPushMetrics(t *testing.T) {
+	beneficiary := common.HexToAddress("aaaa")
+
+	var (
+		method, path string
+		body         []byte
+	)
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path = r.Method, r.URL.Path
+		body, _ = ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer gateway.Close()
+
+	cashoutService := vault.NewCashoutService(
+		storemock.NewStateStore(),
+		backendmock.New(),
+		transactionmock.New(),
+		chequestoremock.NewChequeStore(),
+		vault.WithBeneficiary(beneficiary),
+	)
+
+	err := cashoutService.PushMetrics(context.Background(), gateway.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if method != http.MethodPut {
+		t.Fatalf("wrong method %s", method)
+	}
+	if want := "/metrics/job/btfs_vault_cashout/instance/" + beneficiary.Hex(); path != want {
+		t.Fatalf("wrong path. wanted %s, got %s", want, path)
+	}
+	if !bytes.Contains(body, []byte("vault_cashout_pending")) {
+		t.Fatal("pending cashouts metric not pushed")
+	}
+}
+
+type priceFeedMock struct {
+	price float64
+	token common.Address
+}
+
+func (f *priceFeedMock) TokenPriceUSD(ctx context.Context, token common.Address) (float64, error) {
+	f.token = token
+	return f.price, nil
+}
+
+func TestCashoutMinTokenPrice(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	tokenAddress := common.HexToAddress("1234")
+	txHash := common.HexToHash("dddd")
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: big.NewInt(500),
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	feed := &priceFeedMock{price: 0.5}
+	cashoutService := vault.NewCashoutService(
+		storemock.NewStateStore(),
+		backendmock.New(),
+		transactionmock.New(
+			transactionmock.WithCallFunc(func(ctx context.Context, request *transaction.TxRequest) ([]byte, error) {
+				return tokenAddress.Hash().Bytes(), nil
+			}),
+			transactionmock.WithSendFunc(func(ctx context.Context, request *transaction.TxRequest) (common.Hash, error) {
+				return txHash, nil
+			}),
+			transactionmock.WithWaitForReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				return nil, context.Canceled
+			}),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheque, nil
+			}),
+		),
+		vault.WithPriceFeed(feed),
+	)
+	defer cashoutService.Close()
+
+	opts := vault.CashoutOptions{MinTokenPriceUSD: 1}
+	_, err := cashoutService.CashChequeWithOptions(context.Background(), vaultAddress, recipientAddress, opts)
+	if !errors.Is(err, vault.ErrTokenPriceTooLow) {
+		t.Fatalf("wrong error. wanted %v, got %v", vault.ErrTokenPriceTooLow, err)
+	}
+	if feed.token != tokenAddress {
+		t.Fatalf("price of wrong token %x requested", feed.token)
+	}
+
+	feed.price = 2
+	returnedTxHash, err := cashoutService.CashChequeWithOptions(context.Background(), vaultAddress, recipientAddress, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if returnedTxHash != txHash {
+		t.Fatalf("returned wrong transaction hash. wanted %v, got %v", txHash, returnedTxHash)
+	}
+}
+
+func TestCashoutSelfTest(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	beneficiary := common.HexToAddress("aaaa")
+	txHash := common.HexToHash("dddd")
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      beneficiary,
+			CumulativePayout: big.NewInt(500),
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+	logData, err := chequeCashedEventType.Inputs.NonIndexed().Pack(big.NewInt(500), big.NewInt(500), big.NewInt(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	receipt := &types.Receipt{
+		Status: types.ReceiptStatusSuccessful,
+		TxHash: txHash,
+		Logs: []*types.Log{
+			{
+				Address: vaultAddress,
+				Topics:  []common.Hash{chequeCashedEventType.ID, beneficiary.Hash(), recipientAddress.Hash(), beneficiary.Hash()},
+				Data:    logData,
+			},
+		},
+	}
+
+	newService := func(opts ...vault.CashoutServiceOption) vault.CashoutService {
+		return vault.NewCashoutService(
+			storemock.NewStateStore(),
+			&chainIDBackend{
+				Backend: backendmock.New(
+					backendmock.WithTransactionByHashFunc(func(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+						return nil, false, nil
+					}),
+					backendmock.WithTransactionReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+						return receipt, nil
+					}),
+				),
+				chainID: big.NewInt(1029),
+			},
+			transactionmock.New(
+				transactionmock.WithABISend(&vaultABI, txHash, vaultAddress, big.NewInt(0), "cashChequeBeneficiary", recipientAddress, cheque.CumulativePayout, cheque.Signature),
+				transactionmock.WithWaitForReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+					return receipt, nil
+				}),
+			),
+			chequestoremock.NewChequeStore(
+				chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+					return cheque, nil
+				}),
+			),
+			opts...,
+		)
+	}
+
+	err = newService().SelfTest(context.Background())
+	if !errors.Is(err, vault.ErrSelfTestNotConfigured) {
+		t.Fatalf("wrong error. wanted %v, got %v", vault.ErrSelfTestNotConfigured, err)
+	}
+
+	err = newService(vault.WithSelfTest(vault.SelfTestConfig{ChainID: 199, Vault: vaultAddress, Recipient: recipientAddress})).SelfTest(context.Background())
+	if !errors.Is(err, vault.ErrChainIDMismatch) {
+		t.Fatalf("wrong error. wanted %v, got %v", vault.ErrChainIDMismatch, err)
+	}
+
+	cashoutService := newService(vault.WithSelfTest(vault.SelfTestConfig{ChainID: 1029, Vault: vaultAddress, Recipient: recipientAddress, Timeout: time.Second}))
+	defer cashoutService.Close()
+	err = cashoutService.SelfTest(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCashoutResultsByLabel(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	txHash := common.HexToHash("dddd")
+	label := "monthly-settlement-2024-06"
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: big.NewInt(500),
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	store := storemock.NewStateStore()
+	unlabeled := vault.CashOutResult{TxHash: common.HexToHash("01"), Vault: common.HexToAddress("bbbb"), Amount: big.NewInt(1), CashTime: 1000, Status: "success"}
+	if err := store.Put(statestore.CashoutResultKeyByTime(unlabeled.Vault, unlabeled.CashTime), &unlabeled); err != nil {
+		t.Fatal(err)
+	}
+
+	cashoutService := vault.NewCashoutService(
+		store,
+		backendmock.New(),
+		transactionmock.New(
+			transactionmock.WithABISend(&vaultABI, txHash, vaultAddress, big.NewInt(0), "cashChequeBeneficiary", recipientAddress, cheque.CumulativePayout, cheque.Signature),
+			transactionmock.WithWaitForReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				return nil, context.Canceled
+			}),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheque, nil
+			}),
+		),
+	)
+	defer cashoutService.Close()
+
+	results, unsubscribe := cashoutService.SubscribeCashoutResults()
+	defer unsubscribe()
+
+	_, err := cashoutService.CashChequeWithOptions(context.Background(), vaultAddress, recipientAddress, vault.CashoutOptions{Label: label})
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-results:
+	case <-time.After(time.Second):
+		t.Fatal("no result published")
+	}
+
+	var action vault.CashoutAction
+	if err := store.Get(vault.CashoutActionKey(vaultAddress), &action); err != nil {
+		t.Fatal(err)
+	}
+	if action.Label != label {
+		t.Fatalf("wrong action label %q", action.Label)
+	}
+
+	labeled, err := cashoutService.CashoutResultsByLabel(label)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(labeled) != 1 || labeled[0].TxHash != txHash || labeled[0].Label != label {
+		t.Fatalf("wrong labeled results %+v", labeled)
+	}
+}
+
+func TestCashoutSendHashWithError(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	txHash := common.HexToHash("dddd")
+	sendErr := errors.New("broadcast partially failed")
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: big.NewInt(500),
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	store := storemock.NewStateStore()
+	cashoutService := vault.NewCashoutService(
+		store,
+		backendmock.New(),
+		transactionmock.New(
+			transactionmock.WithSendFunc(func(ctx context.Context, request *transaction.TxRequest) (common.Hash, error) {
+				return txHash, sendErr
+			}),
+			transactionmock.WithWaitForReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				return nil, context.Canceled
+			}),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheque, nil
+			}),
+		),
+	)
+	defer cashoutService.Close()
+
+	results, unsubscribe := cashoutService.SubscribeCashoutResults()
+	defer unsubscribe()
+
+	returnedTxHash, err := cashoutService.CashCheque(context.Background(), vaultAddress, recipientAddress)
+	if !errors.Is(err, sendErr) {
+		t.Fatalf("wrong error. wanted %v, got %v", sendErr, err)
+	}
+	var cashoutErr *vault.CashoutError
+	if !errors.As(err, &cashoutErr) || cashoutErr.Phase != vault.CashoutPhaseSend {
+		t.Fatalf("expected send phase error, got %v", err)
+	}
+	if returnedTxHash != txHash {
+		t.Fatalf("returned wrong transaction hash. wanted %v, got %v", txHash, returnedTxHash)
+	}
+
+	var action vault.CashoutAction
+	if err := store.Get(vault.CashoutActionKey(vaultAddress), &action); err != nil {
+		t.Fatal(err)
+	}
+	if action.TxHash != txHash {
+		t.Fatalf("wrong action transaction %x", action.TxHash)
+	}
+
+	select {
+	case result := <-results:
+		if result.TxHash != txHash {
+			t.Fatalf("wrong result %+v", result)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("cashout not tracked")
+	}
+}
+
+func TestCashoutBounceRate(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	otherVault := common.HexToAddress("bbbb")
+	now := time.Unix(100000, 0)
+
+	store := storemock.NewStateStore()
+	for i, result := range []vault.CashOutResult{
+		{TxHash: common.HexToHash("01"), Vault: vaultAddress, CashTime: now.Unix() - 10, Status: "success", Bounced: true},
+		{TxHash: common.HexToHash("02"), Vault: vaultAddress, CashTime: now.Unix() - 20, Status: "success"},
+		{TxHash: common.HexToHash("03"), Vault: vaultAddress, CashTime: now.Unix() - 30, Status: "success"},
+		{TxHash: common.HexToHash("04"), Vault: vaultAddress, CashTime: now.Unix() - 40, Status: "success", Bounced: true},
+		// failed cashouts and cashouts outside the window are not counted
+		{TxHash: common.HexToHash("05"), Vault: vaultAddress, CashTime: now.Unix() - 50, Status: "fail"},
+		{TxHash: common.HexToHash("06"), Vault: vaultAddress, CashTime: now.Unix() - 7200, Status: "success", Bounced: true},
+		{TxHash: common.HexToHash("07"), Vault: otherVault, CashTime: now.Unix() - 7200, Status: "success", Bounced: true},
+	} {
+		result := result
+		result.Amount = big.NewInt(int64(i + 1))
+		if err := store.Put(statestore.CashoutResultKeyByTime(result.Vault, result.CashTime), &result); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cashoutService := vault.NewCashoutService(
+		store,
+		backendmock.New(),
+		transactionmock.New(),
+		chequestoremock.NewChequeStore(),
+		vault.WithClock(func() time.Time { return now }),
+	)
+	defer cashoutService.Close()
+
+	rate, err := cashoutService.BounceRate(vaultAddress, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rate != 0.5 {
+		t.Fatalf("wrong bounce rate. wanted 0.5, got %v", rate)
+	}
+
+	rate, err = cashoutService.BounceRate(otherVault, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rate != 0 {
+		t.Fatalf("expected no bounce rate without cashouts in the window, got %v", rate)
+	}
+}
+
+type paidOutProviderMock struct {
+	paidOut     *big.Int
+	vault       common.Address
+	beneficiary common.Address
+}
+
+func (p *paidOutProviderMock) PaidOut(ctx context.Context, vault, beneficiary common.Address) (*big.Int, error) {
+	p.vault = vault
+	p.beneficiary = beneficiary
+	return p.paidOut, nil
+}
+
+func TestCashoutStatusPaidOutProvider(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	beneficiary := common.HexToAddress("aaaa")
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      beneficiary,
+			CumulativePayout: big.NewInt(500),
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	provider := &paidOutProviderMock{paidOut: big.NewInt(200)}
+	cashoutService := vault.NewCashoutService(
+		storemock.NewStateStore(),
+		backendmock.New(),
+		// no paidOut call is configured, the provider must be used instead
+		transactionmock.New(),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheque, nil
+			}),
+		),
+		vault.WithPaidOutProvider(provider),
+	)
+	defer cashoutService.Close()
+
+	status, err := cashoutService.CashoutStatus(context.Background(), vaultAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if provider.vault != vaultAddress || provider.beneficiary != beneficiary {
+		t.Fatalf("provider queried for wrong vault %x or beneficiary %x", provider.vault, provider.beneficiary)
+	}
+	verifyStatus(t, status, vault.CashoutStatus{
+		UncashedAmount: big.NewInt(300),
+		State:          vault.CashoutStateNeverCashed,
+	})
+
+	provider.paidOut = nil
+	_, err = cashoutService.CashoutStatus(context.Background(), vaultAddress)
+	if !errors.Is(err, vault.ErrInvalidPaidOut) {
+		t.Fatalf("wrong error. wanted %v, got %v", vault.ErrInvalidPaidOut, err)
+	}
+}
+
+func TestCashoutVerifyTotals(t *testing.T) {
+	store := storemock.NewStateStore()
+	for _, result := range []vault.CashOutResult{
+		{TxHash: common.HexToHash("01"), Vault: common.HexToAddress("aaaa"), Amount: big.NewInt(100), CashTime: 1000, Status: "success"},
+		{TxHash: common.HexToHash("02"), Vault: common.HexToAddress("bbbb"), Amount: big.NewInt(200), CashTime: 2000, Status: "success"},
+		{TxHash: common.HexToHash("03"), Vault: common.HexToAddress("cccc"), Amount: big.NewInt(50), CashTime: 3000, Status: "fail"},
+	} {
+		result := result
+		if err := store.Put(statestore.CashoutResultKeyByTime(result.Vault, result.CashTime), &result); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Put(statestore.TotalReceivedCashedKey, big.NewInt(250)); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put(statestore.TotalReceivedCashedCountKey, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	cashoutService := vault.NewCashoutService(
+		store,
+		backendmock.New(),
+		transactionmock.New(),
+		chequestoremock.NewChequeStore(),
+		vault.WithTotalsVerification(10*time.Millisecond, true),
+	)
+	defer cashoutService.Close()
+
+	diff, err := cashoutService.VerifyTotals(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff.StoredCashed.Cmp(big.NewInt(250)) != 0 || diff.ResultCashed.Cmp(big.NewInt(300)) != 0 || diff.StoredCount != 1 || diff.ResultCount != 2 {
+		t.Fatalf("wrong diff %+v", diff)
+	}
+	if !diff.Drifted() {
+		t.Fatal("expected drift")
+	}
+
+	cashoutService.Start()
+	deadline := time.After(time.Second)
+	for diff.Drifted() {
+		select {
+		case <-deadline:
+			t.Fatalf("totals not corrected, diff %+v", diff)
+		case <-time.After(10 * time.Millisecond):
+		}
+		diff, err = cashoutService.VerifyTotals(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var total *big.Int
+	if err := store.Get(statestore.TotalReceivedCashedKey, &total); err != nil {
+		t.Fatal(err)
+	}
+	if total.Cmp(big.NewInt(300)) != 0 {
+		t.Fatalf("wrong corrected total %d", total)
+	}
+}
+
+func TestCashoutRecipientAllowlist(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	coldWallet := common.HexToAddress("cccc")
+	attacker := common.HexToAddress("6666")
+	txHash := common.HexToHash("dddd")
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: big.NewInt(500),
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	store := storemock.NewStateStore()
+	newService := func() vault.CashoutService {
+		return vault.NewCashoutService(
+			store,
+			backendmock.New(),
+			transactionmock.New(
+				transactionmock.WithABISend(&vaultABI, txHash, vaultAddress, big.NewInt(0), "cashChequeBeneficiary", coldWallet, cheque.CumulativePayout, cheque.Signature),
+			),
+			chequestoremock.NewChequeStore(
+				chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+					return cheque, nil
+				}),
+			),
+		)
+	}
+
+	if err := newService().SetRecipientAllowlist([]common.Address{coldWallet}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the allowlist is persisted
+	cashoutService := newService()
+	allowlist, err := cashoutService.RecipientAllowlist()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(allowlist) != 1 || allowlist[0] != coldWallet {
+		t.Fatalf("wrong allowlist %v", allowlist)
+	}
+
+	_, err = cashoutService.CashCheque(context.Background(), vaultAddress, attacker)
+	if !errors.Is(err, vault.ErrRecipientNotAllowed) {
+		t.Fatalf("wrong error. wanted %v, got %v", vault.ErrRecipientNotAllowed, err)
+	}
+	var cashoutErr *vault.CashoutError
+	if !errors.As(err, &cashoutErr) || cashoutErr.Phase != vault.CashoutPhasePolicy {
+		t.Fatalf("expected policy phase error, got %v", err)
+	}
+
+	returnedTxHash, err := cashoutService.CashCheque(context.Background(), vaultAddress, coldWallet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if returnedTxHash != txHash {
+		t.Fatalf("returned wrong transaction hash. wanted %v, got %v", txHash, returnedTxHash)
+	}
+
+	if err := cashoutService.SetRecipientAllowlist(nil); err != nil {
+		t.Fatal(err)
+	}
+	allowlist, err = cashoutService.RecipientAllowlist()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(allowlist) != 0 {
+		t.Fatalf("allowlist not cleared: %v", allowlist)
+	}
+}
+
+type recordedSpan struct {
+	name       string
+	parent     *recordedSpan
+	links      []*recordedSpan
+	attributes map[string]string
+	ended      chan struct{}
+}
+
+func (s *recordedSpan) SetAttribute(key, value string) { s.attributes[key] = value }
+func (s *recordedSpan) RecordError(err error)          { s.attributes["error"] = err.Error() }
+func (s *recordedSpan) End()                           { close(s.ended) }
+
+type recordedSpanKey struct{}
+
+type recordingTracer struct {
+	mu    sync.Mutex
+	spans []*recordedSpan
+}
+
+func (r *recordingTracer) Start(ctx context.Context, name string, links ...context.Context) (context.Context, vault.Span) {
+	span := &recordedSpan{name: name, attributes: make(map[string]string), ended: make(chan struct{})}
+	span.parent, _ = ctx.Value(recordedSpanKey{}).(*recordedSpan)
+	for _, link := range links {
+		if linked, ok := link.Value(recordedSpanKey{}).(*recordedSpan); ok {
+			span.links = append(span.links, linked)
+		}
+	}
+	r.mu.Lock()
+	r.spans = append(r.spans, span)
+	r.mu.Unlock()
+	return context.WithValue(ctx, recordedSpanKey{}, span), span
+}
+
+func (r *recordingTracer) span(name string) *recordedSpan {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, span := range r.spans {
+		if span.name == name {
+			return span
+		}
+	}
+	return nil
+}
+
+func TestCashoutTracing(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	txHash := common.HexToHash("dddd")
+	totalPayout := big.NewInt(100)
+	cumulativePayout := big.NewInt(500)
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: cumulativePayout,
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	tracer := &recordingTracer{}
+	cashoutService := vault.NewCashoutService(
+		storemock.NewStateStore(),
+		backendmock.New(
+			backendmock.WithTransactionByHashFunc(func(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+				return nil, false, nil
+			}),
+			backendmock.WithTransactionReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				logData, err := chequeCashedEventType.Inputs.NonIndexed().Pack(totalPayout, cumulativePayout, big.NewInt(0))
+				if err != nil {
+					t.Fatal(err)
+				}
+				return &types.Receipt{
+					Status: types.ReceiptStatusSuccessful,
+					Logs: []*types.Log{
+						{
+							Address: vaultAddress,
+							Topics:  []common.Hash{chequeCashedEventType.ID, cheque.Beneficiary.Hash(), recipientAddress.Hash(), cheque.Beneficiary.Hash()},
+							Data:    logData,
+						},
+					},
+				}, nil
+			}),
+		),
+		transactionmock.New(
+			transactionmock.WithABISend(&vaultABI, txHash, vaultAddress, big.NewInt(0), "cashChequeBeneficiary", recipientAddress, cheque.CumulativePayout, cheque.Signature),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheque, nil
+			}),
+		),
+		vault.WithTracer(tracer),
+		vault.WithReceiptPollingInterval(time.Millisecond),
+	)
+	defer cashoutService.Close()
+
+	_, err := cashoutService.CashCheque(context.Background(), vaultAddress, recipientAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cashSpan := tracer.span("vault.CashCheque")
+	if cashSpan == nil {
+		t.Fatal("no cashout span")
+	}
+	<-cashSpan.ended
+	if cashSpan.attributes[vault.TraceAttributeVault] != vaultAddress.Hex() ||
+		cashSpan.attributes[vault.TraceAttributeTxHash] != txHash.Hex() ||
+		cashSpan.attributes[vault.TraceAttributeAmount] != cumulativePayout.String() {
+		t.Fatalf("wrong cashout span attributes %v", cashSpan.attributes)
+	}
+
+	var storeSpan *recordedSpan
+	deadline := time.After(time.Second)
+	for storeSpan == nil {
+		select {
+		case <-deadline:
+			t.Fatal("no span for storing the result")
+		case <-time.After(5 * time.Millisecond):
+		}
+		storeSpan = tracer.span("vault.storeCashResult")
+	}
+	select {
+	case <-storeSpan.ended:
+	case <-time.After(time.Second):
+		t.Fatal("span for storing the result not ended")
+	}
+	if storeSpan.parent != nil || len(storeSpan.links) != 1 || storeSpan.links[0] != cashSpan {
+		t.Fatal("span for storing the result not linked to the cashout span")
+	}
+	if storeSpan.attributes[vault.TraceAttributeAmount] != totalPayout.String() || storeSpan.attributes[vault.TraceAttributeStatus] != "success" {
+		t.Fatalf("wrong attributes of span for storing the result %v", storeSpan.attributes)
+	}
+
+	receiptSpan := tracer.span("backend.TransactionReceipt")
+	if receiptSpan == nil || receiptSpan.attributes[vault.TraceAttributeTxHash] != txHash.Hex() {
+		t.Fatal("backend call not traced")
+	}
+	if receiptSpan.parent == nil || (receiptSpan.parent != storeSpan && receiptSpan.parent.parent != storeSpan) {
+		t.Fatal("backend call not traced within the span for storing the result")
+	}
+}
+
+func TestCashChequeOptimal(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	beneficiary := common.HexToAddress("aaaa")
+	txHash := common.HexToHash("dddd")
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      beneficiary,
+			CumulativePayout: big.NewInt(500),
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	gasPrice := big.NewInt(5)
+	cashoutService := vault.NewCashoutService(
+		storemock.NewStateStore(),
+		backendmock.New(
+			backendmock.WithEstimateGasFunc(func(ctx context.Context, call ethereum.CallMsg) (uint64, error) {
+				return 100, nil
+			}),
+			backendmock.WithSuggestGasPriceFunc(func(ctx context.Context) (*big.Int, error) {
+				return gasPrice, nil
+			}),
+		),
+		transactionmock.New(
+			transactionmock.WithABICallSequence(
+				transactionmock.ABICall(&vaultABI, vaultAddress, big.NewInt(0).FillBytes(make([]byte, 32)), "paidOut", beneficiary),
+				transactionmock.ABICall(&vaultABI, vaultAddress, big.NewInt(0).FillBytes(make([]byte, 32)), "paidOut", beneficiary),
+			),
+			transactionmock.WithABISend(&vaultABI, txHash, vaultAddress, big.NewInt(0), "cashChequeBeneficiary", recipientAddress, cheque.CumulativePayout, cheque.Signature),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheque, nil
+			}),
+		),
+	)
+	defer cashoutService.Close()
+
+	// a gas cost of 500 eats the entire uncashed amount
+	_, err := cashoutService.CashChequeOptimal(context.Background(), vaultAddress, recipientAddress)
+	if !errors.Is(err, vault.ErrUnprofitable) {
+		t.Fatalf("wrong error. wanted %v, got %v", vault.ErrUnprofitable, err)
+	}
+
+	gasPrice = big.NewInt(4)
+	returnedTxHash, err := cashoutService.CashChequeOptimal(context.Background(), vaultAddress, recipientAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if returnedTxHash != txHash {
+		t.Fatalf("returned wrong transaction hash. wanted %v, got %v", txHash, returnedTxHash)
+	}
+}
+
+func TestCashoutEvents(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	txHash := common.HexToHash("dddd")
+	totalPayout := big.NewInt(100)
+	cumulativePayout := big.NewInt(500)
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: cumulativePayout,
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	cashoutService := vault.NewCashoutService(
+		storemock.NewStateStore(),
+		backendmock.New(
+			backendmock.WithTransactionByHashFunc(func(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+				return nil, false, nil
+			}),
+			backendmock.WithTransactionReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				logData, err := chequeCashedEventType.Inputs.NonIndexed().Pack(totalPayout, cumulativePayout, big.NewInt(0))
+				if err != nil {
+					t.Fatal(err)
+				}
+				return &types.Receipt{
+					Status: types.ReceiptStatusSuccessful,
+					Logs: []*types.Log{
+						{
+							Address: vaultAddress,
+							Topics:  []common.Hash{chequeCashedEventType.ID, cheque.Beneficiary.Hash(), recipientAddress.Hash(), cheque.Beneficiary.Hash()},
+							Data:    logData,
+						},
+						{
+							Address: vaultAddress,
+							Topics:  []common.Hash{chequeBouncedEventType.ID},
+						},
+					},
+				}, nil
+			}),
+		),
+		transactionmock.New(
+			transactionmock.WithABISend(&vaultABI, txHash, vaultAddress, big.NewInt(0), "cashChequeBeneficiary", recipientAddress, cheque.CumulativePayout, cheque.Signature),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheque, nil
+			}),
+		),
+		vault.WithReceiptPollingInterval(time.Millisecond),
+	)
+	defer cashoutService.Close()
+
+	results, unsubscribe := cashoutService.SubscribeCashoutResults()
+	defer unsubscribe()
+
+	_, err := cashoutService.CashCheque(context.Background(), vaultAddress, recipientAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-results:
+	case <-time.After(time.Second):
+		t.Fatal("no result published")
+	}
+
+	events, err := cashoutService.CashoutEvents(vaultAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []vault.CashoutEventType{
+		vault.CashoutEventSubmitted,
+		vault.CashoutEventConfirming,
+		vault.CashoutEventConfirmed,
+		vault.CashoutEventBounced,
+	}
+	if len(events) != len(want) {
+		t.Fatalf("wrong events %v", events)
+	}
+	for i, event := range events {
+		if event.Type != want[i] || event.TxHash != txHash {
+			t.Fatalf("wrong event %d: %+v", i, event)
+		}
+		if i > 0 && event.Time < events[i-1].Time {
+			t.Fatalf("events out of order: %v", events)
+		}
+	}
+
+	events, err = cashoutService.CashoutEvents(common.HexToAddress("bbbb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("unexpected events of other vault %v", events)
+	}
+}
+
+func TestCashoutPauseRetries(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	txHash := common.HexToHash("dddd")
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: big.NewInt(500),
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+	chequeStore := chequestoremock.NewChequeStore(
+		chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+			return cheque, nil
+		}),
+	)
+
+	// the first service never finishes waiting for the receipt, leaving the cashout in the write-ahead log
+	block := make(chan struct{})
+	defer close(block)
+	store := storemock.NewStateStore()
+	cashoutService := vault.NewCashoutService(
+		store,
+		backendmock.New(),
+		transactionmock.New(
+			transactionmock.WithABISend(&vaultABI, txHash, vaultAddress, big.NewInt(0), "cashChequeBeneficiary", recipientAddress, cheque.CumulativePayout, cheque.Signature),
+			transactionmock.WithWaitForReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				<-block
+				return nil, context.Canceled
+			}),
+		),
+		chequeStore,
+	)
+
+	_, err := cashoutService.CashCheque(context.Background(), vaultAddress, recipientAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	waited := make(chan common.Hash, 1)
+	restarted := vault.NewCashoutService(
+		store,
+		backendmock.New(),
+		transactionmock.New(
+			transactionmock.WithWaitForReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				waited <- hash
+				return nil, context.Canceled
+			}),
+		),
+		chequeStore,
+	)
+	restarted.PauseRetries()
+	restarted.Start()
+	defer restarted.Close()
+
+	deadline := time.After(time.Second)
+	for {
+		paused, depth := restarted.RetryQueueStatus()
+		if !paused {
+			t.Fatal("retries not paused")
+		}
+		if depth == 1 {
+			break
+		}
+		select {
+		case <-deadline:
+			t.Fatalf("wrong retry queue depth %d", depth)
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+
+	select {
+	case <-waited:
+		t.Fatal("paused retry checked the receipt")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	restarted.ResumeRetries()
+	select {
+	case hash := <-waited:
+		if hash != txHash {
+			t.Fatalf("resumed wrong transaction. wanted %v, got %v", txHash, hash)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("retry was not resumed")
+	}
+
+	deadline = time.After(time.Second)
+	for {
+		paused, depth := restarted.RetryQueueStatus()
+		if !paused && depth == 0 {
+			break
+		}
+		select {
+		case <-deadline:
+			t.Fatalf("wrong retry queue status, paused %v, depth %d", paused, depth)
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+}
+
+func TestCashoutResultRecipient(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	oldVault := common.HexToAddress("bbbb")
+	recipientAddress := common.HexToAddress("efff")
+	txHash := common.HexToHash("dddd")
+	totalPayout := big.NewInt(100)
+	cumulativePayout := big.NewInt(500)
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: cumulativePayout,
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	// a result stored before the recipient was recorded
+	store := storemock.NewStateStore()
+	oldResult := struct {
+		TxHash   common.Hash
+		Vault    common.Address
+		Amount   *big.Int
+		CashTime int64
+		Status   string
+	}{common.HexToHash("01"), oldVault, big.NewInt(1), 1000, "success"}
+	if err := store.Put(statestore.CashoutResultKeyByTime(oldVault, oldResult.CashTime), &oldResult); err != nil {
+		t.Fatal(err)
+	}
+
+	cashoutService := vault.NewCashoutService(
+		store,
+		backendmock.New(
+			backendmock.WithTransactionByHashFunc(func(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+				return nil, false, nil
+			}),
+			backendmock.WithTransactionReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				logData, err := chequeCashedEventType.Inputs.NonIndexed().Pack(totalPayout, cumulativePayout, big.NewInt(0))
+				if err != nil {
+					t.Fatal(err)
+				}
+				return &types.Receipt{
+					Status: types.ReceiptStatusSuccessful,
+					Logs: []*types.Log{
+						{
+							Address: vaultAddress,
+							Topics:  []common.Hash{chequeCashedEventType.ID, cheque.Beneficiary.Hash(), recipientAddress.Hash(), cheque.Beneficiary.Hash()},
+							Data:    logData,
+						},
+					},
+				}, nil
+			}),
+		),
+		transactionmock.New(
+			transactionmock.WithABISend(&vaultABI, txHash, vaultAddress, big.NewInt(0), "cashChequeBeneficiary", recipientAddress, cheque.CumulativePayout, cheque.Signature),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheque, nil
+			}),
+		),
+		vault.WithReceiptPollingInterval(time.Millisecond),
+	)
+	defer cashoutService.Close()
+
+	results, unsubscribe := cashoutService.SubscribeCashoutResults()
+	defer unsubscribe()
+
+	_, err := cashoutService.CashCheque(context.Background(), vaultAddress, recipientAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case result := <-results:
+		if result.Recipient != recipientAddress {
+			t.Fatalf("wrong published recipient %x", result.Recipient)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no result published")
+	}
+
+	stored, err := cashoutService.CashoutResults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 2 {
+		t.Fatalf("wrong results %v", stored)
+	}
+	for _, result := range stored {
+		switch result.Vault {
+		case vaultAddress:
+			if result.Recipient != recipientAddress {
+				t.Fatalf("wrong stored recipient %x", result.Recipient)
+			}
+		case oldVault:
+			if result.Recipient != (common.Address{}) || result.Amount.Cmp(big.NewInt(1)) != 0 {
+				t.Fatalf("old result decoded wrongly %+v", result)
+			}
+		}
+	}
+}
+
+type subscribingChequeStore struct {
+	vault.ChequeStore
+	received chan *vault.SignedCheque
+}
+
+func (s *subscribingChequeStore) SubscribeReceivedCheques() (<-chan *vault.SignedCheque, func()) {
+	return s.received, func() {}
+}
+
+func TestAutoCashoutOnReceivedCheque(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	txHash := common.HexToHash("dddd")
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: big.NewInt(500),
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	sent := make(chan struct{}, 1)
+	chequeStore := &subscribingChequeStore{
+		ChequeStore: chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheque, nil
+			}),
+			chequestoremock.WithLastChequesFunc(func() (map[common.Address]*vault.SignedCheque, error) {
+				t.Error("periodic iteration ran")
+				return nil, nil
+			}),
+		),
+		received: make(chan *vault.SignedCheque),
+	}
+	cashoutService := vault.NewCashoutService(
+		storemock.NewStateStore(),
+		backendmock.New(),
+		transactionmock.New(
+			transactionmock.WithCallFunc(func(ctx context.Context, request *transaction.TxRequest) ([]byte, error) {
+				return big.NewInt(0).FillBytes(make([]byte, 32)), nil
+			}),
+			transactionmock.WithSendFunc(func(ctx context.Context, request *transaction.TxRequest) (common.Hash, error) {
+				select {
+				case sent <- struct{}{}:
+				default:
+				}
+				return txHash, nil
+			}),
+		),
+		chequeStore,
+		vault.WithAutoCashout(vault.AutoCashoutConfig{
+			Recipient:    recipientAddress,
+			LoopInterval: time.Hour,
+			MinAmount:    big.NewInt(100),
+		}),
+	)
+	cashoutService.Start()
+	defer cashoutService.Close()
+
+	select {
+	case chequeStore.received <- cheque:
+	case <-time.After(time.Second):
+		t.Fatal("loop did not subscribe to received cheques")
+	}
+
+	select {
+	case <-sent:
+	case <-time.After(time.Second):
+		t.Fatal("received cheque was not cashed")
+	}
+
+	// the decision is recorded right after sending
+	deadline := time.After(time.Second)
+	for {
+		decision, err := cashoutService.LastAutoCashoutDecision(vaultAddress)
+		if err == nil && decision.Reason == vault.AutoDecisionCashed {
+			break
+		}
+		select {
+		case <-deadline:
+			t.Fatalf("wrong decision %+v, %v", decision, err)
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+}
+
+func TestCashoutForgetVault(t *testing.T) {
+	forgotten := common.HexToAddress("abcd")
+	kept := common.HexToAddress("bbbb")
+	token := common.HexToAddress("1111")
+	actionTxHash := common.HexToHash("dddd")
+	day1 := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+	day1Key := statestore.GetTotalDailyReceivedCashedKeyByTime(day1.Truncate(24 * time.Hour).Unix())
+	day2Key := statestore.GetTotalDailyReceivedCashedKeyByTime(day2.Truncate(24 * time.Hour).Unix())
+	day2TokenKey := statestore.GetTotalDailyReceivedCashedByTokenKeyByTime(day2.Truncate(24*time.Hour).Unix(), token)
+
+	store := storemock.NewStateStore()
+	for _, result := range []vault.CashOutResult{
+		{TxHash: common.HexToHash("01"), Vault: forgotten, Amount: big.NewInt(100), CashTime: day1.Unix(), Status: "success", Token: token, CashedCount: 2, CallerPayout: big.NewInt(10)},
+		{TxHash: common.HexToHash("02"), Vault: forgotten, Amount: big.NewInt(200), CashTime: day2.Unix(), Status: "success", Token: token, CashedCount: 1, CallerPayout: big.NewInt(0)},
+		{TxHash: common.HexToHash("03"), Vault: forgotten, Amount: big.NewInt(500), CashTime: day2.Unix() + 1, Status: "fail"},
+		// rebuilt from chain, never added to the totals
+		{TxHash: common.HexToHash("05"), Vault: forgotten, Amount: big.NewInt(70), CashTime: day2.Unix() + 2, Status: "success", Rebuilt: true},
+		{TxHash: common.HexToHash("04"), Vault: kept, Amount: big.NewInt(50), CashTime: day2.Unix(), Status: "success", Token: token, CashedCount: 2, CallerPayout: big.NewInt(5)},
+	} {
+		result := result
+		if err := store.Put(statestore.CashoutResultKeyByTime(result.Vault, result.CashTime), &result); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for key, value := range map[string]interface{}{
+		statestore.TotalReceivedCashedKey:               big.NewInt(350),
+		statestore.TotalReceivedCashedCountKey:          5,
+		statestore.TotalReceivedCashedByTokenKey(token): big.NewInt(350),
+		statestore.TotalCallerPayoutKey:                 big.NewInt(15),
+		day1Key:                                         big.NewInt(100),
+		day2Key:                                         big.NewInt(250),
+		day2TokenKey:                                    big.NewInt(250),
+		vault.CashoutActionKey(forgotten):               &vault.CashoutAction{TxHash: actionTxHash},
+	} {
+		if err := store.Put(key, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pending := true
+	cashoutService := vault.NewCashoutService(
+		store,
+		backendmock.New(
+			backendmock.WithTransactionByHashFunc(func(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+				if hash != actionTxHash {
+					t.Fatalf("checking wrong transaction %x", hash)
+				}
+				return nil, pending, nil
+			}),
+		),
+		transactionmock.New(),
+		chequestoremock.NewChequeStore(),
+	)
+	defer cashoutService.Close()
+
+	if err := cashoutService.SetDefaultRecipient(forgotten, common.HexToAddress("efff")); err != nil {
+		t.Fatal(err)
+	}
+
+	err := cashoutService.ForgetVault(context.Background(), forgotten)
+	if !errors.Is(err, vault.ErrCashoutPending) {
+		t.Fatalf("wrong error. wanted %v, got %v", vault.ErrCashoutPending, err)
+	}
+
+	pending = false
+	err = cashoutService.ForgetVault(context.Background(), forgotten)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := cashoutService.CashoutResults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Vault != kept {
+		t.Fatalf("wrong remaining results %v", results)
+	}
+
+	for key, want := range map[string]*big.Int{
+		statestore.TotalReceivedCashedKey:               big.NewInt(50),
+		statestore.TotalReceivedCashedByTokenKey(token): big.NewInt(50),
+		statestore.TotalCallerPayoutKey:                 big.NewInt(5),
+		day1Key:                                         big.NewInt(0),
+		day2Key:                                         big.NewInt(50),
+		day2TokenKey:                                    big.NewInt(50),
+	} {
+		var total *big.Int
+		if err := store.Get(key, &total); err != nil {
+			t.Fatal(err)
+		}
+		if total.Cmp(want) != 0 {
+			t.Fatalf("wrong total %s. wanted %d, got %d", key, want, total)
+		}
+	}
+	var count int
+	if err := store.Get(statestore.TotalReceivedCashedCountKey, &count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Fatalf("wrong cashed count %d", count)
+	}
+
+	err = store.Get(vault.CashoutActionKey(forgotten), &vault.CashoutAction{})
+	if !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("action not removed: %v", err)
+	}
+	_, err = cashoutService.DefaultRecipient(forgotten)
+	if !errors.Is(err, vault.ErrNoRecipient) {
+		t.Fatalf("default recipient not removed: %v", err)
+	}
+}
+
+func TestCashoutRecoverableValue(t *testing.T) {
+	coveredVault := common.HexToAddress("abcd")
+	bouncingVault := common.HexToAddress("bcde")
+	brokenVault := common.HexToAddress("cdef")
+
+	cheques := make(map[common.Address]*vault.SignedCheque)
+	for v, amount := range map[common.Address]int64{coveredVault: 500, bouncingVault: 900, brokenVault: 50} {
+		cheques[v] = &vault.SignedCheque{
+			Cheque: vault.Cheque{
+				Beneficiary:      common.HexToAddress("aaaa"),
+				CumulativePayout: big.NewInt(amount),
+				Vault:            v,
+			},
+			Signature: []byte{},
+		}
+	}
+
+	cashoutService := vault.NewCashoutService(
+		storemock.NewStateStore(),
+		backendmock.New(),
+		transactionmock.New(
+			transactionmock.WithCallFunc(func(ctx context.Context, request *transaction.TxRequest) ([]byte, error) {
+				if *request.To == brokenVault {
+					return nil, errors.New("vault unreachable")
+				}
+				// every other vault holds 600 and nothing was paid out yet
+				if bytes.HasPrefix(request.Data, vaultABI.Methods["totalbalance"].ID) {
+					return big.NewInt(600).FillBytes(make([]byte, 32)), nil
+				}
+				return big.NewInt(0).FillBytes(make([]byte, 32)), nil
+			}),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheques[c], nil
+			}),
+			chequestoremock.WithLastChequesFunc(func() (map[common.Address]*vault.SignedCheque, error) {
+				return cheques, nil
+			}),
+		),
+	)
+
+	value, err := cashoutService.RecoverableValue(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 500 of the covered vault and the 600 balance of the bouncing vault
+	if value.Cmp(big.NewInt(1100)) != 0 {
+		t.Fatalf("wrong recoverable value. wanted 1100, got %v", value)
+	}
+}
+
+func TestCashoutTxMetadata(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	txHash := common.HexToHash("dddd")
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: big.NewInt(500),
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	var description string
+	cashoutService := vault.NewCashoutService(
+		storemock.NewStateStore(),
+		backendmock.New(),
+		transactionmock.New(
+			transactionmock.WithSendFunc(func(ctx context.Context, request *transaction.TxRequest) (common.Hash, error) {
+				description = request.Description
+				return txHash, nil
+			}),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheque, nil
+			}),
+		),
+	)
+	defer cashoutService.Close()
+
+	_, err := cashoutService.CashChequeWithOptions(context.Background(), vaultAddress, recipientAddress, vault.CashoutOptions{Label: "march payout"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	meta, rest, ok := vault.ParseCashoutTxDescription(description)
+	if !ok {
+		t.Fatalf("no metadata in description %q", description)
+	}
+	if meta.Vault != vaultAddress || meta.Amount.Cmp(big.NewInt(500)) != 0 || meta.Label != "march payout" || meta.Key == "" {
+		t.Fatalf("wrong metadata %+v", meta)
+	}
+	if rest != "cheque cashout" {
+		t.Fatalf("wrong plain description %q", rest)
+	}
+
+	if _, rest, ok := vault.ParseCashoutTxDescription("vault deployment"); ok || rest != "vault deployment" {
+		t.Fatal("metadata parsed from a plain description")
+	}
+}
+
+func TestIsCashableLocal(t *testing.T) {
+	cheque := vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: big.NewInt(500),
+			Vault:            common.HexToAddress("abcd"),
+		},
+	}
+
+	for _, tc := range []struct {
+		name     string
+		paidOut  *big.Int
+		cashable bool
+		amount   int64
+	}{
+		{"nothing paid out", nil, true, 500},
+		{"partly paid out", big.NewInt(200), true, 300},
+		{"fully paid out", big.NewInt(500), false, 0},
+		{"paid out more", big.NewInt(700), false, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cashable, amount := vault.IsCashableLocal(cheque, tc.paidOut)
+			if cashable != tc.cashable || amount.Cmp(big.NewInt(tc.amount)) != 0 {
+				t.Fatalf("got %v %v, wanted %v %d", cashable, amount, tc.cashable, tc.amount)
+			}
+		})
+	}
+}
+
+func TestCashoutChainNamespace(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	store := storemock.NewStateStore()
+
+	result := vault.CashOutResult{TxHash: common.HexToHash("01"), Vault: vaultAddress, Amount: big.NewInt(300), CashTime: 1000, Status: "success"}
+	for key, value := range map[string]interface{}{
+		statestore.CashoutResultKeyByTime(vaultAddress, result.CashTime): &result,
+		statestore.TotalReceivedCashedKey:                                big.NewInt(300),
+		statestore.TotalReceivedKey:                                      big.NewInt(500),
+		// conflicts with the record already in the namespace
+		statestore.LastCashoutTimeKey:              int64(1000),
+		"chain_1_" + statestore.LastCashoutTimeKey: int64(2000),
+	} {
+		if err := store.Put(key, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	moved, err := vault.MigrateToChainNamespace(store, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved != 2 {
+		t.Fatalf("expected the result and the cashed total to be moved, moved %d", moved)
+	}
+	var lastCashoutTime int64
+	if err := store.Get(statestore.LastCashoutTimeKey, &lastCashoutTime); err != storage.ErrNotFound {
+		t.Fatalf("conflicting record without namespace left in place: %v", err)
+	}
+	if err := store.Get("chain_1_"+statestore.LastCashoutTimeKey, &lastCashoutTime); err != nil || lastCashoutTime != 2000 {
+		t.Fatalf("record of the namespace replaced by %d: %v", lastCashoutTime, err)
+	}
+
+	// the migration runs once, records written without namespace afterwards are not picked up
+	if err := store.Put(statestore.TotalCallerPayoutKey, big.NewInt(10)); err != nil {
+		t.Fatal(err)
+	}
+	moved, err = vault.MigrateToChainNamespace(store, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved != 0 {
+		t.Fatalf("migration ran again and moved %d records", moved)
+	}
+
+	mainnet := vault.ChainNamespacedStore(store, 1)
+	testnet := vault.ChainNamespacedStore(store, 2)
+
+	results, err := vault.NewCashoutService(mainnet, backendmock.New(), transactionmock.New(), chequestoremock.NewChequeStore()).CashoutResults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].TxHash != result.TxHash {
+		t.Fatalf("migrated results not found, got %v", results)
+	}
+	results, err = vault.NewCashoutService(testnet, backendmock.New(), transactionmock.New(), chequestoremock.NewChequeStore()).CashoutResults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 0 {
+		t.Fatalf("results of another chain found %v", results)
+	}
+
+	var total *big.Int
+	if err := mainnet.Get(statestore.TotalReceivedCashedKey, &total); err != nil || total.Cmp(big.NewInt(300)) != 0 {
+		t.Fatalf("wrong cashed total %v: %v", total, err)
+	}
+	if err := testnet.Get(statestore.TotalReceivedCashedKey, &total); err != storage.ErrNotFound {
+		t.Fatalf("cashed total of another chain found: %v", err)
+	}
+	// keys of the cheque store are shared by all chains
+	if err := testnet.Get(statestore.TotalReceivedKey, &total); err != nil || total.Cmp(big.NewInt(500)) != 0 {
+		t.Fatalf("wrong received total %v: %v", total, err)
+	}
+}
+
+func TestCashChequeWithCallback(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	txHash := common.HexToHash("dddd")
+	cumulativePayout := big.NewInt(500)
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: cumulativePayout,
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	cashoutService := vault.NewCashoutService(
+		storemock.NewStateStore(),
+		backendmock.New(
+			backendmock.WithTransactionByHashFunc(func(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+				return nil, false, nil
+			}),
+			backendmock.WithTransactionReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				logData, err := chequeCashedEventType.Inputs.NonIndexed().Pack(cumulativePayout, cumulativePayout, big.NewInt(0))
+				if err != nil {
+					t.Fatal(err)
+				}
+				return &types.Receipt{
+					Status: types.ReceiptStatusSuccessful,
+					Logs: []*types.Log{
+						{
+							Address: vaultAddress,
+							Topics:  []common.Hash{chequeCashedEventType.ID, cheque.Beneficiary.Hash(), recipientAddress.Hash(), cheque.Beneficiary.Hash()},
+							Data:    logData,
+						},
+					},
+				}, nil
+			}),
+		),
+		transactionmock.New(
+			transactionmock.WithABISend(&vaultABI, txHash, vaultAddress, big.NewInt(0), "cashChequeBeneficiary", recipientAddress, cheque.CumulativePayout, cheque.Signature),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				if c != vaultAddress {
+					return nil, vault.ErrNoCheque
+				}
+				return cheque, nil
+			}),
+		),
+		vault.WithReceiptPollingInterval(time.Millisecond),
+	)
+	defer cashoutService.Close()
+
+	type outcome struct {
+		result *vault.CashOutResult
+		err    error
+	}
+	outcomes := make(chan outcome, 2)
+	callback := func(result *vault.CashOutResult, err error) {
+		outcomes <- outcome{result, err}
+	}
+
+	_, err := cashoutService.CashChequeWithCallback(context.Background(), vaultAddress, recipientAddress, callback)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case o := <-outcomes:
+		if o.err != nil {
+			t.Fatal(o.err)
+		}
+		if o.result.TxHash != txHash || o.result.Status != "success" || o.result.Amount.Cmp(cumulativePayout) != 0 {
+			t.Fatalf("wrong result %+v", o.result)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("callback not called")
+	}
+
+	_, err = cashoutService.CashChequeWithCallback(context.Background(), common.HexToAddress("bbbb"), recipientAddress, callback)
+	if !errors.Is(err, vault.ErrNoCheque) {
+		t.Fatalf("wrong error %v", err)
+	}
+	select {
+	case o := <-outcomes:
+		if !errors.Is(o.err, vault.ErrNoCheque) || o.result != nil {
+			t.Fatalf("wrong outcome %+v", o)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("callback not called with the error")
+	}
+
+	select {
+	case o := <-outcomes:
+		t.Fatalf("callback called again with %+v", o)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestCashoutVaultDestroyed(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: big.NewInt(500),
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	store := storemock.NewStateStore()
+	result := vault.CashOutResult{TxHash: common.HexToHash("01"), Vault: vaultAddress, Amount: big.NewInt(200), CashTime: 1000, Status: "success"}
+	if err := store.Put(statestore.CashoutResultKeyByTime(vaultAddress, result.CashTime), &result); err != nil {
+		t.Fatal(err)
+	}
+
+	codeReads := 0
+	cashoutService := vault.NewCashoutService(
+		store,
+		backendmock.New(
+			backendmock.WithCodeAtFunc(func(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+				codeReads++
+				return nil, nil
+			}),
+		),
+		transactionmock.New(),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheque, nil
+			}),
+		),
+	)
+
+	_, err := cashoutService.CashoutStatus(context.Background(), vaultAddress)
+	if !errors.Is(err, vault.ErrVaultDestroyed) {
+		t.Fatalf("wrong status error %v", err)
+	}
+
+	_, err = cashoutService.CashCheque(context.Background(), vaultAddress, common.HexToAddress("efff"))
+	var cashoutErr *vault.CashoutError
+	if !errors.As(err, &cashoutErr) || cashoutErr.Phase != vault.CashoutPhaseValidate || !errors.Is(err, vault.ErrVaultDestroyed) {
+		t.Fatalf("wrong cashout error %v", err)
+	}
+	if codeReads != 1 {
+		t.Fatalf("expected the code to be read once, read %d times", codeReads)
+	}
+
+	destroyed, err := cashoutService.DestroyedVaults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(destroyed) != 1 || destroyed[0].Vault != vaultAddress || destroyed[0].Unrecoverable.Cmp(big.NewInt(300)) != 0 {
+		t.Fatalf("wrong destroyed vaults %+v", destroyed)
+	}
+}
+
+// batchingBackend answers receipt batches from a map and fails all individual transaction lookups
+type batchingBackend struct {
+	transaction.Backend
+	receipts map[common.Hash]*types.Receipt
+	batches  int
+}
+
+func (b *batchingBackend) BatchCallContext(ctx context.Context, batch []rpc.BatchElem) error {
+	b.batches++
+	for _, elem := range batch {
+		hash := elem.Args[0].(common.Hash)
+		*elem.Result.(**types.Receipt) = b.receipts[hash]
+	}
+	return nil
+}
+
+func TestCashoutStatusBatch(t *testing.T) {
+	minedVault := common.HexToAddress("abcd")
+	revertedVault := common.HexToAddress("bcde")
+	neverCashedVault := common.HexToAddress("cdef")
+	beneficiary := common.HexToAddress("aaaa")
+	minedTx := common.HexToHash("01")
+	revertedTx := common.HexToHash("02")
+
+	cheques := make(map[common.Address]*vault.SignedCheque)
+	for _, v := range []common.Address{minedVault, revertedVault, neverCashedVault} {
+		cheques[v] = &vault.SignedCheque{
+			Cheque: vault.Cheque{
+				Beneficiary:      beneficiary,
+				CumulativePayout: big.NewInt(500),
+				Vault:            v,
+			},
+			Signature: []byte{},
+		}
+	}
+
+	logData, err := chequeCashedEventType.Inputs.NonIndexed().Pack(big.NewInt(500), big.NewInt(500), big.NewInt(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend := &batchingBackend{
+		Backend: backendmock.New(),
+		receipts: map[common.Hash]*types.Receipt{
+			minedTx: {
+				Status: types.ReceiptStatusSuccessful,
+				Logs: []*types.Log{
+					{
+						Address: minedVault,
+						Topics:  []common.Hash{chequeCashedEventType.ID, beneficiary.Hash(), beneficiary.Hash(), beneficiary.Hash()},
+						Data:    logData,
+					},
+				},
+			},
+			revertedTx: {Status: types.ReceiptStatusFailed},
+		},
+	}
+
+	store := storemock.NewStateStore()
+	for v, txHash := range map[common.Address]common.Hash{minedVault: minedTx, revertedVault: revertedTx} {
+		if err := store.Put(vault.CashoutActionKey(v), &vault.CashoutAction{TxHash: txHash, Cheque: *cheques[v]}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cashoutService := vault.NewCashoutService(
+		store,
+		backend,
+		transactionmock.New(
+			transactionmock.WithCallFunc(func(ctx context.Context, request *transaction.TxRequest) ([]byte, error) {
+				// paidOut of the reverted and the never cashed vault
+				return big.NewInt(100).FillBytes(make([]byte, 32)), nil
+			}),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheques[c], nil
+			}),
+		),
+	)
+
+	statuses, err := cashoutService.CashoutStatusBatch(context.Background(), []common.Address{minedVault, revertedVault, neverCashedVault})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if backend.batches != 1 {
+		t.Fatalf("expected one receipt batch, got %d", backend.batches)
+	}
+	if len(statuses) != 3 {
+		t.Fatalf("wrong number of statuses %d", len(statuses))
+	}
+	for i, want := range []struct {
+		vault    common.Address
+		state    vault.CashoutState
+		uncashed int64
+	}{
+		{minedVault, vault.CashoutStateConfirmed, 0},
+		{revertedVault, vault.CashoutStateReverted, 400},
+		{neverCashedVault, vault.CashoutStateNeverCashed, 400},
+	} {
+		got := statuses[i]
+		if got.Err != nil {
+			t.Fatalf("status of vault %x failed: %v", want.vault, got.Err)
+		}
+		if got.Vault != want.vault || got.Status.State != want.state || got.Status.UncashedAmount.Cmp(big.NewInt(want.uncashed)) != 0 {
+			t.Fatalf("wrong status of vault %x: %+v", want.vault, got.Status)
+		}
+	}
+}
+
+func TestCashoutImportVaults(t *testing.T) {
+	importedVault := common.HexToAddress("abcd")
+	forgedVault := common.HexToAddress("bcde")
+	beneficiary := common.HexToAddress("aaaa")
+	issuer := common.HexToAddress("1111")
+
+	entry := func(v common.Address, amount int64, signature byte) vault.ImportEntry {
+		return vault.ImportEntry{
+			Vault:       v,
+			Beneficiary: beneficiary,
+			Cheque: vault.SignedCheque{
+				Cheque: vault.Cheque{
+					Beneficiary:      beneficiary,
+					CumulativePayout: big.NewInt(amount),
+					Vault:            v,
+				},
+				Signature: []byte{signature},
+			},
+		}
+	}
+
+	cashoutService := vault.NewCashoutService(
+		storemock.NewStateStore(),
+		backendmock.New(),
+		transactionmock.New(
+			transactionmock.WithCallFunc(func(ctx context.Context, request *transaction.TxRequest) ([]byte, error) {
+				if bytes.HasPrefix(request.Data, vaultABI.Methods["issuer"].ID) {
+					return issuer.Hash().Bytes(), nil
+				}
+				// nothing was paid out yet
+				return big.NewInt(0).FillBytes(make([]byte, 32)), nil
+			}),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return nil, vault.ErrNoCheque
+			}),
+			chequestoremock.WithLastChequesFunc(func() (map[common.Address]*vault.SignedCheque, error) {
+				return map[common.Address]*vault.SignedCheque{}, nil
+			}),
+		),
+		vault.WithBeneficiary(beneficiary),
+		vault.WithSignatureVerification(func(c *vault.SignedCheque, chainID int64) (common.Address, error) {
+			// the signature of the test cheques is the first byte of the signer
+			if c.Signature[0] == 1 {
+				return issuer, nil
+			}
+			return common.HexToAddress("2222"), nil
+		}, 5),
+	)
+
+	err := cashoutService.ImportVaults(context.Background(), []vault.ImportEntry{entry(importedVault, 500, 1), entry(forgedVault, 900, 2)})
+	if !errors.Is(err, vault.ErrChequeInvalid) {
+		t.Fatalf("wrong error for forged cheque %v", err)
+	}
+	status, err := cashoutService.CashoutStatus(context.Background(), importedVault)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.UncashedAmount.Sign() != 0 {
+		t.Fatalf("vault imported although the import failed, uncashed %v", status.UncashedAmount)
+	}
+
+	err = cashoutService.ImportVaults(context.Background(), []vault.ImportEntry{entry(importedVault, 500, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// an older cheque does not replace the imported one
+	err = cashoutService.ImportVaults(context.Background(), []vault.ImportEntry{entry(importedVault, 300, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status, err = cashoutService.CashoutStatus(context.Background(), importedVault)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.State != vault.CashoutStateNeverCashed || status.UncashedAmount.Cmp(big.NewInt(500)) != 0 {
+		t.Fatalf("wrong status of imported vault %+v", status)
+	}
+
+	invalid := entry(importedVault, 500, 1)
+	invalid.Beneficiary = common.HexToAddress("3333")
+	if err := cashoutService.ImportVaults(context.Background(), []vault.ImportEntry{invalid}); !errors.Is(err, vault.ErrInvalidImport) {
+		t.Fatalf("wrong error for mismatching entry %v", err)
+	}
+}
+
+func TestCashoutMaxStoredResults(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	txHash := common.HexToHash("dddd")
+	cumulativePayout := big.NewInt(500)
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: cumulativePayout,
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	store := storemock.NewStateStore()
+	for i, cashTime := range []int64{100, 200, 300} {
+		result := vault.CashOutResult{TxHash: common.BigToHash(big.NewInt(int64(i + 1))), Vault: common.HexToAddress("bbbb"), Amount: big.NewInt(200), CashTime: cashTime, Status: "success"}
+		if err := store.Put(statestore.CashoutResultKeyByTime(result.Vault, result.CashTime), &result); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Put(statestore.TotalReceivedCashedKey, big.NewInt(600)); err != nil {
+		t.Fatal(err)
+	}
+
+	cashoutService := vault.NewCashoutService(
+		store,
+		backendmock.New(
+			backendmock.WithTransactionByHashFunc(func(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+				return nil, false, nil
+			}),
+			backendmock.WithTransactionReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				logData, err := chequeCashedEventType.Inputs.NonIndexed().Pack(cumulativePayout, cumulativePayout, big.NewInt(0))
+				if err != nil {
+					t.Fatal(err)
+				}
+				return &types.Receipt{
+					Status: types.ReceiptStatusSuccessful,
+					Logs: []*types.Log{
+						{
+							Address: vaultAddress,
+							Topics:  []common.Hash{chequeCashedEventType.ID, cheque.Beneficiary.Hash(), recipientAddress.Hash(), cheque.Beneficiary.Hash()},
+							Data:    logData,
+						},
+					},
+				}, nil
+			}),
+		),
+		transactionmock.New(
+			transactionmock.WithABISend(&vaultABI, txHash, vaultAddress, big.NewInt(0), "cashChequeBeneficiary", recipientAddress, cheque.CumulativePayout, cheque.Signature),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheque, nil
+			}),
+		),
+		vault.WithReceiptPollingInterval(time.Millisecond),
+		vault.WithMaxStoredResults(2),
+	)
+	defer cashoutService.Close()
+
+	done := make(chan error, 1)
+	_, err := cashoutService.CashChequeWithCallback(context.Background(), vaultAddress, recipientAddress, func(result *vault.CashOutResult, err error) {
+		done <- err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("cashout not tracked")
+	}
+
+	info, err := cashoutService.StoredResults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Count != 2 || info.Max != 2 || info.EvictedUntil != 200 {
+		t.Fatalf("wrong stored results info %+v", info)
+	}
+
+	results, err := cashoutService.CashoutResults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].CashTime != 300 || results[1].TxHash != txHash {
+		t.Fatalf("wrong results kept %+v", results)
+	}
+
+	var total *big.Int
+	if err := store.Get(statestore.TotalReceivedCashedKey, &total); err != nil || total.Cmp(big.NewInt(1100)) != 0 {
+		t.Fatalf("wrong cashed total %v: %v", total, err)
+	}
+}
+
+func TestCashoutVerifyTotalsWithEviction(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	txHash := common.HexToHash("dddd")
+	cumulativePayout := big.NewInt(500)
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: cumulativePayout,
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	store := storemock.NewStateStore()
+	for i, cashTime := range []int64{100, 200, 300} {
+		result := vault.CashOutResult{TxHash: common.BigToHash(big.NewInt(int64(i + 1))), Vault: common.HexToAddress("bbbb"), Amount: big.NewInt(200), CashTime: cashTime, Status: "success"}
+		if err := store.Put(statestore.CashoutResultKeyByTime(result.Vault, result.CashTime), &result); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Put(statestore.TotalReceivedCashedKey, big.NewInt(600)); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put(statestore.TotalReceivedCashedCountKey, 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put(statestore.PeerReceivedUncashRecordsCountKey(vaultAddress), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	cashoutService := vault.NewCashoutService(
+		store,
+		backendmock.New(
+			backendmock.WithTransactionByHashFunc(func(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+				return nil, false, nil
+			}),
+			backendmock.WithTransactionReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				logData, err := chequeCashedEventType.Inputs.NonIndexed().Pack(cumulativePayout, cumulativePayout, big.NewInt(0))
+				if err != nil {
+					t.Fatal(err)
+				}
+				return &types.Receipt{
+					Status: types.ReceiptStatusSuccessful,
+					Logs: []*types.Log{
+						{
+							Address: vaultAddress,
+							Topics:  []common.Hash{chequeCashedEventType.ID, cheque.Beneficiary.Hash(), recipientAddress.Hash(), cheque.Beneficiary.Hash()},
+							Data:    logData,
+						},
+					},
+				}, nil
+			}),
+		),
+		transactionmock.New(
+			transactionmock.WithABISend(&vaultABI, txHash, vaultAddress, big.NewInt(0), "cashChequeBeneficiary", recipientAddress, cheque.CumulativePayout, cheque.Signature),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheque, nil
+			}),
+		),
+		vault.WithReceiptPollingInterval(time.Millisecond),
+		vault.WithMaxStoredResults(2),
+		vault.WithTotalsVerification(10*time.Millisecond, true),
+	)
+	defer cashoutService.Close()
+
+	done := make(chan error, 1)
+	_, err := cashoutService.CashChequeWithCallback(context.Background(), vaultAddress, recipientAddress, func(result *vault.CashOutResult, err error) {
+		done <- err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("cashout not tracked")
+	}
+
+	diff, err := cashoutService.VerifyTotals(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff.Drifted() || diff.ResultCashed.Cmp(big.NewInt(1100)) != 0 || diff.ResultCount != 4 {
+		t.Fatalf("evicted results missing from the recomputed totals %+v", diff)
+	}
+
+	// several verification passes must leave the totals of the evicted results intact
+	cashoutService.Start()
+	time.Sleep(50 * time.Millisecond)
+
+	var total *big.Int
+	if err := store.Get(statestore.TotalReceivedCashedKey, &total); err != nil || total.Cmp(big.NewInt(1100)) != 0 {
+		t.Fatalf("wrong cashed total %v: %v", total, err)
+	}
+	var count int
+	if err := store.Get(statestore.TotalReceivedCashedCountKey, &count); err != nil || count != 4 {
+		t.Fatalf("wrong cashed count %d: %v", count, err)
+	}
+}
+
+func TestCashoutResultsByAmount(t *testing.T) {
+	store := storemock.NewStateStore()
+	for i, r := range []struct {
+		amount   int64
+		cashTime int64
+		status   string
+	}{
+		{50, 100, "success"},
+		{200, 200, "success"},
+		{900, 300, "success"},
+		{300, 400, "fail"},
+	} {
+		result := vault.CashOutResult{TxHash: common.BigToHash(big.NewInt(int64(i + 1))), Vault: common.HexToAddress("abcd"), Amount: big.NewInt(r.amount), CashTime: r.cashTime, Status: r.status}
+		if err := store.Put(statestore.CashoutResultKeyByTime(result.Vault, result.CashTime), &result); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cashoutService := vault.NewCashoutService(store, backendmock.New(), transactionmock.New(), chequestoremock.NewChequeStore())
+
+	amounts := func(results []vault.CashOutResult) []int64 {
+		got := make([]int64, 0, len(results))
+		for _, result := range results {
+			got = append(got, result.Amount.Int64())
+		}
+		return got
+	}
+
+	for _, tc := range []struct {
+		name     string
+		min, max *big.Int
+		want     []int64
+	}{
+		{"closed range", big.NewInt(100), big.NewInt(300), []int64{200, 300}},
+		{"no minimum", nil, big.NewInt(200), []int64{50, 200}},
+		{"no maximum", big.NewInt(300), nil, []int64{900, 300}},
+		{"open range", nil, nil, []int64{50, 200, 900, 300}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			results, err := cashoutService.CashoutResultsByAmount(tc.min, tc.max)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := amounts(results); !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("wrong amounts %v, wanted %v", got, tc.want)
+			}
+		})
+	}
+
+	results, err := cashoutService.FilterCashoutResults(vault.CashoutResultFilter{
+		MinAmount: big.NewInt(100),
+		From:      time.Unix(200, 0),
+		To:        time.Unix(400, 0),
+		Status:    "success",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := amounts(results); !reflect.DeepEqual(got, []int64{200, 900}) {
+		t.Fatalf("wrong filtered amounts %v", got)
+	}
+}
+
+func TestCashoutDryRun(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	beneficiary := common.HexToAddress("aaaa")
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      beneficiary,
+			CumulativePayout: big.NewInt(500),
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	var estimates int32
+	store := storemock.NewStateStore()
+	cashoutService := vault.NewCashoutService(
+		store,
+		backendmock.New(
+			backendmock.WithCodeAtFunc(func(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+				return []byte{1}, nil
+			}),
+			backendmock.WithEstimateGasFunc(func(ctx context.Context, call ethereum.CallMsg) (uint64, error) {
+				atomic.AddInt32(&estimates, 1)
+				return 60000, nil
+			}),
+		),
+		transactionmock.New(
+			transactionmock.WithCallFunc(func(ctx context.Context, request *transaction.TxRequest) ([]byte, error) {
+				switch {
+				case bytes.Equal(request.Data[:4], vaultABI.Methods["paidOut"].ID):
+					return big.NewInt(100).FillBytes(make([]byte, 32)), nil
+				case bytes.Equal(request.Data[:4], vaultABI.Methods["totalbalance"].ID):
+					return big.NewInt(300).FillBytes(make([]byte, 32)), nil
+				}
+				return nil, nil
+			}),
+			transactionmock.WithSendFunc(func(ctx context.Context, request *transaction.TxRequest) (common.Hash, error) {
+				t.Fatal("dry run sent a transaction")
+				return common.Hash{}, nil
+			}),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheque, nil
+			}),
+		),
+		vault.WithDryRun(),
+	)
+
+	txHash, err := cashoutService.CashCheque(context.Background(), vaultAddress, recipientAddress)
+	if !errors.Is(err, vault.ErrDryRun) {
+		t.Fatalf("got error %v, wanted %v", err, vault.ErrDryRun)
+	}
+	var cashoutErr *vault.CashoutError
+	if !errors.As(err, &cashoutErr) || cashoutErr.Phase != vault.CashoutPhaseSend {
+		t.Fatalf("got error %v, wanted a cashout error in the send phase", err)
+	}
+	if txHash != (common.Hash{}) {
+		t.Fatalf("got transaction hash %x in dry run", txHash)
+	}
+	if n := atomic.LoadInt32(&estimates); n != 1 {
+		t.Fatalf("gas estimated %d times, wanted once", n)
+	}
+
+	result, err := cashoutService.PreviewCashout(context.Background(), vaultAddress, recipientAddress, vault.CashoutOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.To != vaultAddress || len(result.Cashouts) != 1 {
+		t.Fatalf("wrong dry run result %+v", result)
+	}
+	cashout := result.Cashouts[0]
+	if cashout.Recipient != recipientAddress || cashout.EstimatedGas != 60000 || cashout.SimulationError != "" {
+		t.Fatalf("wrong dry run cashout %+v", cashout)
+	}
+	if cashout.Simulation == nil || !cashout.Simulation.Bounced || cashout.Simulation.TotalPayout.Cmp(big.NewInt(300)) != 0 {
+		t.Fatalf("wrong simulated result %+v", cashout.Simulation)
+	}
+
+	err = store.Iterate("swap_cashout_", func(key, val []byte) (bool, error) {
+		t.Fatalf("dry run stored %s", key)
+		return true, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a destroyed vault is reported but not recorded
+	destroyedService := vault.NewCashoutService(
+		store,
+		backendmock.New(
+			backendmock.WithCodeAtFunc(func(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+				return nil, nil
+			}),
+		),
+		transactionmock.New(),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheque, nil
+			}),
+		),
+		vault.WithDryRun(),
+	)
+	_, err = destroyedService.CashCheque(context.Background(), vaultAddress, recipientAddress)
+	if !errors.Is(err, vault.ErrVaultDestroyed) {
+		t.Fatalf("got error %v, wanted %v", err, vault.ErrVaultDestroyed)
+	}
+	destroyed, err := destroyedService.DestroyedVaults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(destroyed) != 0 {
+		t.Fatalf("dry run recorded destroyed vaults %+v", destroyed)
+	}
+}
+
+func TestWaitForCashouts(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	txHash := common.HexToHash("dddd")
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: big.NewInt(500),
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	store := storemock.NewStateStore()
+	stored := vault.CashOutResult{TxHash: common.HexToHash("01"), Vault: common.HexToAddress("bcde"), Amount: big.NewInt(200), CashTime: 1000, Status: "success"}
+	if err := store.Put(statestore.CashoutResultKeyByTime(stored.Vault, stored.CashTime), &stored); err != nil {
+		t.Fatal(err)
+	}
+
+	cashoutService := vault.NewCashoutService(
+		store,
+		backendmock.New(),
+		transactionmock.New(
+			transactionmock.WithABISend(&vaultABI, txHash, vaultAddress, big.NewInt(0), "cashChequeBeneficiary", recipientAddress, cheque.CumulativePayout, cheque.Signature),
+			transactionmock.WithWaitForReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				return nil, context.DeadlineExceeded
+			}),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheque, nil
+			}),
+		),
+	)
+
+	_, err := cashoutService.CashCheque(context.Background(), vaultAddress, recipientAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	results, err := cashoutService.WaitForCashouts(ctx, []common.Hash{stored.TxHash, txHash})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[stored.TxHash].Status != "success" || results[txHash].Status != "fail" || results[txHash].Vault != vaultAddress {
+		t.Fatalf("wrong results %+v", results)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	unknown := common.HexToHash("ffff")
+	results, err = cashoutService.WaitForCashouts(ctx, []common.Hash{stored.TxHash, unknown})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got error %v, wanted %v", err, context.DeadlineExceeded)
+	}
+	if len(results) != 1 || results[stored.TxHash] == nil {
+		t.Fatalf("wrong partial results %+v", results)
+	}
+}
+
+// scanSignalRepository signals every finished iteration of the stored results
+type scanSignalRepository struct {
+	*memoryCashoutRepository
+	scanned chan struct{}
+}
+
+func (r *scanSignalRepository) IterateResults(fn func(result vault.CashOutResult) (bool, error)) error {
+	defer func() {
+		select {
+		case r.scanned <- struct{}{}:
+		default:
+		}
+	}()
+	return r.memoryCashoutRepository.IterateResults(fn)
+}
+
+func TestWaitForCashoutsMissedResult(t *testing.T) {
+	waited := vault.CashOutResult{TxHash: common.HexToHash("01"), Vault: common.HexToAddress("abcd"), Amount: big.NewInt(200), CashTime: 1000, Status: "success"}
+
+	repository := &scanSignalRepository{
+		memoryCashoutRepository: &memoryCashoutRepository{actions: make(map[common.Address]*vault.CashoutAction)},
+		scanned:                 make(chan struct{}, 1),
+	}
+	cashoutService := vault.NewCashoutService(
+		storemock.NewStateStore(),
+		backendmock.New(),
+		transactionmock.New(),
+		chequestoremock.NewChequeStore(),
+		vault.WithCashoutRepository(repository),
+	)
+
+	type waitResult struct {
+		results map[common.Hash]*vault.CashOutResult
+		err     error
+	}
+	done := make(chan waitResult, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		results, err := cashoutService.WaitForCashouts(ctx, []common.Hash{waited.TxHash})
+		done <- waitResult{results: results, err: err}
+	}()
+
+	select {
+	case <-repository.scanned:
+	case <-time.After(time.Second):
+		t.Fatal("stored results were not looked at")
+	}
+	// the result is stored after the first look, but its publication is lost among other results
+	if err := repository.PutResult(&waited); err != nil {
+		t.Fatal(err)
+	}
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			other := vault.CashOutResult{TxHash: common.HexToHash("02"), Vault: common.HexToAddress("bcde"), Status: "success"}
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					vault.PublishCashoutResult(cashoutService, other)
+				}
+			}
+		}()
+	}
+
+	r := <-done
+	close(stop)
+	wg.Wait()
+	if r.err != nil {
+		t.Fatal(r.err)
+	}
+	if r.results[waited.TxHash] == nil {
+		t.Fatalf("wrong results %+v", r.results)
+	}
+}
+
+func TestCashoutGasLimit(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	txHash := common.HexToHash("dddd")
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: big.NewInt(500),
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	store := storemock.NewStateStore()
+	var sentGasLimit uint64
+	cashoutService := vault.NewCashoutService(
+		store,
+		backendmock.New(),
+		transactionmock.New(
+			transactionmock.WithSendFunc(func(ctx context.Context, request *transaction.TxRequest) (common.Hash, error) {
+				sentGasLimit = request.GasLimit
+				return txHash, nil
+			}),
+			transactionmock.WithWaitForReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			}),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheque, nil
+			}),
+		),
+	)
+
+	_, err := cashoutService.CashChequeWithOptions(context.Background(), vaultAddress, recipientAddress, vault.CashoutOptions{GasLimit: 21000})
+	if !errors.Is(err, vault.ErrGasLimitTooLow) {
+		t.Fatalf("got error %v, wanted %v", err, vault.ErrGasLimitTooLow)
+	}
+	if sentGasLimit != 0 {
+		t.Fatal("cashout with too low gas limit was sent")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, err = cashoutService.CashChequeWithOptions(ctx, vaultAddress, recipientAddress, vault.CashoutOptions{GasLimit: 120000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sentGasLimit != 120000 {
+		t.Fatalf("sent gas limit %d, wanted %d", sentGasLimit, 120000)
+	}
+	var action vault.CashoutAction
+	if err := store.Get(vault.CashoutActionKey(vaultAddress), &action); err != nil {
+		t.Fatal(err)
+	}
+	if action.GasLimit != 120000 {
+		t.Fatalf("recorded gas limit %d, wanted %d", action.GasLimit, 120000)
+	}
+}
+
+func TestCashoutConfirmationTimeStats(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	txHash := common.HexToHash("dddd")
+	now := time.Unix(10000, 0)
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: big.NewInt(500),
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	store := storemock.NewStateStore()
+	put := func(result vault.CashOutResult) {
+		if err := store.Put(statestore.CashoutResultKeyByTime(result.Vault, result.CashTime), &result); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// confirmed after 1 to 10 seconds
+	for i := int64(1); i <= 10; i++ {
+		cashTime := 9000 + 10*i
+		put(vault.CashOutResult{TxHash: common.BigToHash(big.NewInt(i)), Vault: common.HexToAddress("bcde"), CashTime: cashTime, Submitted: time.Unix(cashTime-i, 0).UnixNano(), Status: "success"})
+	}
+	// without submission time
+	put(vault.CashOutResult{TxHash: common.HexToHash("0100"), Vault: common.HexToAddress("bcde"), CashTime: 9500, Status: "success"})
+	// outside of the window
+	put(vault.CashOutResult{TxHash: common.HexToHash("0200"), Vault: common.HexToAddress("bcde"), CashTime: 1100, Submitted: time.Unix(100, 0).UnixNano(), Status: "success"})
+
+	cashoutService := vault.NewCashoutService(
+		store,
+		backendmock.New(),
+		transactionmock.New(
+			transactionmock.WithABISend(&vaultABI, txHash, vaultAddress, big.NewInt(0), "cashChequeBeneficiary", recipientAddress, cheque.CumulativePayout, cheque.Signature),
+			transactionmock.WithWaitForReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				return nil, context.DeadlineExceeded
+			}),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheque, nil
+			}),
+		),
+		vault.WithClock(func() time.Time { return now }),
+	)
+
+	stats, err := cashoutService.ConfirmationTimeStats(2 * time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := vault.ConfTimeStats{Count: 10, Min: time.Second, Median: 5 * time.Second, P95: 10 * time.Second, Max: 10 * time.Second}
+	if stats != want {
+		t.Fatalf("got stats %+v, wanted %+v", stats, want)
+	}
+
+	stats, err = cashoutService.ConfirmationTimeStats(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Count != 11 || stats.Max != 1000*time.Second {
+		t.Fatalf("wrong stats without window %+v", stats)
+	}
+
+	_, err = cashoutService.CashCheque(context.Background(), vaultAddress, recipientAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	results, err := cashoutService.WaitForCashouts(ctx, []common.Hash{txHash})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[txHash].Submitted != now.UnixNano() {
+		t.Fatalf("result recorded submission time %d, wanted %d", results[txHash].Submitted, now.UnixNano())
+	}
+}
+
+func TestCashoutPreSendHook(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	txHash := common.HexToHash("dddd")
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: big.NewInt(500),
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+	callData, err := vaultABI.Pack("cashChequeBeneficiary", recipientAddress, cheque.CumulativePayout, cheque.Signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := storemock.NewStateStore()
+	errRejected := errors.New("rejected")
+	var (
+		hooked []*transaction.TxRequest
+		sent   int
+		reject = true
+	)
+	cashoutService := vault.NewCashoutService(
+		store,
+		backendmock.New(),
+		transactionmock.New(
+			transactionmock.WithSendFunc(func(ctx context.Context, request *transaction.TxRequest) (common.Hash, error) {
+				sent++
+				return txHash, nil
+			}),
+			transactionmock.WithWaitForReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			}),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheque, nil
+			}),
+		),
+		vault.WithPreSendHook(func(ctx context.Context, request *transaction.TxRequest) error {
+			hooked = append(hooked, request)
+			if reject {
+				return errRejected
+			}
+			return nil
+		}),
+	)
+
+	_, err = cashoutService.CashChequeWithOptions(context.Background(), vaultAddress, recipientAddress, vault.CashoutOptions{GasLimit: 90000})
+	if !errors.Is(err, errRejected) {
+		t.Fatalf("got error %v, wanted %v", err, errRejected)
+	}
+	if sent != 0 {
+		t.Fatal("rejected cashout was sent")
+	}
+	if err := store.Get(vault.CashoutActionKey(vaultAddress), &vault.CashoutAction{}); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("rejected cashout stored an action: %v", err)
+	}
+
+	reject = false
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, err = cashoutService.CashChequeWithOptions(ctx, vaultAddress, recipientAddress, vault.CashoutOptions{GasLimit: 90000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent != 1 || len(hooked) != 2 {
+		t.Fatalf("hook invoked %d times and %d transactions sent, wanted 2 and 1", len(hooked), sent)
+	}
+	request := hooked[1]
+	if *request.To != vaultAddress || request.GasLimit != 90000 || !bytes.Equal(request.Data, callData) || request.Value.Sign() != 0 {
+		t.Fatalf("hook got incomplete request %+v", request)
+	}
+}
+
+func TestAutoCashoutCursor(t *testing.T) {
+	vaultA := common.HexToAddress("01")
+	vaultB := common.HexToAddress("02")
+	vaultC := common.HexToAddress("03")
+
+	cheques := make(map[common.Address]*vault.SignedCheque)
+	for _, v := range []common.Address{vaultC, vaultA, vaultB} {
+		cheques[v] = &vault.SignedCheque{
+			Cheque: vault.Cheque{
+				Beneficiary:      common.HexToAddress("aaaa"),
+				CumulativePayout: big.NewInt(500),
+				Vault:            v,
+			},
+			Signature: []byte{},
+		}
+	}
+
+	store := storemock.NewStateStore()
+	sent := make(chan common.Address, 10)
+	newService := func() vault.CashoutService {
+		return vault.NewCashoutService(
+			store,
+			backendmock.New(),
+			transactionmock.New(
+				transactionmock.WithCallFunc(func(ctx context.Context, request *transaction.TxRequest) ([]byte, error) {
+					return big.NewInt(0).FillBytes(make([]byte, 32)), nil
+				}),
+				transactionmock.WithSendFunc(func(ctx context.Context, request *transaction.TxRequest) (common.Hash, error) {
+					sent <- *request.To
+					return request.To.Hash(), nil
+				}),
+				transactionmock.WithWaitForReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+					<-ctx.Done()
+					return nil, ctx.Err()
+				}),
+			),
+			chequestoremock.NewChequeStore(
+				chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+					return cheques[c], nil
+				}),
+				chequestoremock.WithLastChequesFunc(func() (map[common.Address]*vault.SignedCheque, error) {
+					return cheques, nil
+				}),
+			),
+			vault.WithAutoCashout(vault.AutoCashoutConfig{
+				Recipient:             common.HexToAddress("efff"),
+				LoopInterval:          50 * time.Millisecond,
+				MinAmount:             big.NewInt(100),
+				MaxVaultsPerIteration: 1,
+			}),
+		)
+	}
+
+	cashoutService := newService()
+	cursor, err := cashoutService.AutoCashoutCursor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cursor != (common.Address{}) {
+		t.Fatalf("got cursor %x before the first iteration", cursor)
+	}
+
+	cashoutService.Start()
+	select {
+	case v := <-sent:
+		if v != vaultA {
+			t.Fatalf("first iteration cashed vault %x, wanted %x", v, vaultA)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("auto cashout did not cash any vault")
+	}
+	cashoutService.Close()
+
+	cursor, err = cashoutService.AutoCashoutCursor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cursor != vaultA {
+		t.Fatalf("got cursor %x, wanted %x", cursor, vaultA)
+	}
+
+	// after a restart the loop continues after the cursor instead of checking the first vault again
+	cashoutService = newService()
+	cashoutService.Start()
+	defer cashoutService.Close()
+	select {
+	case v := <-sent:
+		if v != vaultB {
+			t.Fatalf("resumed iteration cashed vault %x, wanted %x", v, vaultB)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("resumed auto cashout did not cash any vault")
+	}
+	if _, err := cashoutService.LastAutoCashoutDecision(vaultA); !errors.Is(err, vault.ErrNoAutoCashoutDecision) {
+		t.Fatalf("resumed loop checked vault %x again: %v", vaultA, err)
+	}
+}
+
+func TestCashoutResultSignature(t *testing.T) {
+	key, err := crypto.GenerateSecp256k1Key()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := crypto.NewDefaultSigner(key)
+	signerAddress, err := signer.EthereumAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newService := func(opts ...vault.CashoutServiceOption) vault.CashoutService {
+		return vault.NewCashoutService(
+			storemock.NewStateStore(),
+			backendmock.New(),
+			transactionmock.New(),
+			chequestoremock.NewChequeStore(),
+			opts...,
+		)
+	}
+	cashoutService := newService(vault.WithResultSigner(signer, 1))
+
+	result := vault.CashOutResult{
+		TxHash:   common.HexToHash("dddd"),
+		Vault:    common.HexToAddress("abcd"),
+		Amount:   big.NewInt(500),
+		CashTime: 1000,
+		Status:   "success",
+	}
+	signature, err := cashoutService.SignCashoutResult(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cashoutService.VerifyCashoutResult(result, signature, signerAddress); err != nil {
+		t.Fatal(err)
+	}
+	recovered, err := vault.RecoverCashoutResult(&result, signature, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recovered != signerAddress {
+		t.Fatalf("recovered signer %x, wanted %x", recovered, signerAddress)
+	}
+
+	// fields outside of the attestation do not matter
+	unsigned := result
+	unsigned.Status = "fail"
+	if err := cashoutService.VerifyCashoutResult(unsigned, signature, signerAddress); err != nil {
+		t.Fatal(err)
+	}
+
+	tampered := result
+	tampered.Amount = big.NewInt(5000)
+	if err := cashoutService.VerifyCashoutResult(tampered, signature, signerAddress); !errors.Is(err, vault.ErrInvalidResultSignature) {
+		t.Fatalf("got error %v for tampered result, wanted %v", err, vault.ErrInvalidResultSignature)
+	}
+	if err := cashoutService.VerifyCashoutResult(result, signature, common.HexToAddress("aaaa")); !errors.Is(err, vault.ErrInvalidResultSignature) {
+		t.Fatalf("got error %v for wrong signer, wanted %v", err, vault.ErrInvalidResultSignature)
+	}
+	// the signature is bound to the chain
+	if err := newService(vault.WithResultSigner(signer, 2)).VerifyCashoutResult(result, signature, signerAddress); !errors.Is(err, vault.ErrInvalidResultSignature) {
+		t.Fatalf("got error %v for other chain, wanted %v", err, vault.ErrInvalidResultSignature)
+	}
+
+	if _, err := newService().SignCashoutResult(result); !errors.Is(err, vault.ErrNoResultSigner) {
+		t.Fatalf("got error %v without signer, wanted %v", err, vault.ErrNoResultSigner)
+	}
+}
+
+type nameResolverFunc func(ctx context.Context, name string) (common.Address, error)
+
+func (f nameResolverFunc) ResolveName(ctx context.Context, name string) (common.Address, error) {
+	return f(ctx, name)
+}
+
+func TestCashChequeTo(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	txHash := common.HexToHash("dddd")
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: big.NewInt(500),
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	var (
+		mu          sync.Mutex
+		now         = time.Unix(1000, 0)
+		resolutions int
+	)
+	errUnknown := errors.New("unknown name")
+	resolver := nameResolverFunc(func(ctx context.Context, name string) (common.Address, error) {
+		resolutions++
+		switch name {
+		case "node.eth":
+			return recipientAddress, nil
+		case "unset.eth":
+			return common.Address{}, nil
+		}
+		return common.Address{}, errUnknown
+	})
+
+	cashoutService := vault.NewCashoutService(
+		storemock.NewStateStore(),
+		backendmock.New(),
+		transactionmock.New(
+			transactionmock.WithABISend(&vaultABI, txHash, vaultAddress, big.NewInt(0), "cashChequeBeneficiary", recipientAddress, cheque.CumulativePayout, cheque.Signature),
+			transactionmock.WithWaitForReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				return nil, context.DeadlineExceeded
+			}),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheque, nil
+			}),
+		),
+		vault.WithNameResolver(resolver),
+		vault.WithClock(func() time.Time {
+			mu.Lock()
+			defer mu.Unlock()
+			return now
+		}),
+	)
+
+	for i := 0; i < 2; i++ {
+		hash, err := cashoutService.CashChequeTo(context.Background(), vaultAddress, "node.eth")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hash != txHash {
+			t.Fatalf("got transaction %x, wanted %x", hash, txHash)
+		}
+	}
+	if resolutions != 1 {
+		t.Fatalf("name resolved %d times, wanted the resolution to be cached", resolutions)
+	}
+
+	mu.Lock()
+	now = now.Add(time.Hour)
+	mu.Unlock()
+	if _, err := cashoutService.CashChequeTo(context.Background(), vaultAddress, "node.eth"); err != nil {
+		t.Fatal(err)
+	}
+	if resolutions != 2 {
+		t.Fatalf("expired resolution was not refreshed, %d resolutions", resolutions)
+	}
+
+	// hex addresses need no resolution
+	if _, err := cashoutService.CashChequeTo(context.Background(), vaultAddress, recipientAddress.Hex()); err != nil {
+		t.Fatal(err)
+	}
+	if resolutions != 2 {
+		t.Fatal("hex address was resolved")
+	}
+
+	_, err := cashoutService.CashChequeTo(context.Background(), vaultAddress, "unknown.eth")
+	if !errors.Is(err, vault.ErrNameResolution) {
+		t.Fatalf("got error %v, wanted %v", err, vault.ErrNameResolution)
+	}
+	_, err = cashoutService.CashChequeTo(context.Background(), vaultAddress, "unset.eth")
+	if !errors.Is(err, vault.ErrNameResolution) {
+		t.Fatalf("got error %v for zero address, wanted %v", err, vault.ErrNameResolution)
+	}
+}
+
+func TestCashoutResultCaller(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	node := common.HexToAddress("aaaa")
+	txHash := common.HexToHash("dddd")
+	totalPayout := big.NewInt(100)
+	cumulativePayout := big.NewInt(500)
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      node,
+			CumulativePayout: cumulativePayout,
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	cashoutService := vault.NewCashoutService(
+		storemock.NewStateStore(),
+		backendmock.New(
+			backendmock.WithTransactionByHashFunc(func(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+				return nil, false, nil
+			}),
+			backendmock.WithTransactionReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				logData, err := chequeCashedEventType.Inputs.NonIndexed().Pack(totalPayout, cumulativePayout, big.NewInt(7))
+				if err != nil {
+					t.Fatal(err)
+				}
+				return &types.Receipt{
+					Status: types.ReceiptStatusSuccessful,
+					Logs: []*types.Log{
+						{
+							Address: vaultAddress,
+							Topics:  []common.Hash{chequeCashedEventType.ID, node.Hash(), recipientAddress.Hash(), node.Hash()},
+							Data:    logData,
+						},
+					},
+				}, nil
+			}),
+		),
+		transactionmock.New(
+			transactionmock.WithABISend(&vaultABI, txHash, vaultAddress, big.NewInt(0), "cashChequeBeneficiary", recipientAddress, cheque.CumulativePayout, cheque.Signature),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheque, nil
+			}),
+		),
+		vault.WithBeneficiary(node),
+		vault.WithReceiptPollingInterval(time.Millisecond),
+	)
+	defer cashoutService.Close()
+
+	_, err := cashoutService.CashCheque(context.Background(), vaultAddress, recipientAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	results, err := cashoutService.WaitForCashouts(ctx, []common.Hash{txHash})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := results[txHash]
+	if result.Caller != node || result.Beneficiary != node || result.CallerPayout == nil || result.CallerPayout.Cmp(big.NewInt(7)) != 0 {
+		t.Fatalf("caller not recorded in result %+v", result)
+	}
+
+}
+
+func TestParseVaultEvents(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	beneficiary := common.HexToAddress("aaaa")
+	depositor := common.HexToAddress("cccc")
+	txHash := common.HexToHash("dddd")
+	totalPayout := big.NewInt(100)
+	cumulativePayout := big.NewInt(500)
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      beneficiary,
+			CumulativePayout: cumulativePayout,
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	cashedData, err := chequeCashedEventType.Inputs.NonIndexed().Pack(totalPayout, cumulativePayout, big.NewInt(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	depositData, err := vaultABI.Events["VaultDeposit"].Inputs.NonIndexed().Pack(big.NewInt(300))
+	if err != nil {
+		t.Fatal(err)
+	}
+	withdrawData, err := vaultABI.Events["VaultWithdraw"].Inputs.NonIndexed().Pack(big.NewInt(50))
+	if err != nil {
+		t.Fatal(err)
+	}
+	receipt := &types.Receipt{
+		Status: types.ReceiptStatusSuccessful,
+		Logs: []*types.Log{
+			{
+				Address: vaultAddress,
+				Topics:  []common.Hash{vaultABI.Events["VaultDeposit"].ID, depositor.Hash()},
+				Data:    depositData,
+				Index:   0,
+			},
+			{
+				Address: vaultAddress,
+				Topics:  []common.Hash{chequeCashedEventType.ID, beneficiary.Hash(), recipientAddress.Hash(), beneficiary.Hash()},
+				Data:    cashedData,
+				Index:   1,
+			},
+			{
+				Address: vaultAddress,
+				Topics:  []common.Hash{chequeBouncedEventType.ID},
+				Index:   2,
+			},
+			// not recognized
+			{
+				Address: vaultAddress,
+				Topics:  []common.Hash{vaultABI.Events["Upgraded"].ID, common.HexToHash("01")},
+				Index:   3,
+			},
+			// another contract
+			{
+				Address: common.HexToAddress("bcde"),
+				Topics:  []common.Hash{vaultABI.Events["VaultWithdraw"].ID, depositor.Hash()},
+				Data:    withdrawData,
+				Index:   4,
+			},
+			{
+				Address: vaultAddress,
+				Topics:  []common.Hash{vaultABI.Events["VaultWithdraw"].ID, beneficiary.Hash()},
+				Data:    withdrawData,
+				Index:   5,
+			},
+		},
+	}
+
+	events, err := vault.ParseVaultEvents(receipt, vaultAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 4 {
+		t.Fatalf("got %d events, wanted 4: %+v", len(events), events)
+	}
+	if e := events[0]; e.Type != vault.VaultEventDeposit || e.From != depositor || e.Amount.Cmp(big.NewInt(300)) != 0 || e.LogIndex != 0 {
+		t.Fatalf("wrong deposit event %+v", e)
+	}
+	if e := events[1]; e.Type != vault.VaultEventChequeCashed || e.Cashed.Beneficiary != beneficiary || e.Cashed.Recipient != recipientAddress || e.Cashed.TotalPayout.Cmp(totalPayout) != 0 {
+		t.Fatalf("wrong cashed event %+v", e)
+	}
+	if e := events[2]; e.Type != vault.VaultEventChequeBounced {
+		t.Fatalf("wrong bounced event %+v", e)
+	}
+	if e := events[3]; e.Type != vault.VaultEventWithdraw || e.From != beneficiary || e.Amount.Cmp(big.NewInt(50)) != 0 || e.LogIndex != 5 {
+		t.Fatalf("wrong withdraw event %+v", e)
+	}
+
+	if _, err := vault.ParseVaultEvents(&types.Receipt{Status: types.ReceiptStatusFailed}, vaultAddress); !errors.Is(err, transaction.ErrTransactionReverted) {
+		t.Fatalf("got error %v for reverted receipt, wanted %v", err, transaction.ErrTransactionReverted)
+	}
+
+	// the stored result records the net balance change, 300 deposited, 100 cashed and 50 withdrawn
+	cashoutService := vault.NewCashoutService(
+		storemock.NewStateStore(),
+		backendmock.New(
+			backendmock.WithTransactionByHashFunc(func(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+				return nil, false, nil
+			}),
+			backendmock.WithTransactionReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				return receipt, nil
+			}),
+		),
+		transactionmock.New(
+			transactionmock.WithABISend(&vaultABI, txHash, vaultAddress, big.NewInt(0), "cashChequeBeneficiary", recipientAddress, cheque.CumulativePayout, cheque.Signature),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheque, nil
+			}),
+		),
+		vault.WithReceiptPollingInterval(time.Millisecond),
+	)
+	defer cashoutService.Close()
+
+	_, err = cashoutService.CashCheque(context.Background(), vaultAddress, recipientAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	results, err := cashoutService.WaitForCashouts(ctx, []common.Hash{txHash})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if change := results[txHash].VaultBalanceChange; change == nil || change.Cmp(big.NewInt(150)) != 0 {
+		t.Fatalf("got vault balance change %v, wanted %d", change, 150)
+	}
+}
+
+func TestCashoutGasBalancePreflight(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	beneficiary := common.HexToAddress("aaaa")
+	txHash := common.HexToHash("dddd")
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      beneficiary,
+			CumulativePayout: big.NewInt(500),
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	var (
+		mu      sync.Mutex
+		balance = big.NewInt(500000)
+		sent    int
+	)
+	cashoutService := vault.NewCashoutService(
+		storemock.NewStateStore(),
+		backendmock.New(
+			backendmock.WithEstimateGasFunc(func(ctx context.Context, call ethereum.CallMsg) (uint64, error) {
+				return 60000, nil
+			}),
+			backendmock.WithSuggestGasPriceFunc(func(ctx context.Context) (*big.Int, error) {
+				return big.NewInt(10), nil
+			}),
+			backendmock.WithBalanceAtFunc(func(ctx context.Context, address common.Address, block *big.Int) (*big.Int, error) {
+				if address != beneficiary {
+					t.Fatalf("queried balance of %x, wanted the sending account %x", address, beneficiary)
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				return new(big.Int).Set(balance), nil
+			}),
+		),
+		transactionmock.New(
+			transactionmock.WithSendFunc(func(ctx context.Context, request *transaction.TxRequest) (common.Hash, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				sent++
+				return txHash, nil
+			}),
+			transactionmock.WithWaitForReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			}),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheque, nil
+			}),
+		),
+		vault.WithBeneficiary(beneficiary),
+	)
+
+	// 60000 gas at a price of 10 cost 600000
+	_, err := cashoutService.CashCheque(context.Background(), vaultAddress, recipientAddress)
+	if !errors.Is(err, vault.ErrInsufficientGasBalance) {
+		t.Fatalf("got error %v, wanted %v", err, vault.ErrInsufficientGasBalance)
+	}
+	var balanceErr *vault.InsufficientGasBalanceError
+	if !errors.As(err, &balanceErr) {
+		t.Fatalf("got error %v, wanted the shortfall", err)
+	}
+	if balanceErr.Shortfall.Cmp(big.NewInt(100000)) != 0 || balanceErr.Cost.Cmp(big.NewInt(600000)) != 0 {
+		t.Fatalf("wrong shortfall %+v", balanceErr)
+	}
+	if sent != 0 {
+		t.Fatal("cashout sent without enough gas balance")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// a forced gas limit replaces the estimate
+	_, err = cashoutService.CashChequeWithOptions(ctx, vaultAddress, recipientAddress, vault.CashoutOptions{GasLimit: 50000})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	balance = big.NewInt(600000)
+	mu.Unlock()
+	_, err = cashoutService.CashCheque(ctx, vaultAddress, recipientAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if sent != 2 {
+		t.Fatalf("sent %d cashouts, wanted 2", sent)
+	}
+}
+
+func TestCashoutGasBalancePreflightDisabled(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	txHash := common.HexToHash("dddd")
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      common.HexToAddress("aaaa"),
+			CumulativePayout: big.NewInt(500),
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	var estimates int32
+	cashoutService := vault.NewCashoutService(
+		storemock.NewStateStore(),
+		backendmock.New(
+			backendmock.WithEstimateGasFunc(func(ctx context.Context, call ethereum.CallMsg) (uint64, error) {
+				atomic.AddInt32(&estimates, 1)
+				return 60000, nil
+			}),
+		),
+		transactionmock.New(
+			transactionmock.WithABISend(&vaultABI, txHash, vaultAddress, big.NewInt(0), "cashChequeBeneficiary", recipientAddress, cheque.CumulativePayout, cheque.Signature),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheque, nil
+			}),
+		),
+	)
+
+	// without a beneficiary there is no account to check the balance of, the gas is not estimated for nothing
+	_, err := cashoutService.CashCheque(context.Background(), vaultAddress, recipientAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&estimates); n != 0 {
+		t.Fatalf("gas estimated %d times without a gas balance preflight", n)
+	}
+}
+
+func TestCashoutResultAppliedOnce(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	beneficiary := common.HexToAddress("aaaa")
+	txHash := common.HexToHash("dddd")
+	totalPayout := big.NewInt(100)
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      beneficiary,
+			CumulativePayout: big.NewInt(500),
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	logData, err := chequeCashedEventType.Inputs.NonIndexed().Pack(totalPayout, cheque.CumulativePayout, big.NewInt(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	receipt := &types.Receipt{
+		Status: types.ReceiptStatusSuccessful,
+		TxHash: txHash,
+		Logs: []*types.Log{
+			{
+				Address: vaultAddress,
+				Topics:  []common.Hash{chequeCashedEventType.ID, beneficiary.Hash(), recipientAddress.Hash(), beneficiary.Hash()},
+				Data:    logData,
+			},
+		},
+	}
+
+	// the background tracking and a recovery pass race to record the same transaction
+	for round := 0; round < 20; round++ {
+		store := storemock.NewStateStore()
+		// an earlier wait for the receipt timed out and recorded the cashout as failed
+		failed := vault.CashOutResult{TxHash: txHash, Vault: vaultAddress, Amount: cheque.CumulativePayout, CashTime: 1000, Status: "fail"}
+		if err := store.Put(statestore.CashoutResultKeyByTime(vaultAddress, failed.CashTime), &failed); err != nil {
+			t.Fatal(err)
+		}
+
+		cashoutService := vault.NewCashoutService(
+			store,
+			backendmock.New(
+				backendmock.WithTransactionByHashFunc(func(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+					return nil, false, nil
+				}),
+				backendmock.WithTransactionReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+					return receipt, nil
+				}),
+			),
+			transactionmock.New(
+				transactionmock.WithABISend(&vaultABI, txHash, vaultAddress, big.NewInt(0), "cashChequeBeneficiary", recipientAddress, cheque.CumulativePayout, cheque.Signature),
+				transactionmock.WithWaitForReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+					return receipt, nil
+				}),
+			),
+			chequestoremock.NewChequeStore(
+				chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+					return cheque, nil
+				}),
+			),
+		)
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if _, err := cashoutService.RecoverFailedResults(context.Background()); err != nil {
+				t.Error(err)
+			}
+		}()
+		tracked := make(chan error, 1)
+		_, err := cashoutService.CashChequeWithCallback(context.Background(), vaultAddress, recipientAddress, func(result *vault.CashOutResult, err error) {
+			defer wg.Done()
+			if err == nil && (result == nil || result.Status != "success") {
+				err = fmt.Errorf("tracked result %+v", result)
+			}
+			tracked <- err
+		})
+		if err != nil {
+			t.Fatal(err)
 		}
-		if status.Last.Reverted != expected.Last.Reverted {
-			t.Fatalf("wrong reverted value. wanted %v, got %v", expected.Last.Reverted, status.Last.Reverted)
+		wg.Wait()
+		if err := <-tracked; err != nil {
+			t.Fatal(err)
 		}
-		if status.Last.TxHash != expected.Last.TxHash {
-			t.Fatalf("wrong transaction hash. wanted %v, got %v", expected.Last.TxHash, status.Last.TxHash)
+
+		var total *big.Int
+		if err := store.Get(statestore.TotalReceivedCashedKey, &total); err != nil {
+			t.Fatal(err)
 		}
-		if !status.Last.Cheque.Equal(&expected.Last.Cheque) {
-			t.Fatalf("wrong cheque in status. wanted %v, got %v", expected.Last.Cheque, status.Last.Cheque)
+		if total.Cmp(totalPayout) != 0 {
+			t.Fatalf("round %d: total received cashed %d, wanted %d", round, total, totalPayout)
 		}
 
-		if expected.Last.Result != nil {
-			if !expected.Last.Result.Equal(status.Last.Result) {
-				t.Fatalf("wrong result. wanted %v, got %v", expected.Last.Result, status.Last.Result)
-			}
+		results, err := cashoutService.CashoutResults()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 1 || results[0].Status != "success" || results[0].Amount.Cmp(totalPayout) != 0 {
+			t.Fatalf("round %d: wrong results %+v", round, results)
+		}
+
+		// the marker only keeps the cash time of the result the totals were applied with
+		var appliedTime int64
+		if err := store.Get(vault.AppliedResultKey(vaultAddress, txHash), &appliedTime); err != nil {
+			t.Fatal(err)
+		}
+		if appliedTime != results[0].CashTime {
+			t.Fatalf("round %d: applied marker has cash time %d, wanted %d", round, appliedTime, results[0].CashTime)
 		}
+		cashoutService.Close()
 	}
+}
 
-	if status.UncashedAmount.Cmp(expected.UncashedAmount) != 0 {
-		t.Fatalf("wrong uncashed amount. wanted %d, got %d", expected.UncashedAmount, status.UncashedAmount)
+func TestCashoutResultTwoTrackedTransactions(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	beneficiary := common.HexToAddress("aaaa")
+	firstTxHash := common.HexToHash("dddd")
+	secondTxHash := common.HexToHash("eeee")
+
+	cheques := []*vault.SignedCheque{
+		{Cheque: vault.Cheque{Beneficiary: beneficiary, CumulativePayout: big.NewInt(100), Vault: vaultAddress}, Signature: []byte{}},
+		{Cheque: vault.Cheque{Beneficiary: beneficiary, CumulativePayout: big.NewInt(300), Vault: vaultAddress}, Signature: []byte{}},
+	}
+	receipts := make(map[common.Hash]*types.Receipt)
+	for i, txHash := range []common.Hash{firstTxHash, secondTxHash} {
+		// the second cashout pays out what the second cheque adds on top of the first
+		totalPayout := big.NewInt(int64(100 * (i + 1)))
+		logData, err := chequeCashedEventType.Inputs.NonIndexed().Pack(totalPayout, cheques[i].CumulativePayout, big.NewInt(0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		receipts[txHash] = &types.Receipt{
+			Status: types.ReceiptStatusSuccessful,
+			TxHash: txHash,
+			Logs: []*types.Log{
+				{
+					Address: vaultAddress,
+					Topics:  []common.Hash{chequeCashedEventType.ID, beneficiary.Hash(), recipientAddress.Hash(), beneficiary.Hash()},
+					Data:    logData,
+				},
+			},
+		}
+	}
+
+	var lock sync.Mutex
+	current := 0
+	now := time.Unix(1000, 0)
+	release := make(chan struct{})
+	store := storemock.NewStateStore()
+	cashoutService := vault.NewCashoutService(
+		store,
+		backendmock.New(
+			backendmock.WithTransactionByHashFunc(func(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+				return nil, false, nil
+			}),
+			backendmock.WithTransactionReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				return receipts[hash], nil
+			}),
+		),
+		transactionmock.New(
+			transactionmock.WithSendFunc(func(ctx context.Context, request *transaction.TxRequest) (common.Hash, error) {
+				lock.Lock()
+				defer lock.Unlock()
+				if current == 0 {
+					return firstTxHash, nil
+				}
+				return secondTxHash, nil
+			}),
+			transactionmock.WithWaitForReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				// the older cashout is only mined once the newer one is the latest action of the vault
+				if hash == firstTxHash {
+					<-release
+				}
+				return receipts[hash], nil
+			}),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				lock.Lock()
+				defer lock.Unlock()
+				return cheques[current], nil
+			}),
+		),
+		// each result gets its own second so the results do not share a key
+		vault.WithClock(func() time.Time {
+			lock.Lock()
+			defer lock.Unlock()
+			now = now.Add(time.Second)
+			return now
+		}),
+	)
+	defer cashoutService.Close()
+
+	_, err := cashoutService.CashCheque(context.Background(), vaultAddress, recipientAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lock.Lock()
+	current = 1
+	lock.Unlock()
+	_, err = cashoutService.CashCheque(context.Background(), vaultAddress, recipientAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	results, err := cashoutService.WaitForCashouts(ctx, []common.Hash{firstTxHash, secondTxHash})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[firstTxHash].Amount.Cmp(big.NewInt(100)) != 0 || results[secondTxHash].Amount.Cmp(big.NewInt(200)) != 0 {
+		t.Fatalf("wrong amounts %d and %d", results[firstTxHash].Amount, results[secondTxHash].Amount)
+	}
+
+	var total *big.Int
+	err = store.Get(statestore.TotalReceivedCashedKey, &total)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total.Cmp(big.NewInt(300)) != 0 {
+		t.Fatalf("total received cashed %d, wanted %d", total, 300)
+	}
+}
+
+func TestCashoutStartDoesNotWaitForBackend(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+
+	store := storemock.NewStateStore()
+	err := store.Put(statestore.CashoutResultKeyByTime(vaultAddress, 1000), &vault.CashOutResult{
+		TxHash:   common.HexToHash("dddd"),
+		Vault:    vaultAddress,
+		Amount:   big.NewInt(500),
+		CashTime: 1000,
+		Status:   "fail",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the backend never answers, recovering the failed result blocks until its context ends
+	recovering := make(chan struct{})
+	cashoutService := vault.NewCashoutService(
+		store,
+		backendmock.New(
+			backendmock.WithTransactionReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				close(recovering)
+				<-ctx.Done()
+				return nil, ctx.Err()
+			}),
+		),
+		transactionmock.New(),
+		chequestoremock.NewChequeStore(),
+	)
+
+	started := make(chan struct{})
+	go func() {
+		cashoutService.Start()
+		close(started)
+	}()
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("start waited for the backend")
+	}
+
+	select {
+	case <-recovering:
+	case <-time.After(time.Second):
+		t.Fatal("failed results not recovered in the background")
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		cashoutService.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("close did not cancel the startup tasks")
+	}
+}
+
+func TestCashoutTokenLookupOutsideTotalsLock(t *testing.T) {
+	vaultAddress := common.HexToAddress("abcd")
+	recipientAddress := common.HexToAddress("efff")
+	beneficiary := common.HexToAddress("aaaa")
+	tokenAddress := common.HexToAddress("eeee")
+	txHash := common.HexToHash("dddd")
+	totalPayout := big.NewInt(100)
+
+	cheque := &vault.SignedCheque{
+		Cheque: vault.Cheque{
+			Beneficiary:      beneficiary,
+			CumulativePayout: big.NewInt(500),
+			Vault:            vaultAddress,
+		},
+		Signature: []byte{},
+	}
+
+	logData, err := chequeCashedEventType.Inputs.NonIndexed().Pack(totalPayout, cheque.CumulativePayout, big.NewInt(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	receipt := &types.Receipt{
+		Status: types.ReceiptStatusSuccessful,
+		TxHash: txHash,
+		Logs: []*types.Log{
+			{
+				Address: vaultAddress,
+				Topics:  []common.Hash{chequeCashedEventType.ID, beneficiary.Hash(), recipientAddress.Hash(), beneficiary.Hash()},
+				Data:    logData,
+			},
+		},
+	}
+
+	tokenCallData, err := vaultABI.Pack("token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lookingUp := make(chan struct{})
+	release := make(chan struct{})
+	cashoutService := vault.NewCashoutService(
+		storemock.NewStateStore(),
+		backendmock.New(
+			backendmock.WithTransactionByHashFunc(func(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+				return nil, false, nil
+			}),
+			backendmock.WithTransactionReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				return receipt, nil
+			}),
+		),
+		transactionmock.New(
+			transactionmock.WithABISend(&vaultABI, txHash, vaultAddress, big.NewInt(0), "cashChequeBeneficiary", recipientAddress, cheque.CumulativePayout, cheque.Signature),
+			transactionmock.WithWaitForReceiptFunc(func(ctx context.Context, hash common.Hash) (*types.Receipt, error) {
+				return receipt, nil
+			}),
+			transactionmock.WithCallFunc(func(ctx context.Context, request *transaction.TxRequest) ([]byte, error) {
+				if bytes.Equal(request.Data, tokenCallData) {
+					// the token lookup of the tracked cashout is slow
+					close(lookingUp)
+					<-release
+				}
+				return tokenAddress.Hash().Bytes(), nil
+			}),
+		),
+		chequestoremock.NewChequeStore(
+			chequestoremock.WithLastChequeFunc(func(c common.Address) (*vault.SignedCheque, error) {
+				return cheque, nil
+			}),
+		),
+	)
+	defer cashoutService.Close()
+
+	_, err = cashoutService.CashCheque(context.Background(), vaultAddress, recipientAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-lookingUp:
+	case <-time.After(time.Second):
+		t.Fatal("token not looked up")
+	}
+
+	// the totals are not locked while the token is looked up
+	verified := make(chan error, 1)
+	go func() {
+		_, err := cashoutService.VerifyTotals(context.Background())
+		verified <- err
+	}()
+	select {
+	case err := <-verified:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("totals blocked by the token lookup")
+	}
+	close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err = cashoutService.WaitForCashouts(ctx, []common.Hash{txHash})
+	if err != nil {
+		t.Fatal(err)
+	}
+	totals, err := cashoutService.TotalReceivedCashedByToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if totals[tokenAddress] == nil || totals[tokenAddress].Cmp(totalPayout) != 0 {
+		t.Fatalf("wrong token totals %v", totals)
 	}
 }
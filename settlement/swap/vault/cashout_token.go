@@ -0,0 +1,107 @@
+package vault
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/bittorrent/go-btfs/statestore"
+	"github.com/bittorrent/go-btfs/transaction"
+	"github.com/bittorrent/go-btfs/transaction/storage"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// vaultToken returns the token in which the vault pays out
+func (s *cashoutService) vaultToken(ctx context.Context, vault common.Address) (common.Address, error) {
+	callData, err := vaultABI.Pack("token")
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	output, err := s.transactionService.Call(ctx, &transaction.TxRequest{
+		To:   &vault,
+		Data: callData,
+	})
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	results, err := vaultABI.Unpack("token", output)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	if len(results) != 1 {
+		return common.Address{}, errDecodeABI
+	}
+
+	token, ok := abi.ConvertType(results[0], new(common.Address)).(*common.Address)
+	if !ok || token == nil {
+		return common.Address{}, errDecodeABI
+	}
+	return *token, nil
+}
+
+// cashoutToken resolves the token paid out by vault for the token namespaced totals. It is called before taking the
+// totals lock so a slow backend does not block other totals updates. A zero address means the token could not be
+// resolved and the token totals are skipped.
+func (s *cashoutService) cashoutToken(ctx context.Context, vault common.Address) common.Address {
+	token, err := s.vaultToken(ctx, vault)
+	if err != nil {
+		log.Infof("CashOutStats:get vault token err:%+v", err)
+		return common.Address{}
+	}
+	return token
+}
+
+// addTokenReceivedCashed adds amount to the total and the daily received cashed amount of token of the given day
+func addTokenReceivedCashed(store storeReadWriter, token common.Address, day int64, amount *big.Int) error {
+	for _, key := range []string{
+		statestore.TotalReceivedCashedByTokenKey(token),
+		statestore.GetTotalDailyReceivedCashedByTokenKeyByTime(day, token),
+	} {
+		total := big.NewInt(0)
+		err := store.Get(key, &total)
+		if err != nil && err != storage.ErrNotFound {
+			return err
+		}
+		err = store.Put(key, total.Add(total, amount))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TotalReceivedCashedByToken returns the total received cashed amount for every token
+func (s *cashoutService) TotalReceivedCashedByToken() (map[common.Address]*big.Int, error) {
+	return s.receivedCashedByToken(statestore.TotalReceivedCashedByTokenKeyPrefix)
+}
+
+// DailyReceivedCashedByToken returns the received cashed amount of the given day for every token
+func (s *cashoutService) DailyReceivedCashedByToken(day time.Time) (map[common.Address]*big.Int, error) {
+	return s.receivedCashedByToken(statestore.GetTotalDailyReceivedCashedByTokenPrefixByTime(dayUnix(day)))
+}
+
+func (s *cashoutService) receivedCashedByToken(prefix string) (map[common.Address]*big.Int, error) {
+	result := make(map[common.Address]*big.Int)
+	err := s.store.Iterate(prefix, func(key, val []byte) (stop bool, err error) {
+		token := strings.TrimPrefix(string(key), prefix)
+		if !common.IsHexAddress(token) {
+			return false, nil
+		}
+		total := big.NewInt(0)
+		err = s.store.Get(string(key), &total)
+		if err != nil {
+			return false, err
+		}
+		result[common.HexToAddress(token)] = total
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
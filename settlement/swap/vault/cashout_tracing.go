@@ -0,0 +1,97 @@
+package vault
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Attribute keys of the cashout spans
+const (
+	TraceAttributeVault  = "vault"
+	TraceAttributeTxHash = "tx_hash"
+	TraceAttributeAmount = "amount"
+	TraceAttributeStatus = "status"
+)
+
+// Tracer starts the spans of the cashout service. It follows the OpenTelemetry tracer so that an adapter only
+// has to translate the calls.
+type Tracer interface {
+	// Start starts a span as child of the span in ctx. The spans of the links contexts are linked to the new span,
+	// which connects work running detached from the call that started it.
+	Start(ctx context.Context, name string, links ...context.Context) (context.Context, Span)
+}
+
+// Span is a span started by a Tracer
+type Span interface {
+	SetAttribute(key, value string)
+	RecordError(err error)
+	End()
+}
+
+// WithTracer traces cashouts, status queries and the backend calls they make with the tracer
+func WithTracer(tracer Tracer) CashoutServiceOption {
+	return cashoutServiceOptionFunc(func(s *cashoutService) {
+		if tracer != nil {
+			s.tracer = tracer
+		}
+	})
+}
+
+type noopTracer struct{}
+
+func (noopTracer) Start(ctx context.Context, name string, links ...context.Context) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(key, value string) {}
+func (noopSpan) RecordError(err error)          {}
+func (noopSpan) End()                           {}
+
+type spanContextKey struct{}
+
+// startSpan starts a span and keeps it in the returned context for spanFromContext
+func (s *cashoutService) startSpan(ctx context.Context, name string, links ...context.Context) (context.Context, Span) {
+	ctx, span := s.tracer.Start(ctx, name, links...)
+	return context.WithValue(ctx, spanContextKey{}, span), span
+}
+
+// spanFromContext returns the span started last by startSpan for ctx, a no-op span if there is none
+func spanFromContext(ctx context.Context) Span {
+	if span, ok := ctx.Value(spanContextKey{}).(Span); ok {
+		return span
+	}
+	return noopSpan{}
+}
+
+// endSpan records err on the span, if any, and ends it
+func endSpan(span Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
+
+// transactionReceipt fetches the receipt from the backend within a span unless it was prefetched for ctx
+func (s *cashoutService) transactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	if receipt, ok := prefetchedReceipt(ctx, txHash); ok {
+		return receipt, nil
+	}
+	ctx, span := s.startSpan(ctx, "backend.TransactionReceipt")
+	span.SetAttribute(TraceAttributeTxHash, txHash.Hex())
+	receipt, err := s.backend.TransactionReceipt(ctx, txHash)
+	endSpan(span, err)
+	return receipt, err
+}
+
+// transactionByHash fetches the transaction from the backend within a span
+func (s *cashoutService) transactionByHash(ctx context.Context, txHash common.Hash) (*types.Transaction, bool, error) {
+	ctx, span := s.startSpan(ctx, "backend.TransactionByHash")
+	span.SetAttribute(TraceAttributeTxHash, txHash.Hex())
+	tx, pending, err := s.backend.TransactionByHash(ctx, txHash)
+	endSpan(span, err)
+	return tx, pending, err
+}
@@ -0,0 +1,34 @@
+package vault
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// CashoutTxChain returns every transaction sent for the last cashout of the vault: the original cashout
+// transaction followed by the transactions which replaced it, e.g. after a missed deadline, oldest first.
+func (s *cashoutService) CashoutTxChain(vault common.Address) ([]common.Hash, error) {
+	action, err := s.repository.Action(vault)
+	if err != nil {
+		return nil, err
+	}
+	chain := make([]common.Hash, 0, 1+len(action.Replacements))
+	chain = append(chain, action.TxHash)
+	return append(chain, action.Replacements...), nil
+}
+
+// recordReplacement appends the transaction replacing the cashout transaction txHash to the ledger of the vault's
+// cashout. Nothing is recorded if the vault has been cashed again in the meantime.
+func (s *cashoutService) recordReplacement(vault common.Address, txHash, replacement common.Hash) error {
+	s.actionLock.Lock()
+	defer s.actionLock.Unlock()
+
+	action, err := s.repository.Action(vault)
+	if err != nil {
+		return err
+	}
+	if action.TxHash != txHash {
+		return nil
+	}
+	action.Replacements = append(action.Replacements, replacement)
+	return s.repository.PutAction(vault, action)
+}
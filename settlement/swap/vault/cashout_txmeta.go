@@ -0,0 +1,46 @@
+package vault
+
+import (
+	"encoding/json"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// cashoutTxMetadataPrefix starts the description of cashout transactions which carry metadata
+const cashoutTxMetadataPrefix = "cashout-meta:"
+
+// CashoutTxMetadata describes the cashout a transaction was sent for. The transaction request has no field for
+// structured data so it is encoded into the description, see ParseCashoutTxDescription.
+type CashoutTxMetadata struct {
+	Vault  common.Address `json:"vault"`
+	Amount *big.Int       `json:"amount"` // cumulative payout of the cashed cheque
+	Label  string         `json:"label,omitempty"`
+	Key    string         `json:"key"` // write-ahead log key of the cashout, unique for every cashout
+}
+
+// cashoutTxDescription prefixes the description with the encoded metadata. The plain description is returned if
+// the metadata cannot be encoded.
+func cashoutTxDescription(meta CashoutTxMetadata, description string) string {
+	encoded, err := json.Marshal(meta)
+	if err != nil {
+		log.Infof("cashout of vault %x: could not encode transaction metadata: %v", meta.Vault, err)
+		return description
+	}
+	return cashoutTxMetadataPrefix + string(encoded) + " " + description
+}
+
+// ParseCashoutTxDescription extracts the metadata from the description of a cashout transaction and returns it
+// together with the remaining plain description. ok is false if the description carries no metadata.
+func ParseCashoutTxDescription(description string) (meta CashoutTxMetadata, rest string, ok bool) {
+	if !strings.HasPrefix(description, cashoutTxMetadataPrefix) {
+		return CashoutTxMetadata{}, description, false
+	}
+	decoder := json.NewDecoder(strings.NewReader(description[len(cashoutTxMetadataPrefix):]))
+	if err := decoder.Decode(&meta); err != nil {
+		return CashoutTxMetadata{}, description, false
+	}
+	rest = description[len(cashoutTxMetadataPrefix)+int(decoder.InputOffset()):]
+	return meta, strings.TrimPrefix(rest, " "), true
+}
@@ -0,0 +1,105 @@
+package vault
+
+import (
+	"math/big"
+
+	"github.com/bittorrent/go-btfs/transaction"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// VaultEventType is the name of an event in the vault ABI
+type VaultEventType string
+
+// The vault events recognized by ParseVaultEvents
+const (
+	VaultEventChequeCashed  VaultEventType = "ChequeCashed"
+	VaultEventChequeBounced VaultEventType = "ChequeBounced"
+	VaultEventDeposit       VaultEventType = "VaultDeposit"
+	VaultEventWithdraw      VaultEventType = "VaultWithdraw"
+)
+
+// VaultEvent is an event emitted by a vault. Only the fields of its type are set.
+type VaultEvent struct {
+	Type     VaultEventType
+	LogIndex uint              // index of the log in the block
+	Cashed   *CashChequeResult // the cashed cheque of ChequeCashed, Bounced is not set
+	From     common.Address    // depositor of VaultDeposit, withdrawer of VaultWithdraw
+	Amount   *big.Int          // amount of VaultDeposit and VaultWithdraw
+}
+
+// vaultTransferEvent is the data of the VaultDeposit and VaultWithdraw events
+type vaultTransferEvent struct {
+	From   common.Address
+	Amount *big.Int
+}
+
+// ParseVaultEvents decodes all recognized events the vault emitted in the receipt in log order. Events of other
+// contracts and vault events without a VaultEventType, e.g. upgrades, are skipped.
+func ParseVaultEvents(receipt *types.Receipt, vault common.Address) ([]VaultEvent, error) {
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return nil, transaction.ErrTransactionReverted
+	}
+
+	var events []VaultEvent
+	for _, l := range receipt.Logs {
+		if l.Address != vault || len(l.Topics) == 0 {
+			continue
+		}
+		event := VaultEvent{LogIndex: l.Index}
+		switch l.Topics[0] {
+		case chequeCashedEventType.ID:
+			var cashed chequeCashedEvent
+			err := transaction.ParseEvent(&vaultABI, chequeCashedEventType.Name, &cashed, *l)
+			if err != nil {
+				return nil, err
+			}
+			event.Type = VaultEventChequeCashed
+			event.Cashed = &CashChequeResult{
+				Beneficiary:      cashed.Beneficiary,
+				Recipient:        cashed.Recipient,
+				Caller:           cashed.Caller,
+				TotalPayout:      cashed.TotalPayout,
+				CumulativePayout: cashed.CumulativePayout,
+				CallerPayout:     cashed.CallerPayout,
+			}
+		case chequeBouncedEventType.ID:
+			event.Type = VaultEventChequeBounced
+		case vaultDepositEventType.ID, vaultWithdrawEventType.ID:
+			eventType := vaultDepositEventType
+			event.Type = VaultEventDeposit
+			if l.Topics[0] == vaultWithdrawEventType.ID {
+				eventType = vaultWithdrawEventType
+				event.Type = VaultEventWithdraw
+			}
+			var transfer vaultTransferEvent
+			err := transaction.ParseEvent(&vaultABI, eventType.Name, &transfer, *l)
+			if err != nil {
+				return nil, err
+			}
+			event.From = transfer.From
+			event.Amount = transfer.Amount
+		default:
+			continue
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// vaultBalanceChange computes the net change of the vault balance caused by the events: deposits minus withdrawals
+// and cashed payouts
+func vaultBalanceChange(events []VaultEvent) *big.Int {
+	change := big.NewInt(0)
+	for _, event := range events {
+		switch event.Type {
+		case VaultEventDeposit:
+			change.Add(change, event.Amount)
+		case VaultEventWithdraw:
+			change.Sub(change, event.Amount)
+		case VaultEventChequeCashed:
+			change.Sub(change, event.Cashed.TotalPayout)
+		}
+	}
+	return change
+}
@@ -0,0 +1,150 @@
+package vault
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/bittorrent/go-btfs/statestore"
+	"github.com/bittorrent/go-btfs/transaction/storage"
+)
+
+// TotalsDiff compares the stored cashout totals with the totals recomputed from the successful cashout results.
+// Results removed by WithMaxStoredResults are included in the recomputed totals, results reconstructed by
+// RebuildHistoryFromChain never updated the totals and are left out.
+type TotalsDiff struct {
+	StoredCashed *big.Int // stored TotalReceivedCashed
+	ResultCashed *big.Int // sum of the amounts of the successful results, including evicted ones
+	StoredCount  int      // stored TotalReceivedCashedCount
+	ResultCount  int      // number of successful results, including evicted ones
+}
+
+// Drifted reports whether the stored totals disagree with the results. The stored count is the number of cashed
+// cheques and a cashout can cash several cheques at once, so it only drifted if it is below the number of results.
+func (d TotalsDiff) Drifted() bool {
+	return d.StoredCashed.Cmp(d.ResultCashed) != 0 || d.StoredCount < d.ResultCount
+}
+
+// totalsVerification configures the periodic comparison of the totals with the results
+type totalsVerification struct {
+	interval    time.Duration
+	autoCorrect bool
+}
+
+// WithTotalsVerification periodically compares the cashout totals with the results once the service is started.
+// Drift is logged and with autoCorrect the stored totals are overwritten with the recomputed ones.
+func WithTotalsVerification(interval time.Duration, autoCorrect bool) CashoutServiceOption {
+	return cashoutServiceOptionFunc(func(s *cashoutService) {
+		if interval > 0 {
+			s.totalsVerification = &totalsVerification{
+				interval:    interval,
+				autoCorrect: autoCorrect,
+			}
+		}
+	})
+}
+
+// VerifyTotals recomputes TotalReceivedCashed and TotalReceivedCashedCount from the successful cashout results and
+// compares them with the stored totals. It does not modify the store.
+func (s *cashoutService) VerifyTotals(ctx context.Context) (TotalsDiff, error) {
+	// results and totals are committed together under the lock, reading them under it gives a consistent view
+	s.totalsLock.Lock()
+	defer s.totalsLock.Unlock()
+	return s.diffTotals(ctx)
+}
+
+func (s *cashoutService) diffTotals(ctx context.Context) (TotalsDiff, error) {
+	diff := TotalsDiff{
+		StoredCashed: big.NewInt(0),
+		ResultCashed: big.NewInt(0),
+	}
+
+	err := s.store.Get(statestore.TotalReceivedCashedKey, &diff.StoredCashed)
+	if err != nil && err != storage.ErrNotFound {
+		return TotalsDiff{}, err
+	}
+	err = s.store.Get(statestore.TotalReceivedCashedCountKey, &diff.StoredCount)
+	if err != nil && err != storage.ErrNotFound {
+		return TotalsDiff{}, err
+	}
+
+	evicted, err := loadEvictedTotals(s.store)
+	if err != nil {
+		return TotalsDiff{}, err
+	}
+	diff.ResultCashed.Add(diff.ResultCashed, evicted.Cashed)
+	diff.ResultCount += evicted.Count
+
+	err = s.repository.IterateResults(func(result CashOutResult) (bool, error) {
+		if err := ctx.Err(); err != nil {
+			return true, err
+		}
+		if result.Status != "success" || result.Amount == nil || result.Rebuilt {
+			return false, nil
+		}
+		diff.ResultCashed.Add(diff.ResultCashed, result.Amount)
+		diff.ResultCount++
+		return false, nil
+	})
+	if err != nil {
+		return TotalsDiff{}, err
+	}
+	return diff, nil
+}
+
+// verifyTotals compares the totals with the results and corrects drift if configured. The recomputed totals
+// account for evicted and rebuilt results, so they are only overwritten for drift the results cannot explain.
+func (s *cashoutService) verifyTotals(ctx context.Context) error {
+	s.totalsLock.Lock()
+	defer s.totalsLock.Unlock()
+
+	diff, err := s.diffTotals(ctx)
+	if err != nil {
+		return err
+	}
+	if !diff.Drifted() {
+		return nil
+	}
+	log.Warnf("cashout: totals drifted, stored %d in %d cheques, results %d in %d cashouts",
+		diff.StoredCashed, diff.StoredCount, diff.ResultCashed, diff.ResultCount)
+	if !s.totalsVerification.autoCorrect {
+		return nil
+	}
+
+	batch := newStoreBatch(s.store)
+	err = batch.Put(statestore.TotalReceivedCashedKey, diff.ResultCashed)
+	if err != nil {
+		return err
+	}
+	if diff.StoredCount < diff.ResultCount {
+		err = batch.Put(statestore.TotalReceivedCashedCountKey, diff.ResultCount)
+		if err != nil {
+			return err
+		}
+	}
+	return batch.Commit()
+}
+
+func (s *cashoutService) totalsVerificationLoop() {
+	defer s.wg.Done()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-s.quit
+		cancel()
+	}()
+
+	for {
+		select {
+		case <-s.quit:
+			return
+		case <-time.After(s.totalsVerification.interval):
+		}
+
+		err := s.verifyTotals(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Errorf("cashout: could not verify totals: %v", err)
+		}
+	}
+}
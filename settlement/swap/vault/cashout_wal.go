@@ -0,0 +1,231 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/bittorrent/go-btfs/transaction/storage"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const cashoutIntentPrefix = "swap_cashout_wal_"
+
+// cashoutIntent is the write-ahead log record of a cashout. It is written before the cashout transaction is sent
+// and only removed once the result of the cashout has been stored, so a cashout interrupted by a crash can be
+// picked up again on the next start.
+type cashoutIntent struct {
+	Vault              common.Address
+	Cheque             SignedCheque
+	RequestedRecipient common.Address
+	Recipient          common.Address
+	CallData           []byte      // call data of the cashout transaction
+	TxHash             common.Hash // hash of the cashout transaction, zero until the transaction was sent
+	Created            int64
+	Label              string
+}
+
+// cashoutIntentKey computes the store key for a write-ahead log record
+func cashoutIntentKey(vault common.Address, created int64) string {
+	return fmt.Sprintf("%s%x_%d", cashoutIntentPrefix, vault, created)
+}
+
+// newCashoutIntent persists the intent to cash out before the transaction is sent. It must be called under the
+// in-flight lock.
+func (s *cashoutService) newCashoutIntent(intent *cashoutIntent) (string, error) {
+	intent.Created = s.now().UnixNano()
+	key := cashoutIntentKey(intent.Vault, intent.Created)
+	// the clock may not have advanced since the last intent of the vault, e.g. a test clock, keep the keys apart
+	for {
+		err := s.store.Get(key, &cashoutIntent{})
+		if errors.Is(err, storage.ErrNotFound) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		intent.Created++
+		key = cashoutIntentKey(intent.Vault, intent.Created)
+	}
+	err := s.store.Put(key, intent)
+	if err != nil {
+		return "", err
+	}
+	s.metrics.PendingCashouts.Inc()
+	return key, nil
+}
+
+// pendingCashouts counts the cashouts which have been started but whose result has not been stored yet.
+// The write-ahead log doubles as the index of pending cashouts.
+func (s *cashoutService) pendingCashouts() (int, error) {
+	count := 0
+	err := s.store.Iterate(cashoutIntentPrefix, func(key, val []byte) (stop bool, err error) {
+		count++
+		return false, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// clearCashoutIntent removes a write-ahead log record once the cashout has been fully recorded
+func (s *cashoutService) clearCashoutIntent(key string) {
+	err := s.store.Delete(key)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		log.Errorf("cashout: could not clear write-ahead log record %s: %v", key, err)
+		return
+	}
+	s.metrics.PendingCashouts.Dec()
+}
+
+// cashoutIntentKeyOf looks up the key of the write-ahead log record of the cashout of the vault in the transaction.
+// ok is false if there is none.
+func (s *cashoutService) cashoutIntentKeyOf(vault common.Address, txHash common.Hash) (key string, ok bool, err error) {
+	err = s.store.Iterate(fmt.Sprintf("%s%x_", cashoutIntentPrefix, vault), func(k, val []byte) (stop bool, err error) {
+		var intent cashoutIntent
+		err = s.store.Get(string(k), &intent)
+		if err != nil {
+			return false, err
+		}
+		if intent.TxHash == txHash {
+			key, ok = string(k), true
+			return true, nil
+		}
+		return false, nil
+	})
+	if err != nil {
+		return "", false, err
+	}
+	return key, ok, nil
+}
+
+// cashoutTrackers are the background trackers of the sent cashouts, so the tracker of a dropped cashout can be
+// stopped
+type cashoutTrackers struct {
+	mu      sync.Mutex
+	cancels map[common.Hash]context.CancelFunc
+	dropped map[common.Hash]bool
+}
+
+// add registers the tracker of the transaction and returns the context it has to wait with
+func (t *cashoutTrackers) add(ctx context.Context, txHash common.Hash) context.Context {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.cancels == nil {
+		t.cancels = make(map[common.Hash]context.CancelFunc)
+		t.dropped = make(map[common.Hash]bool)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	t.cancels[txHash] = cancel
+	return ctx
+}
+
+// remove unregisters the tracker of the transaction once it is done and reports whether the cashout was dropped
+func (t *cashoutTrackers) remove(txHash common.Hash) (dropped bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if cancel, ok := t.cancels[txHash]; ok {
+		cancel()
+	}
+	dropped = t.dropped[txHash]
+	delete(t.cancels, txHash)
+	delete(t.dropped, txHash)
+	return dropped
+}
+
+// drop stops the tracker of the transaction, it is a no-op if the transaction is not tracked
+func (t *cashoutTrackers) drop(txHash common.Hash) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	cancel, ok := t.cancels[txHash]
+	if !ok {
+		return
+	}
+	t.dropped[txHash] = true
+	cancel()
+}
+
+// isDropped reports whether the cashout of the transaction was dropped while it was tracked
+func (t *cashoutTrackers) isDropped(txHash common.Hash) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.dropped[txHash]
+}
+
+// replayCashoutIntents resumes the tracking of all cashouts which have not been fully recorded before the last shutdown
+func (s *cashoutService) replayCashoutIntents(ctx context.Context) error {
+	intents := make(map[string]cashoutIntent)
+	err := s.store.Iterate(cashoutIntentPrefix, func(key, val []byte) (stop bool, err error) {
+		var intent cashoutIntent
+		err = s.store.Get(string(key), &intent)
+		if err != nil {
+			return false, err
+		}
+		intents[string(key)] = intent
+		return false, nil
+	})
+	if err != nil {
+		return err
+	}
+	s.metrics.PendingCashouts.Set(float64(len(intents)))
+
+	for key, intent := range intents {
+		intent := intent
+		if intent.TxHash == (common.Hash{}) {
+			// we crashed before Send returned, check whether the transaction was broadcast anyway
+			txHash, found, err := s.findSentCashout(&intent)
+			if err != nil {
+				log.Errorf("cashout: could not look up transaction of write-ahead log record %s: %v", key, err)
+				continue
+			}
+			if !found {
+				log.Infof("cashout: dropping write-ahead log record %s of vault %x, the transaction was never sent", key, intent.Vault)
+				s.clearCashoutIntent(key)
+				continue
+			}
+			intent.TxHash = txHash
+		}
+
+		// the stored action may still be the one of an earlier cashout if we crashed before it was replaced
+		action, err := s.repository.Action(intent.Vault)
+		if errors.Is(err, storage.ErrNotFound) || (err == nil && action.TxHash != intent.TxHash && action.Submitted <= intent.Created) {
+			err = s.repository.PutAction(intent.Vault, &CashoutAction{
+				TxHash:             intent.TxHash,
+				Cheque:             intent.Cheque,
+				RequestedRecipient: intent.RequestedRecipient,
+				Recipient:          intent.Recipient,
+				Submitted:          intent.Created,
+				Label:              intent.Label,
+			})
+		}
+		if err != nil {
+			log.Errorf("cashout: could not restore cashout action of vault %x: %v", intent.Vault, err)
+			continue
+		}
+
+		log.Infof("cashout: resuming tracking of cashout %x of vault %x", intent.TxHash, intent.Vault)
+		s.trackCashout(ctx, intent.Vault, intent.TxHash, &intent.Cheque, intent.Label, key, true)
+	}
+	return nil
+}
+
+// findSentCashout searches the pending transactions for the cashout transaction of the intent
+func (s *cashoutService) findSentCashout(intent *cashoutIntent) (common.Hash, bool, error) {
+	pending, err := s.transactionService.PendingTransactions()
+	if err != nil {
+		return common.Hash{}, false, err
+	}
+	for _, txHash := range pending {
+		stored, err := s.transactionService.StoredTransaction(txHash)
+		if err != nil {
+			return common.Hash{}, false, err
+		}
+		if stored.To != nil && *stored.To == intent.Vault && bytes.Equal(stored.Data, intent.CallData) {
+			return txHash, true, nil
+		}
+	}
+	return common.Hash{}, false, nil
+}
@@ -0,0 +1,100 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/bittorrent/go-btfs/settlement/swap/erc20"
+	"github.com/bittorrent/go-btfs/transaction"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+var (
+	// ErrCashoutBounced is the error if a cashout confirmed but parts of the cheque bounced
+	ErrCashoutBounced = errors.New("cashout bounced")
+)
+
+// CashAndWithdrawResult tracks the transactions sent by CashAndWithdraw
+type CashAndWithdrawResult struct {
+	CashoutTxHash  common.Hash // transaction cashing the cheque into the recipient vault
+	WithdrawTxHash common.Hash // transaction withdrawing the cashed amount from the recipient vault
+	TransferTxHash common.Hash // transaction forwarding the withdrawn amount to withdrawTo, zero if not needed
+	Amount         *big.Int    // amount which was withdrawn
+}
+
+// CashAndWithdraw cashes the last cheque of vault into the operator's own vault recipient and, once the cashout
+// confirmed without bouncing, withdraws the cashed amount from it. The recipient policy and the default recipient
+// may change the payee, so the withdrawal goes to the recipient the ChequeCashed event paid. Vault withdrawals are paid to the vault issuer,
+// so if withdrawTo is neither zero nor the issuer the amount is forwarded with an additional token transfer.
+func (s *cashoutService) CashAndWithdraw(ctx context.Context, vault, recipient common.Address, withdrawTo common.Address) (*CashAndWithdrawResult, error) {
+	txHash, err := s.CashCheque(ctx, vault, recipient)
+	if err != nil {
+		return nil, err
+	}
+	result := &CashAndWithdrawResult{
+		CashoutTxHash: txHash,
+	}
+
+	receipt, err := s.waitForReceipt(ctx, txHash)
+	if err != nil {
+		return result, err
+	}
+	if receipt.Status == types.ReceiptStatusFailed {
+		return result, transaction.ErrTransactionReverted
+	}
+
+	cashResult, err := s.parseCashChequeBeneficiaryReceipt(vault, receipt)
+	if err != nil {
+		return result, err
+	}
+	if cashResult.Bounced {
+		return result, ErrCashoutBounced
+	}
+
+	// the caller payout went to the sending account, only the rest arrived in the recipient vault
+	result.Amount = new(big.Int).Sub(cashResult.TotalPayout, cashResult.CallerPayout)
+	if result.Amount.Sign() <= 0 {
+		return result, nil
+	}
+
+	// the recipient actually paid, which differs from the requested one if it was overridden
+	payee := cashResult.Recipient
+	callData, err := vaultABI.Pack("withdraw", result.Amount)
+	if err != nil {
+		return result, err
+	}
+	result.WithdrawTxHash, err = s.sendTransaction(ctx, &transaction.TxRequest{
+		To:          &payee,
+		Data:        callData,
+		Value:       big.NewInt(0),
+		Description: fmt.Sprintf("vault withdrawal of %d after cashout", result.Amount),
+	})
+	if err != nil {
+		return result, err
+	}
+
+	if withdrawTo == (common.Address{}) || withdrawTo == s.beneficiary {
+		return result, nil
+	}
+
+	receipt, err = s.waitForReceipt(ctx, result.WithdrawTxHash)
+	if err != nil {
+		return result, err
+	}
+	if receipt.Status == types.ReceiptStatusFailed {
+		return result, transaction.ErrTransactionReverted
+	}
+
+	token, err := s.vaultToken(ctx, payee)
+	if err != nil {
+		return result, err
+	}
+	result.TransferTxHash, err = erc20.New(s.backend, s.transactionService, token).Transfer(ctx, withdrawTo, result.Amount)
+	if err != nil {
+		return result, err
+	}
+	return result, nil
+}
@@ -4,4 +4,15 @@ var (
 	LastIssuedChequeKey   = lastIssuedChequeKey
 	LastReceivedChequeKey = lastReceivedChequeKey
 	CashoutActionKey      = cashoutActionKey
+	DecodeRevert          = decodeRevert
+	CashoutBatchKey       = cashoutBatchKey
+	CashoutIntentKey      = cashoutIntentKey
+	AppliedResultKey      = appliedResultKey
 )
+
+type CashoutBatch = cashoutBatch
+
+// PublishCashoutResult notifies the result subscribers of the service of a result without storing it
+func PublishCashoutResult(s CashoutService, result CashOutResult) {
+	s.(*cashoutService).publishCashoutResult(result)
+}
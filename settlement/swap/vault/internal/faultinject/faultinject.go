@@ -0,0 +1,181 @@
+// Package faultinject wraps the transaction backend and service so tests can make specific calls fail.
+package faultinject
+
+import (
+	"context"
+	"math/big"
+	"sync"
+
+	"github.com/bittorrent/go-btfs/transaction"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Faults counts the calls per method and decides which of them fail
+type Faults struct {
+	mu       sync.Mutex
+	calls    map[string]int
+	failures map[string]map[int]error // method -> call number -> error, call number 0 fails every call
+}
+
+func New() *Faults {
+	return &Faults{
+		calls:    make(map[string]int),
+		failures: make(map[string]map[int]error),
+	}
+}
+
+// FailOn makes the given calls of method fail with err. Calls are counted from 1, without any call numbers every
+// call of method fails.
+func (f *Faults) FailOn(method string, err error, calls ...int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.failures[method] == nil {
+		f.failures[method] = make(map[int]error)
+	}
+	if len(calls) == 0 {
+		f.failures[method][0] = err
+	}
+	for _, call := range calls {
+		f.failures[method][call] = err
+	}
+}
+
+// Calls returns how often method has been called
+func (f *Faults) Calls(method string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[method]
+}
+
+// check records a call of method and returns the error it should fail with, if any
+func (f *Faults) check(method string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls[method]++
+	failures := f.failures[method]
+	if err, ok := failures[f.calls[method]]; ok {
+		return err
+	}
+	return failures[0]
+}
+
+type backend struct {
+	transaction.Backend
+	faults *Faults
+}
+
+// NewBackend wraps b so its methods fail as configured in faults
+func NewBackend(b transaction.Backend, faults *Faults) transaction.Backend {
+	return &backend{
+		Backend: b,
+		faults:  faults,
+	}
+}
+
+func (b *backend) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+	if err := b.faults.check("CodeAt"); err != nil {
+		return nil, err
+	}
+	return b.Backend.CodeAt(ctx, contract, blockNumber)
+}
+
+func (b *backend) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	if err := b.faults.check("SuggestGasPrice"); err != nil {
+		return nil, err
+	}
+	return b.Backend.SuggestGasPrice(ctx)
+}
+
+func (b *backend) FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+	if err := b.faults.check("FilterLogs"); err != nil {
+		return nil, err
+	}
+	return b.Backend.FilterLogs(ctx, query)
+}
+
+func (b *backend) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	if err := b.faults.check("TransactionReceipt"); err != nil {
+		return nil, err
+	}
+	return b.Backend.TransactionReceipt(ctx, txHash)
+}
+
+func (b *backend) TransactionByHash(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error) {
+	if err := b.faults.check("TransactionByHash"); err != nil {
+		return nil, false, err
+	}
+	return b.Backend.TransactionByHash(ctx, hash)
+}
+
+func (b *backend) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	if err := b.faults.check("HeaderByNumber"); err != nil {
+		return nil, err
+	}
+	return b.Backend.HeaderByNumber(ctx, number)
+}
+
+func (b *backend) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+	if err := b.faults.check("NonceAt"); err != nil {
+		return 0, err
+	}
+	return b.Backend.NonceAt(ctx, account, blockNumber)
+}
+
+type service struct {
+	transaction.Service
+	faults *Faults
+}
+
+// NewService wraps s so its methods fail as configured in faults
+func NewService(s transaction.Service, faults *Faults) transaction.Service {
+	return &service{
+		Service: s,
+		faults:  faults,
+	}
+}
+
+func (s *service) Send(ctx context.Context, request *transaction.TxRequest) (common.Hash, error) {
+	if err := s.faults.check("Send"); err != nil {
+		return common.Hash{}, err
+	}
+	return s.Service.Send(ctx, request)
+}
+
+func (s *service) Call(ctx context.Context, request *transaction.TxRequest) ([]byte, error) {
+	if err := s.faults.check("Call"); err != nil {
+		return nil, err
+	}
+	return s.Service.Call(ctx, request)
+}
+
+func (s *service) WaitForReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	if err := s.faults.check("WaitForReceipt"); err != nil {
+		return nil, err
+	}
+	return s.Service.WaitForReceipt(ctx, txHash)
+}
+
+func (s *service) StoredTransaction(txHash common.Hash) (*transaction.StoredTransaction, error) {
+	if err := s.faults.check("StoredTransaction"); err != nil {
+		return nil, err
+	}
+	return s.Service.StoredTransaction(txHash)
+}
+
+func (s *service) PendingTransactions() ([]common.Hash, error) {
+	if err := s.faults.check("PendingTransactions"); err != nil {
+		return nil, err
+	}
+	return s.Service.PendingTransactions()
+}
+
+func (s *service) CancelTransaction(ctx context.Context, originalTxHash common.Hash) (common.Hash, error) {
+	if err := s.faults.check("CancelTransaction"); err != nil {
+		return common.Hash{}, err
+	}
+	return s.Service.CancelTransaction(ctx, originalTxHash)
+}
@@ -0,0 +1,146 @@
+package vault
+
+import (
+	"context"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+// pushJob is the job label of the cashout metrics pushed to a pushgateway
+const pushJob = "btfs_vault_cashout"
+
+// aggregateVaultLabel is the vault label of the metrics of all vaults not tracked individually
+const aggregateVaultLabel = "other"
+
+type metrics struct {
+	PendingCashouts  prometheus.Gauge
+	RetryingCashouts prometheus.Gauge
+	CashedTotal      *prometheus.CounterVec
+	Cashouts         *prometheus.CounterVec
+	BouncedCashouts  *prometheus.CounterVec
+}
+
+func newMetrics() metrics {
+	subsystem := "vault_cashout"
+
+	return metrics{
+		PendingCashouts: prometheus.NewGauge(prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      "pending",
+			Help:      "Number of cashouts which have been submitted but whose result has not been recorded yet.",
+		}),
+		RetryingCashouts: prometheus.NewGauge(prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      "retrying",
+			Help:      "Number of cashouts interrupted by a shutdown which are being tracked again.",
+		}),
+		CashedTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "cashed_total",
+			Help:      "Total amount paid out by successful cashouts, by tracked vault.",
+		}, []string{"vault"}),
+		Cashouts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "successful_total",
+			Help:      "Number of successful cashouts, by tracked vault.",
+		}, []string{"vault"}),
+		BouncedCashouts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "bounced_total",
+			Help:      "Number of successful cashouts which bounced partially, by tracked vault.",
+		}, []string{"vault"}),
+	}
+}
+
+// Metrics returns the prometheus collectors of the cashout service
+func (s *cashoutService) Metrics() []prometheus.Collector {
+	return []prometheus.Collector{
+		s.metrics.PendingCashouts,
+		s.metrics.RetryingCashouts,
+		s.metrics.CashedTotal,
+		s.metrics.Cashouts,
+		s.metrics.BouncedCashouts,
+	}
+}
+
+// PushMetrics pushes the current cashout metrics to the Prometheus pushgateway at gatewayURL, replacing the
+// metrics previously pushed by this node. The instance label is the beneficiary of the node, or the host name if
+// the beneficiary is unknown.
+func (s *cashoutService) PushMetrics(ctx context.Context, gatewayURL string) error {
+	pusher := push.New(gatewayURL, pushJob).
+		Grouping("instance", s.metricsInstance()).
+		Client(contextDoer{ctx: ctx, client: http.DefaultClient})
+	for _, c := range s.Metrics() {
+		pusher = pusher.Collector(c)
+	}
+	return pusher.Push()
+}
+
+// metricsInstance identifies the node in pushed metrics
+func (s *cashoutService) metricsInstance() string {
+	if s.beneficiary != (common.Address{}) {
+		return s.beneficiary.Hex()
+	}
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		return "unknown"
+	}
+	return hostname
+}
+
+// contextDoer binds the requests of the pusher to a context
+type contextDoer struct {
+	ctx    context.Context
+	client *http.Client
+}
+
+func (d contextDoer) Do(req *http.Request) (*http.Response, error) {
+	return d.client.Do(req.WithContext(d.ctx))
+}
+
+// trackedVaults are the vaults whose metrics are labeled individually. Every other vault is reported under
+// aggregateVaultLabel to bound the cardinality of the metrics.
+type trackedVaults struct {
+	mu     sync.Mutex
+	vaults map[common.Address]struct{}
+}
+
+// TrackVaultMetrics reports the cashout metrics of the vault under its own label from now on. It should only be
+// used for a few high value peers as every tracked vault adds a series to each labeled metric.
+func (s *cashoutService) TrackVaultMetrics(vault common.Address) {
+	s.trackedVaults.mu.Lock()
+	defer s.trackedVaults.mu.Unlock()
+	if s.trackedVaults.vaults == nil {
+		s.trackedVaults.vaults = make(map[common.Address]struct{})
+	}
+	s.trackedVaults.vaults[vault] = struct{}{}
+}
+
+// vaultLabel returns the metrics label of the vault
+func (s *cashoutService) vaultLabel(vault common.Address) string {
+	s.trackedVaults.mu.Lock()
+	defer s.trackedVaults.mu.Unlock()
+	if _, ok := s.trackedVaults.vaults[vault]; ok {
+		return vault.Hex()
+	}
+	return aggregateVaultLabel
+}
+
+// observeCashout updates the labeled metrics with a successful cashout
+func (s *cashoutService) observeCashout(vault common.Address, amount *big.Int, bounced bool) {
+	label := s.vaultLabel(vault)
+	if amount != nil {
+		cashed, _ := new(big.Float).SetInt(amount).Float64()
+		s.metrics.CashedTotal.WithLabelValues(label).Add(cashed)
+	}
+	s.metrics.Cashouts.WithLabelValues(label).Inc()
+	if bounced {
+		s.metrics.BouncedCashouts.WithLabelValues(label).Inc()
+	}
+}
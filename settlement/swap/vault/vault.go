@@ -35,6 +35,8 @@ var (
 	vaultABI               = transaction.ParseABIUnchecked(conabi.VaultABI)
 	chequeCashedEventType  = vaultABI.Events["ChequeCashed"]
 	chequeBouncedEventType = vaultABI.Events["ChequeBounced"]
+	vaultDepositEventType  = vaultABI.Events["VaultDeposit"]
+	vaultWithdrawEventType = vaultABI.Events["VaultWithdraw"]
 )
 
 // Service is the main interface for interacting with the nodes vault.
@@ -20,8 +20,36 @@ var (
 	TotalDailySentKey           = "swap_vault_total_daily_sent_"            // 单日发出支票总额度/总数量
 
 	PeerReceivedUncashRecordsCountKeyPrefix = "swap_vault_peer_received_uncashed_records_count_" // 每个peer收到支票未兑现数量
+
+	TotalDailyCashoutGasKey = "swap_vault_total_daily_cashout_gas_" // 单日兑现支票花费的gas总额
+
+	TotalReceivedCashedByTokenKeyPrefix      = "swap_vault_total_received_cashed_token_"       // 每种token收到支票兑现总额度
+	TotalDailyReceivedCashedByTokenKeyPrefix = "swap_vault_total_daily_received_cashed_token_" // 每种token单日收到支票兑现总额度
+
+	TotalCallerPayoutKey = "swap_vault_total_caller_payout" // 兑现支票获得的caller payout总额
+	LastCashoutTimeKey   = "swap_vault_last_cashout_time"   // 最近一次兑现支票的时间
 )
 
+func GetTotalDailyCashoutGasKeyByTime(timestamp int64) string {
+	return fmt.Sprintf("%s%d", TotalDailyCashoutGasKey, timestamp)
+}
+
+func TotalReceivedCashedByTokenKey(token common.Address) string {
+	return fmt.Sprintf("%s%x", TotalReceivedCashedByTokenKeyPrefix, token)
+}
+
+func GetTotalDailyReceivedCashedByTokenPrefixByTime(timestamp int64) string {
+	return fmt.Sprintf("%s%d_", TotalDailyReceivedCashedByTokenKeyPrefix, timestamp)
+}
+
+func GetTotalDailyReceivedCashedByTokenKeyByTime(timestamp int64, token common.Address) string {
+	return fmt.Sprintf("%s%x", GetTotalDailyReceivedCashedByTokenPrefixByTime(timestamp), token)
+}
+
+func GetTodayTotalDailyReceivedCashedByTokenKey(token common.Address) string {
+	return GetTotalDailyReceivedCashedByTokenKeyByTime(utils.TodayUnix(), token)
+}
+
 func PeerReceivedUncashRecordsCountKey(vault common.Address) string {
 	return fmt.Sprintf("%s%s", PeerReceivedUncashRecordsCountKeyPrefix, vault.String())
 }
@@ -55,5 +83,17 @@ func CashoutResultPrefixKey() string {
 }
 
 func CashoutResultKey(vault common.Address) string {
-	return fmt.Sprintf("%s%x_%d", CashoutResultPrefixKey(), vault, time.Now().Unix())
+	return CashoutResultKeyByTime(vault, time.Now().Unix())
+}
+
+// 结果按天分区: <prefix><day>_<vault>_<time>
+// the day is the local date like the daily totals, see utils.TodayUnix
+func CashoutResultKeyByTime(vault common.Address, ts int64) string {
+	y, m, d := time.Unix(ts, 0).Date()
+	day := time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Unix()
+	return fmt.Sprintf("%s%x_%d", CashoutResultDayPrefixKey(day), vault, ts)
+}
+
+func CashoutResultDayPrefixKey(day int64) string {
+	return fmt.Sprintf("%s%d_", CashoutResultPrefixKey(), day)
 }
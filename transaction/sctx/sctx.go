@@ -17,6 +17,7 @@ type (
 	HTTPRequestIDKey struct{}
 	gasPriceKey      struct{}
 	gasLimitKey      struct{}
+	nonceKey         struct{}
 )
 
 func SetGasLimit(ctx context.Context, limit uint64) context.Context {
@@ -43,3 +44,14 @@ func GetGasPrice(ctx context.Context) *big.Int {
 	}
 	return nil
 }
+
+// SetNonce sets an explicit nonce to be used for the transaction instead of the next free one.
+func SetNonce(ctx context.Context, nonce uint64) context.Context {
+	return context.WithValue(ctx, nonceKey{}, nonce)
+}
+
+// GetNonce returns the explicit nonce set on the context, if any.
+func GetNonce(ctx context.Context) (uint64, bool) {
+	v, ok := ctx.Value(nonceKey{}).(uint64)
+	return v, ok
+}
@@ -140,10 +140,14 @@ func (t *transactionService) Send(ctx context.Context, request *TxRequest) (txHa
 	t.lock.Lock()
 	defer t.lock.Unlock()
 
-	nonce, err := t.nextNonce(ctx)
+	nextNonce, err := t.nextNonce(ctx)
 	if err != nil {
 		return common.Hash{}, err
 	}
+	nonce := nextNonce
+	if explicitNonce, ok := sctx.GetNonce(ctx); ok {
+		nonce = explicitNonce
+	}
 
 	tx, err := prepareTransaction(ctx, request, t.sender, t.backend, nonce)
 	if err != nil {
@@ -162,9 +166,12 @@ func (t *transactionService) Send(ctx context.Context, request *TxRequest) (txHa
 		return common.Hash{}, err
 	}
 
-	err = t.putNonce(nonce + 1)
-	if err != nil {
-		return common.Hash{}, err
+	// an explicit nonce below the next free one replaces a transaction and must not move the nonce backwards
+	if nonce >= nextNonce {
+		err = t.putNonce(nonce + 1)
+		if err != nil {
+			return common.Hash{}, err
+		}
 	}
 
 	txHash = signedTx.Hash()